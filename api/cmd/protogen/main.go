@@ -0,0 +1,514 @@
+// protogen generates the Go payload structs and TypeScript types for the
+// WebSocket protocol from internal/websocket/protocol.schema.json, so the
+// server and the web client can't drift apart.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"slices"
+	"strings"
+)
+
+const generatedHeader = "// Code generated by protogen from protocol.schema.json. DO NOT EDIT."
+
+// schemaFile is the top level of protocol.schema.json
+type schemaFile struct {
+	ProtocolVersion    int             `json:"x-protocol-version"`
+	MinProtocolVersion int             `json:"x-min-protocol-version"`
+	Messages           json.RawMessage `json:"x-messages"`
+	Defs               json.RawMessage `json:"$defs"`
+}
+
+// messageDef maps a message type to its payload definition
+type messageDef struct {
+	Direction string `json:"direction"`
+	Payload   string `json:"payload"`
+}
+
+// typeDef is the subset of JSON Schema the protocol uses
+type typeDef struct {
+	Ref         string          `json:"$ref"`
+	Type        json.RawMessage `json:"type"`
+	Format      string          `json:"format"`
+	Description string          `json:"description"`
+	Properties  json.RawMessage `json:"properties"`
+	Required    []string        `json:"required"`
+	Items       *typeDef        `json:"items"`
+	OneOf       []typeDef       `json:"oneOf"`
+	Enum        []string        `json:"enum"`
+	EnumName    string          `json:"x-enum-name"`
+	GoType      string          `json:"x-go-type"`
+}
+
+// property is a named field of an object definition
+type property struct {
+	Name     string
+	Def      typeDef
+	Required bool
+}
+
+// protocol is the parsed schema with definition order preserved
+type protocol struct {
+	version    int
+	minVersion int
+	messages   []string
+	messageDef map[string]messageDef
+	defs       []string
+	defMap     map[string]typeDef
+}
+
+func main() {
+	schemaPath := flag.String("schema", "protocol.schema.json", "path to the protocol schema")
+	goOut := flag.String("go-out", "protocol.gen.go", "Go output file")
+	goPackage := flag.String("go-package", "websocket", "Go package name")
+	tsOut := flag.String("ts-out", "", "TypeScript output file (skipped if empty)")
+	flag.Parse()
+
+	data, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		log.Fatalf("Failed to read schema: %v", err)
+	}
+
+	proto, err := parseProtocol(data)
+	if err != nil {
+		log.Fatalf("Failed to parse schema: %v", err)
+	}
+
+	goSource, err := proto.generateGo(*goPackage)
+	if err != nil {
+		log.Fatalf("Failed to generate Go: %v", err)
+	}
+	if err := os.WriteFile(*goOut, goSource, 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *goOut, err)
+	}
+
+	if *tsOut != "" {
+		if err := os.WriteFile(*tsOut, proto.generateTS(), 0o644); err != nil {
+			log.Fatalf("Failed to write %s: %v", *tsOut, err)
+		}
+	}
+}
+
+func parseProtocol(data []byte) (*protocol, error) {
+	var file schemaFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	proto := &protocol{
+		version:    file.ProtocolVersion,
+		minVersion: file.MinProtocolVersion,
+		messageDef: make(map[string]messageDef),
+		defMap:     make(map[string]typeDef),
+	}
+
+	messageKeys, messageRaw, err := orderedObject(file.Messages)
+	if err != nil {
+		return nil, fmt.Errorf("x-messages: %w", err)
+	}
+	for _, key := range messageKeys {
+		var def messageDef
+		if err := json.Unmarshal(messageRaw[key], &def); err != nil {
+			return nil, fmt.Errorf("message %s: %w", key, err)
+		}
+		proto.messages = append(proto.messages, key)
+		proto.messageDef[key] = def
+	}
+
+	defKeys, defRaw, err := orderedObject(file.Defs)
+	if err != nil {
+		return nil, fmt.Errorf("$defs: %w", err)
+	}
+	for _, key := range defKeys {
+		var def typeDef
+		if err := json.Unmarshal(defRaw[key], &def); err != nil {
+			return nil, fmt.Errorf("definition %s: %w", key, err)
+		}
+		proto.defs = append(proto.defs, key)
+		proto.defMap[key] = def
+	}
+
+	for _, name := range proto.messages {
+		if _, ok := proto.defMap[proto.messageDef[name].Payload]; !ok {
+			return nil, fmt.Errorf("message %s references unknown payload %s", name, proto.messageDef[name].Payload)
+		}
+	}
+
+	return proto, nil
+}
+
+// orderedObject decodes a JSON object keeping the order of its keys
+func orderedObject(raw json.RawMessage) ([]string, map[string]json.RawMessage, error) {
+	values := make(map[string]json.RawMessage)
+	if len(raw) == 0 {
+		return nil, values, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("expected object")
+	}
+
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		key := tok.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, key)
+		values[key] = value
+	}
+	return keys, values, nil
+}
+
+func (d typeDef) properties() ([]property, error) {
+	keys, raw, err := orderedObject(d.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	props := make([]property, 0, len(keys))
+	for _, key := range keys {
+		var def typeDef
+		if err := json.Unmarshal(raw[key], &def); err != nil {
+			return nil, fmt.Errorf("property %s: %w", key, err)
+		}
+		props = append(props, property{Name: key, Def: def, Required: slices.Contains(d.Required, key)})
+	}
+	return props, nil
+}
+
+// types returns the JSON types of d and whether null is one of them
+func (d typeDef) types() (primary string, nullable bool) {
+	var single string
+	if err := json.Unmarshal(d.Type, &single); err == nil {
+		return single, single == "null"
+	}
+
+	var multiple []string
+	_ = json.Unmarshal(d.Type, &multiple)
+	for _, t := range multiple {
+		if t == "null" {
+			nullable = true
+		} else if primary == "" {
+			primary = t
+		}
+	}
+	return primary, nullable
+}
+
+// splitOneOf separates a nullable oneOf into its non-null variant
+func (d typeDef) splitOneOf() (variant typeDef, nullable bool) {
+	for _, option := range d.OneOf {
+		if t, _ := option.types(); t == "null" {
+			nullable = true
+		} else {
+			variant = option
+		}
+	}
+	return variant, nullable
+}
+
+func refName(ref string) string {
+	return strings.TrimPrefix(ref, "#/$defs/")
+}
+
+// pascalCase turns USER_JOINED into UserJoined and userId into UserID
+func pascalCase(name string) string {
+	var words []string
+	if strings.Contains(name, "_") || name == strings.ToUpper(name) {
+		words = strings.Split(strings.ToLower(name), "_")
+	} else {
+		start := 0
+		for i := 1; i < len(name); i++ {
+			if name[i] >= 'A' && name[i] <= 'Z' {
+				words = append(words, strings.ToLower(name[start:i]))
+				start = i
+			}
+		}
+		words = append(words, strings.ToLower(name[start:]))
+	}
+
+	var b strings.Builder
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// goFieldName applies Go initialism conventions to JSON property names
+func goFieldName(name string) string {
+	field := pascalCase(name)
+	for _, initialism := range []string{"Id", "Url", "Uuid", "Ai"} {
+		if strings.HasSuffix(field, initialism) {
+			field = strings.TrimSuffix(field, initialism) + strings.ToUpper(initialism)
+		} else if strings.HasSuffix(field, initialism+"s") {
+			field = strings.TrimSuffix(field, initialism+"s") + strings.ToUpper(initialism) + "s"
+		}
+	}
+	return field
+}
+
+func (p *protocol) goType(d typeDef) string {
+	if d.Ref != "" {
+		name := refName(d.Ref)
+		if target := p.defMap[name]; target.GoType != "" {
+			return target.GoType
+		}
+		return name
+	}
+
+	if len(d.OneOf) > 0 {
+		variant, nullable := d.splitOneOf()
+		base := p.goType(variant)
+		if nullable && !strings.HasPrefix(base, "*") {
+			return "*" + base
+		}
+		return base
+	}
+
+	primary, nullable := d.types()
+	var base string
+	switch primary {
+	case "string":
+		if d.Format == "date-time" {
+			base = "time.Time"
+		} else {
+			base = "string"
+		}
+	case "integer":
+		if d.Format == "uint64" {
+			base = "uint64"
+		} else {
+			base = "int"
+		}
+	case "number":
+		base = "float64"
+	case "boolean":
+		base = "bool"
+	case "array":
+		return "[]" + p.goType(*d.Items)
+	default:
+		return "map[string]interface{}"
+	}
+
+	if nullable {
+		return "*" + base
+	}
+	return base
+}
+
+func (p *protocol) generateGo(pkg string) ([]byte, error) {
+	var body strings.Builder
+
+	fmt.Fprintf(&body, "const (\n\tProtocolVersion = %d\n\tMinProtocolVersion = %d\n)\n\n", p.version, p.minVersion)
+
+	body.WriteString("const (\n")
+	for _, name := range p.messages {
+		fmt.Fprintf(&body, "\tMessageType%s = %q\n", pascalCase(name), name)
+	}
+	body.WriteString(")\n\n")
+
+	// An enum may be used by several properties but is declared once
+	enums := make(map[string]bool)
+	for _, name := range p.defs {
+		def := p.defMap[name]
+		props, err := def.properties()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		for _, prop := range props {
+			if prop.Def.EnumName == "" || enums[prop.Def.EnumName] {
+				continue
+			}
+			enums[prop.Def.EnumName] = true
+			body.WriteString("const (\n")
+			for _, value := range prop.Def.Enum {
+				fmt.Fprintf(&body, "\t%s%s = %q\n", prop.Def.EnumName, pascalCase(value), value)
+			}
+			body.WriteString(")\n\n")
+		}
+	}
+
+	for _, name := range p.defs {
+		def := p.defMap[name]
+		if def.GoType != "" {
+			// Defined elsewhere in the Go code base
+			continue
+		}
+		props, err := def.properties()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if def.Description != "" {
+			fmt.Fprintf(&body, "// %s %s\n", name, lowerFirst(def.Description))
+		}
+		if len(props) == 0 {
+			fmt.Fprintf(&body, "type %s struct{}\n\n", name)
+			continue
+		}
+		fmt.Fprintf(&body, "type %s struct {\n", name)
+		for _, prop := range props {
+			tag := prop.Name
+			if !prop.Required {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&body, "\t%s %s `json:%q`\n", goFieldName(prop.Name), p.goType(prop.Def), tag)
+		}
+		body.WriteString("}\n\n")
+	}
+
+	var imports []string
+	if strings.Contains(body.String(), "time.") {
+		imports = append(imports, `"time"`)
+	}
+	if strings.Contains(body.String(), "models.") {
+		imports = append(imports, `"kitchenmix/api/internal/models"`)
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s\n\npackage %s\n\n", generatedHeader, pkg)
+	if len(imports) > 0 {
+		fmt.Fprintf(&out, "import (\n\t%s\n)\n\n", strings.Join(imports, "\n\t"))
+	}
+	out.WriteString(body.String())
+
+	return format.Source([]byte(out.String()))
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func (p *protocol) tsType(d typeDef) string {
+	if d.Ref != "" {
+		return refName(d.Ref)
+	}
+
+	if len(d.OneOf) > 0 {
+		variant, nullable := d.splitOneOf()
+		if nullable {
+			return p.tsType(variant) + " | null"
+		}
+		return p.tsType(variant)
+	}
+
+	primary, nullable := d.types()
+	var base string
+	switch primary {
+	case "string":
+		base = "string"
+		if d.EnumName != "" {
+			base = d.EnumName
+		}
+	case "integer", "number":
+		base = "number"
+	case "boolean":
+		base = "boolean"
+	case "array":
+		item := p.tsType(*d.Items)
+		if strings.Contains(item, "|") {
+			item = "(" + item + ")"
+		}
+		base = item + "[]"
+	default:
+		base = "Record<string, unknown>"
+	}
+
+	if nullable {
+		return base + " | null"
+	}
+	return base
+}
+
+func (p *protocol) generateTS() []byte {
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "%s\n\n", generatedHeader)
+	fmt.Fprintf(&out, "export const PROTOCOL_VERSION = %d\nexport const MIN_PROTOCOL_VERSION = %d\n\n", p.version, p.minVersion)
+
+	writeUnion := func(name string, values []string) {
+		fmt.Fprintf(&out, "export type %s =\n", name)
+		for _, value := range values {
+			fmt.Fprintf(&out, "  | '%s'\n", value)
+		}
+		out.WriteString("\n")
+	}
+
+	writeUnion("WSMessageType", p.messages)
+
+	var clientTypes, serverTypes []string
+	for _, name := range p.messages {
+		switch p.messageDef[name].Direction {
+		case "client":
+			clientTypes = append(clientTypes, name)
+		case "server":
+			serverTypes = append(serverTypes, name)
+		default:
+			clientTypes = append(clientTypes, name)
+			serverTypes = append(serverTypes, name)
+		}
+	}
+	writeUnion("ClientMessageType", clientTypes)
+	writeUnion("ServerMessageType", serverTypes)
+
+	enums := make(map[string]bool)
+	for _, name := range p.defs {
+		props, _ := p.defMap[name].properties()
+		for _, prop := range props {
+			if prop.Def.EnumName != "" && !enums[prop.Def.EnumName] {
+				enums[prop.Def.EnumName] = true
+				writeUnion(prop.Def.EnumName, prop.Def.Enum)
+			}
+		}
+	}
+
+	for _, name := range p.defs {
+		def := p.defMap[name]
+		props, _ := def.properties()
+
+		if def.Description != "" {
+			fmt.Fprintf(&out, "// %s %s\n", name, def.Description)
+		}
+		if len(props) == 0 {
+			fmt.Fprintf(&out, "export type %s = Record<string, never>\n\n", name)
+			continue
+		}
+		fmt.Fprintf(&out, "export interface %s {\n", name)
+		for _, prop := range props {
+			optional := ""
+			if !prop.Required {
+				optional = "?"
+			}
+			fmt.Fprintf(&out, "  %s%s: %s\n", prop.Name, optional, p.tsType(prop.Def))
+		}
+		out.WriteString("}\n\n")
+	}
+
+	out.WriteString("export interface WSPayloadMap {\n")
+	for _, name := range p.messages {
+		fmt.Fprintf(&out, "  %s: %s\n", name, p.messageDef[name].Payload)
+	}
+	out.WriteString("}\n")
+
+	return []byte(out.String())
+}
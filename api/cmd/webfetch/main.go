@@ -1,14 +1,23 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"slices"
+	"strings"
 
 	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/exporter"
 	"kitchenmix/api/internal/services/recipe"
 )
 
+// console receives progress and results. It is stderr while an -export is
+// written to stdout, so the export can be piped or redirected on its own.
+var console io.Writer = os.Stdout
+
 // getEnv gets environment variable with default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -19,17 +28,17 @@ func getEnv(key, defaultValue string) string {
 
 // displayRecipe displays the extracted recipe in a formatted way
 func displayRecipe(recipe *models.Recipe) {
-	fmt.Printf("✅ Recipe Name: %s\n", recipe.Name)
+	fmt.Fprintf(console, "✅ Recipe Name: %s\n", recipe.Name)
 
 	// Display image if available
 	if recipe.Image != nil {
-		fmt.Printf("🖼️  Recipe Image: %s\n", *recipe.Image)
+		fmt.Fprintf(console, "🖼️  Recipe Image: %s\n", *recipe.Image)
 	} else {
-		fmt.Printf("🖼️  Recipe Image: No image found\n")
+		fmt.Fprintf(console, "🖼️  Recipe Image: No image found\n")
 	}
 
 	// Display ingredients
-	fmt.Printf("🥕 Found %d ingredients:\n", len(recipe.Ingredients))
+	fmt.Fprintf(console, "🥕 Found %d ingredients:\n", len(recipe.Ingredients))
 
 	for i, ingredient := range recipe.Ingredients {
 		quantity := "null"
@@ -42,37 +51,119 @@ func displayRecipe(recipe *models.Recipe) {
 			unit = *ingredient.Unit
 		}
 
-		fmt.Printf("  %d. %s (Quantity: %s, Unit: %s)\n",
+		fmt.Fprintf(console, "  %d. %s (Quantity: %s, Unit: %s)\n",
 			i+1, ingredient.Name, quantity, unit)
 	}
 
 	// Display source URL
-	fmt.Printf("🔗 Source: %s\n", recipe.URL)
+	fmt.Fprintf(console, "🔗 Source: %s\n", recipe.URL)
 
 	// Display sharer information
-	fmt.Printf("👤 Added by: %s (ID: %s)\n", recipe.SharerName, recipe.SharerID)
+	fmt.Fprintf(console, "👤 Added by: %s (ID: %s)\n", recipe.SharerName, recipe.SharerID)
 
 	// Display timestamps
-	fmt.Printf("📅 Added: %s\n", recipe.CreatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(console, "📅 Added: %s\n", recipe.CreatedAt.Format("2006-01-02 15:04:05"))
 
 	// Full Recipe struct for debugging
-	fmt.Printf("\n📋 Full Recipe Structure:\n%+v\n", recipe)
+	fmt.Fprintf(console, "\n📋 Full Recipe Structure:\n%+v\n", recipe)
+}
+
+// importFile extracts the recipes in a saved HTML page, MHTML archive or zip
+// of pages instead of fetching a live URL
+func importFile(service *recipe.RecipeService, path string, mixId string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("❌ Failed to read %s: %v", path, err)
+	}
+
+	pages, err := recipe.ReadSavedPages(path, data)
+	if err != nil {
+		log.Fatalf("❌ Failed to read pages from %s: %v", path, err)
+	}
+
+	fmt.Fprintf(console, "🚀 Starting recipe extraction from %d saved pages in: %s\n", len(pages), path)
+
+	result := service.ImportPages(pages, mixId, "webfetch-cli", "WebFetch CLI", func(phase, status, message string) {
+		fmt.Fprintf(console, "📊 %s: %s - %s\n", phase, status, message)
+	})
+
+	for _, recipe := range result.Added {
+		displayRecipe(recipe)
+	}
+	for _, skipped := range result.Skipped {
+		fmt.Fprintf(console, "⏭️  Skipped %s: %s\n", skipped.Name, skipped.Reason)
+	}
+	for _, failed := range result.Failed {
+		fmt.Fprintf(console, "❌ Failed %s: %s\n", failed.Name, failed.Reason)
+	}
+}
+
+// exportRecipes writes the recipes extracted in this run in an export format,
+// to path or to stdout when path is empty
+func exportRecipes(service *recipe.RecipeService, format string, path string, title string, mixId string) {
+	recipes := service.GetMixRecipes(mixId)
+	slices.SortStableFunc(recipes, func(a, b *models.Recipe) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	out := os.Stdout
+	if path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			log.Fatalf("❌ Failed to create %s: %v", path, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := exporter.Export(out, format, title, recipes); err != nil {
+		log.Fatalf("❌ Failed to export recipes: %v", err)
+	}
+	if path != "" {
+		fmt.Fprintf(console, "💾 Exported %d recipes as %s to %s\n", len(recipes), format, path)
+	}
 }
 
 func main() {
+	file := flag.String("file", "", "extract from a saved HTML page, MHTML archive or zip of pages instead of TARGET_URL")
+	export := flag.String("export", "", "export the extracted recipes as "+strings.Join(exporter.Formats(), ", "))
+	out := flag.String("out", "", "write the -export to this file instead of stdout")
+	title := flag.String("title", "WebFetch recipes", "title of the -export")
+	flag.Parse()
+
+	if *export != "" && *out == "" {
+		console = os.Stderr
+	}
+
+	if *export != "" {
+		if _, err := exporter.Lookup(*export); err != nil {
+			log.Fatalf("❌ -export must be one of %s", strings.Join(exporter.Formats(), ", "))
+		}
+	}
+
 	// Create recipe service
 	service := recipe.NewRecipeService()
 
+	// For webfetch, we use default sharer info since it's a standalone CLI tool
+	mixId := getEnv("MIX_ID", "webfetch-cli") // Default mixId for webfetch
+
+	if *file != "" {
+		importFile(service, *file, mixId)
+		if *export != "" {
+			exportRecipes(service, *export, *out, *title, mixId)
+		}
+		fmt.Fprintln(console, "🏁 Webfetch prototype completed")
+		return
+	}
+
 	// Target URL - can be overridden via environment variable
 	targetURL := getEnv("TARGET_URL", "https://www.theguardian.com/food/2025/oct/11/meera-sodha-recipe-zaatar-roast-vegetables-whipped-feta")
 
-	fmt.Printf("🚀 Starting recipe extraction from: %s\n", targetURL)
+	fmt.Fprintf(console, "🚀 Starting recipe extraction from: %s\n", targetURL)
 
 	// Extract recipe using service with progress callback
-	// For webfetch, we use default sharer info since it's a standalone CLI tool
-	mixId := getEnv("MIX_ID", "webfetch-cli") // Default mixId for webfetch
 	recipe, err := service.GetRecipeByURL(targetURL, mixId, "webfetch-cli", "WebFetch CLI", func(phase, status, message string) {
-		fmt.Printf("📊 %s: %s - %s\n", phase, status, message)
+		fmt.Fprintf(console, "📊 %s: %s - %s\n", phase, status, message)
 	})
 
 	if err != nil {
@@ -82,5 +173,9 @@ func main() {
 	// Display results
 	displayRecipe(recipe)
 
-	fmt.Println("🏁 Webfetch prototype completed")
+	if *export != "" {
+		exportRecipes(service, *export, *out, *title, mixId)
+	}
+
+	fmt.Fprintln(console, "🏁 Webfetch prototype completed")
 }
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/identity"
+	"kitchenmix/api/internal/services/mix"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// authenticate verifies the identity token in the Authorization: Bearer header,
+// responding with 401 when it is missing or invalid
+func authenticate(c *gin.Context) (identity.Identity, bool) {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "An identity token is required",
+		})
+		return identity.Identity{}, false
+	}
+
+	ident, err := ws.Identities.Verify(token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "invalid_token",
+			"message": err.Error(),
+		})
+		return identity.Identity{}, false
+	}
+	return ident, true
+}
+
+// authorize authenticates the request and checks that the user has at least
+// the required role in the mix, responding with 404 or 403 otherwise
+func authorize(c *gin.Context, mixID string, required string) (models.MixMember, bool) {
+	ident, ok := authenticate(c)
+	if !ok {
+		return models.MixMember{}, false
+	}
+
+	member, err := ws.Mixes.Member(mixID, ident.UserID)
+	if errors.Is(err, mix.ErrNotMember) || (err == nil && !mix.RoleAllows(member.Role, required)) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "forbidden",
+			"message": "This requires the " + required + " role in the mix",
+		})
+		return models.MixMember{}, false
+	}
+	if err != nil {
+		respondMixError(c, err)
+		return models.MixMember{}, false
+	}
+	return member, true
+}
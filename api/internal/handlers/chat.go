@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"kitchenmix/api/internal/models"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// ChatHistoryResponse is a page of chat history, oldest message first
+type ChatHistoryResponse struct {
+	Messages []models.ChatMessage `json:"messages"`
+	HasMore  bool                 `json:"hasMore"`
+}
+
+// GetChatHistory returns a page of a mix's chat history to its members. Pass the ID of the
+// oldest message already loaded as ?before= to page backwards.
+func GetChatHistory(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "ID must be a valid UUID",
+		})
+		return
+	}
+
+	if _, ok := authorize(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	limit := 0
+	if v := c.Query("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_limit",
+				"message": "limit must be a positive integer",
+			})
+			return
+		}
+		limit = parsed
+	}
+
+	messages, hasMore := ws.Chat.History(id, c.Query("before"), limit)
+	c.JSON(http.StatusOK, ChatHistoryResponse{
+		Messages: messages,
+		HasMore:  hasMore,
+	})
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/cookbook"
+	"kitchenmix/api/internal/services/images"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// GetMixCookbook renders the recipes in a mix, or the comma-separated
+// ?recipes=, as a standalone HTML document to print. ?scale= multiplies the
+// quantities and ?shopping=false leaves out the shopping list page, which
+// leaves out what is in the mix's pantry unless ?pantry=false. Stored images
+// are embedded so the page prints the same offline.
+func GetMixCookbook(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	scale := 1.0
+	if raw := c.Query("scale"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed <= 0 || parsed > cookbook.MaxScale {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_scale",
+				"message": "scale must be a number above 0 and at most " + strconv.Itoa(cookbook.MaxScale),
+			})
+			return
+		}
+		scale = parsed
+	}
+
+	recipes, ok := selectRecipes(c, id)
+	if !ok {
+		return
+	}
+	mix, err := ws.Mixes.Get(id)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+
+	options := cookbook.Options{
+		Title:        mix.Title,
+		Scale:        scale,
+		ShoppingList: c.Query("shopping") != "false",
+		Image:        embeddedImage,
+	}
+	if c.Query("pantry") != "false" {
+		options.Pantry = ws.Pantries.Items(id)
+	}
+
+	var body bytes.Buffer
+	err = cookbook.Render(&body, options, recipes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", body.Bytes())
+}
+
+// embeddedImage inlines a recipe's stored image as a data: URL, falling back
+// to where it was found when it isn't stored here
+func embeddedImage(recipe *models.Recipe) string {
+	if recipe.Image != nil {
+		if imageID, ok := images.IDFromURL(*recipe.Image); ok {
+			if file, contentType, err := ws.Recipes.Images().Open(imageID, false); err == nil {
+				defer file.Close()
+				if data, err := io.ReadAll(file); err == nil {
+					return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data)
+				}
+			}
+		}
+	}
+	if recipe.ImageSource != nil {
+		return *recipe.ImageSource
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/exporter"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// ExportMix downloads the recipes in a mix as ?format=jsonld, markdown,
+// shopping-list, csv or paprika. ?recipes= limits the export to a
+// comma-separated list of recipe IDs; otherwise every recipe is exported,
+// oldest first.
+func ExportMix(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	format, err := exporter.Lookup(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_format",
+			"message": "format must be one of " + strings.Join(exporter.Formats(), ", "),
+		})
+		return
+	}
+
+	recipes, ok := selectRecipes(c, id)
+	if !ok {
+		return
+	}
+	mix, err := ws.Mixes.Get(id)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := format.Write(&body, mix.Title, recipes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": format.Filename(mix.Title)}))
+	c.Data(http.StatusOK, format.ContentType, body.Bytes())
+}
+
+// selectRecipes returns the recipes named in ?recipes=, or all of the mix's
+// recipes, oldest first. Unknown IDs respond with 404.
+func selectRecipes(c *gin.Context, mixID string) ([]*models.Recipe, bool) {
+	var recipes []*models.Recipe
+	if ids := c.Query("recipes"); ids != "" {
+		for _, recipeID := range strings.Split(ids, ",") {
+			selected, err := ws.Recipes.GetMixRecipe(mixID, strings.TrimSpace(recipeID))
+			if err != nil {
+				respondRecipeError(c, err, nil)
+				return nil, false
+			}
+			if !slices.Contains(recipes, selected) {
+				recipes = append(recipes, selected)
+			}
+		}
+	} else {
+		recipes = ws.Recipes.GetMixRecipes(mixID)
+	}
+
+	slices.SortStableFunc(recipes, func(a, b *models.Recipe) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return recipes, true
+}
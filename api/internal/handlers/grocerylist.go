@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/grocerylist"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// GroceryListResponse lists a mix's grocery list in the order items were added
+type GroceryListResponse struct {
+	Items []models.GroceryListItem `json:"items"`
+}
+
+// ClearCheckedResponse lists the items taken off a grocery list
+type ClearCheckedResponse struct {
+	ItemIDs []string `json:"itemIds"`
+}
+
+// GroceryListFromPlanResponse lists the items POST
+// /mixes/:id/grocery-list/from-plan put on the grocery list, and those left
+// off, e.g. because they were already on it
+type GroceryListFromPlanResponse struct {
+	From    string                   `json:"from"`
+	To      string                   `json:"to"`
+	Items   []models.GroceryListItem `json:"items"`
+	Skipped []ws.SkippedGroceryItem  `json:"skipped"`
+}
+
+// AddGroceryItemRequest is the body of POST /mixes/:id/grocery-list
+type AddGroceryItemRequest struct {
+	Name        string              `json:"name" binding:"required"`
+	GroceryItem *models.GroceryItem `json:"groceryItem"`
+	Quantity    *string             `json:"quantity"`
+	Unit        *string             `json:"unit"`
+	AssigneeID  string              `json:"assigneeId"`
+}
+
+// UpdateGroceryItemRequest is the body of PATCH
+// /mixes/:id/grocery-list/:itemId. Version is the version the edit is based
+// on; omitted fields are unchanged and empty ones cleared.
+type UpdateGroceryItemRequest struct {
+	Version    int     `json:"version" binding:"required"`
+	Name       *string `json:"name"`
+	Quantity   *string `json:"quantity"`
+	Unit       *string `json:"unit"`
+	AssigneeID *string `json:"assigneeId"`
+}
+
+// CheckGroceryItemRequest is the body of PUT /mixes/:id/grocery-list/:itemId/checked
+type CheckGroceryItemRequest struct {
+	Checked *bool `json:"checked" binding:"required"`
+}
+
+// GetMixGroceryList lists a mix's grocery list
+func GetMixGroceryList(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, GroceryListResponse{Items: ws.GroceryLists.Items(id)})
+}
+
+// AddMixGroceryItem adds an item to the grocery list, added by the caller,
+// and sends it to everyone in the mix
+func AddMixGroceryItem(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	member, ok := authorize(c, id, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var req AddGroceryItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with a name",
+		})
+		return
+	}
+
+	added, err := ws.AddGroceryItem(id, models.GroceryListItem{
+		Name:        req.Name,
+		GroceryItem: req.GroceryItem,
+		Quantity:    req.Quantity,
+		Unit:        req.Unit,
+		AssigneeID:  req.AssigneeID,
+		AddedByID:   member.UserID,
+		AddedByName: member.UserName,
+	}, "")
+	if err != nil {
+		respondGroceryListError(c, err, models.GroceryListItem{})
+		return
+	}
+
+	c.JSON(http.StatusCreated, added)
+}
+
+// AddMixGroceryItemsFromPlan puts what the recipes planned ?from= one date
+// ?to= another need on the grocery list, as GET
+// /mixes/:id/plan/shopping-list lists them, and tells everyone in the mix.
+// Items already on the list and not yet ticked off are skipped, so filling
+// the list twice doesn't buy twice, as are items too long for the list.
+func AddMixGroceryItemsFromPlan(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	member, ok := authorize(c, id, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	list, ok := planShoppingList(c, id)
+	if !ok {
+		return
+	}
+
+	added, skipped, err := ws.AddShoppingItems(id, list.Items, member.UserID, member.UserName, "")
+	if err != nil {
+		respondGroceryListError(c, err, models.GroceryListItem{})
+		return
+	}
+
+	c.JSON(http.StatusCreated, GroceryListFromPlanResponse{From: list.From, To: list.To, Items: added, Skipped: skipped})
+}
+
+// UpdateMixGroceryItem edits a grocery list item and sends its new state to
+// everyone in the mix. Edits based on an outdated version get 409 with the
+// current item.
+func UpdateMixGroceryItem(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleEditor); !ok {
+		return
+	}
+
+	var req UpdateGroceryItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with the version being edited",
+		})
+		return
+	}
+
+	updated, err := ws.UpdateGroceryItem(id, c.Param("itemId"), req.Version, grocerylist.ItemUpdate{
+		Name:       req.Name,
+		Quantity:   req.Quantity,
+		Unit:       req.Unit,
+		AssigneeID: req.AssigneeID,
+	}, "")
+	if err != nil {
+		respondGroceryListError(c, err, updated)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// CheckMixGroceryItem ticks a grocery list item off as bought by the
+// caller, or unticks it, and tells everyone in the mix
+func CheckMixGroceryItem(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	member, ok := authorize(c, id, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var req CheckGroceryItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with checked",
+		})
+		return
+	}
+
+	checked, err := ws.CheckGroceryItem(id, c.Param("itemId"), *req.Checked, member.UserID, member.UserName, "")
+	if err != nil {
+		respondGroceryListError(c, err, models.GroceryListItem{})
+		return
+	}
+
+	c.JSON(http.StatusOK, checked)
+}
+
+// DeleteMixGroceryItem takes an item off the grocery list and tells
+// everyone in the mix
+func DeleteMixGroceryItem(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleEditor); !ok {
+		return
+	}
+
+	if err := ws.RemoveGroceryItem(id, c.Param("itemId"), ""); err != nil {
+		respondGroceryListError(c, err, models.GroceryListItem{})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ClearMixGroceryListChecked takes every checked item off the grocery list
+// and tells everyone in the mix
+func ClearMixGroceryListChecked(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleEditor); !ok {
+		return
+	}
+
+	removed := ws.ClearCheckedGroceryItems(id, "")
+	if removed == nil {
+		removed = []string{}
+	}
+	c.JSON(http.StatusOK, ClearCheckedResponse{ItemIDs: removed})
+}
+
+// respondGroceryListError maps grocery list failures to responses; current
+// is the item as it is now on a version conflict
+func respondGroceryListError(c *gin.Context, err error, current models.GroceryListItem) {
+	switch {
+	case errors.Is(err, grocerylist.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "version_conflict",
+			"message": err.Error(),
+			"item":    current,
+		})
+	case errors.Is(err, grocerylist.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "grocery_item_not_found",
+			"message": "Item is not on this mix's grocery list",
+		})
+	case errors.Is(err, ws.ErrInvalidAssignee):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_assignee",
+			"message": err.Error(),
+		})
+	case errors.Is(err, grocerylist.ErrInvalidName), errors.Is(err, grocerylist.ErrInvalidQuantity):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_grocery_item",
+			"message": err.Error(),
+		})
+	case errors.Is(err, grocerylist.ErrListFull):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "grocery_list_full",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/services/identity"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// GuestIdentityRequest names the guest. Passing a previously issued token keeps
+// its user ID, which is how guests rename themselves.
+type GuestIdentityRequest struct {
+	Name  string `json:"name"`
+	Token string `json:"token,omitempty"`
+}
+
+// IdentityResponse is a signed identity to present on USER_IDENTIFY
+type IdentityResponse struct {
+	identity.Identity
+	Token string `json:"token"`
+}
+
+// MintGuestIdentity issues a signed guest identity
+func MintGuestIdentity(c *gin.Context) {
+	var req GuestIdentityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with a name",
+		})
+		return
+	}
+
+	userID := ""
+	if req.Token != "" {
+		existing, err := ws.Identities.Verify(req.Token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "invalid_token",
+				"message": err.Error(),
+			})
+			return
+		}
+		userID = existing.UserID
+	}
+
+	var (
+		ident identity.Identity
+		token string
+		err   error
+	)
+	if userID != "" {
+		ident, token, err = ws.Identities.Mint(userID, req.Name)
+	} else {
+		ident, token, err = ws.Identities.MintGuest(req.Name)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_name",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, IdentityResponse{Identity: ident, Token: token})
+}
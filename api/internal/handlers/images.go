@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// GetImage serves a stored recipe image, or its thumbnail with ?size=thumb
+func GetImage(c *gin.Context) {
+	file, contentType, err := ws.Recipes.Images().Open(c.Param("id"), c.Query("size") == "thumb")
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not_found",
+			"message": "Image not found",
+		})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "read_failed",
+			"message": "Failed to read image",
+		})
+		return
+	}
+
+	// Image IDs are content hashes, so the bytes behind an ID never change
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, info.Size(), contentType, file, nil)
+}
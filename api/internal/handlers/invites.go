@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// CreateInviteRequest is the body of POST /mixes/:id/invites. ExpiresIn is in
+// seconds; zero or omitted never expires, as does MaxUses for unlimited joins.
+type CreateInviteRequest struct {
+	Role      string `json:"role"`
+	ExpiresIn int    `json:"expiresIn"`
+	MaxUses   int    `json:"maxUses"`
+}
+
+// JoinMixRequest is the body of POST /mixes/:id/join
+type JoinMixRequest struct {
+	Token string `json:"token"`
+}
+
+// InvitesResponse lists a mix's invites, including revoked ones
+type InvitesResponse struct {
+	Invites []models.Invite `json:"invites"`
+}
+
+// CreateInvite lets the owner create an invite link for a mix
+func CreateInvite(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	owner, ok := authorize(c, id, models.RoleOwner)
+	if !ok {
+		return
+	}
+
+	var req CreateInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with a role",
+		})
+		return
+	}
+
+	invite, err := ws.Mixes.CreateInvite(id, owner.UserID, req.Role, time.Duration(req.ExpiresIn)*time.Second, req.MaxUses)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, invite)
+}
+
+// ListInvites lets the owner see a mix's invites and how often they were used
+func ListInvites(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleOwner); !ok {
+		return
+	}
+
+	found, err := ws.Mixes.Get(id)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+
+	invites := found.Invites
+	if invites == nil {
+		invites = []models.Invite{}
+	}
+	c.JSON(http.StatusOK, InvitesResponse{Invites: invites})
+}
+
+// RevokeInvite lets the owner revoke an invite. Members who joined through it
+// lose their membership and are disconnected.
+func RevokeInvite(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleOwner); !ok {
+		return
+	}
+
+	removed, err := ws.Mixes.RevokeInvite(id, c.Param("inviteId"))
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+	ws.Pool.CloseUsers(id, removed, ws.CloseMembershipRevoked, "invite revoked")
+
+	c.Status(http.StatusNoContent)
+}
+
+// JoinMix exchanges an invite token for membership of a mix
+func JoinMix(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	ident, ok := authenticate(c)
+	if !ok {
+		return
+	}
+
+	var req JoinMixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with a token",
+		})
+		return
+	}
+
+	member, err := ws.Mixes.Redeem(id, req.Token, ident.UserID, ident.UserName)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
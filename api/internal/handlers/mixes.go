@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/mix"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// CreateMixRequest is the body of POST /mixes
+type CreateMixRequest struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// UpdateMixRequest is the body of PATCH /mixes/:id; omitted fields are unchanged
+type UpdateMixRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+}
+
+// UpdateMemberRequest is the body of PATCH /mixes/:id/members/:userId
+type UpdateMemberRequest struct {
+	Role string `json:"role"`
+}
+
+// MixResponse is a mix's metadata along with how many recipes it holds
+type MixResponse struct {
+	*models.Mix
+	RecipeCount int `json:"recipeCount"`
+}
+
+// MixRecipesResponse lists the recipes in a mix
+type MixRecipesResponse struct {
+	Recipes []*models.Recipe `json:"recipes"`
+}
+
+// CreateMix creates a mix owned by the caller that clients can then connect to
+func CreateMix(c *gin.Context) {
+	owner, ok := authenticate(c)
+	if !ok {
+		return
+	}
+
+	var req CreateMixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with a title",
+		})
+		return
+	}
+
+	created, err := ws.Mixes.Create(req.Title, req.Description, owner.UserID, owner.UserName)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, MixResponse{Mix: created})
+}
+
+// GetMix returns a mix's metadata to its members; only the owner sees invites
+func GetMix(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	member, ok := authorize(c, id, models.RoleViewer)
+	if !ok {
+		return
+	}
+
+	found, err := ws.Mixes.Get(id)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+	if member.Role != models.RoleOwner {
+		found.Invites = nil
+	}
+
+	c.JSON(http.StatusOK, MixResponse{Mix: found, RecipeCount: ws.Recipes.GetMixRecipeCount(id)})
+}
+
+// UpdateMix lets the owner rename a mix or change its description
+func UpdateMix(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleOwner); !ok {
+		return
+	}
+
+	var req UpdateMixRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON",
+		})
+		return
+	}
+
+	updated, err := ws.Mixes.Update(id, req.Title, req.Description)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MixResponse{Mix: updated, RecipeCount: ws.Recipes.GetMixRecipeCount(id)})
+}
+
+// DeleteMix lets the owner delete a mix with its recipes and chat,
+// disconnecting everyone in it
+func DeleteMix(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleOwner); !ok {
+		return
+	}
+
+	if err := ws.Mixes.Delete(id); err != nil {
+		respondMixError(c, err)
+		return
+	}
+	ws.Recipes.ClearMix(id)
+	ws.Chat.Delete(id)
+	ws.Plans.Delete(id)
+	ws.GroceryLists.Delete(id)
+	ws.Pantries.Delete(id)
+	ws.Pool.CloseMix(id, ws.CloseMixDeleted, "mix deleted")
+
+	c.Status(http.StatusNoContent)
+}
+
+// UpdateMember lets the owner change another member's role. Connections of
+// that member pick up the new role immediately.
+func UpdateMember(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleOwner); !ok {
+		return
+	}
+
+	var req UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with a role",
+		})
+		return
+	}
+
+	member, err := ws.Mixes.SetRole(id, c.Param("userId"), req.Role)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+	ws.Pool.SetRole(id, member.UserID, member.Role)
+
+	c.JSON(http.StatusOK, member)
+}
+
+// GetMixRecipes lists the recipes in a mix
+func GetMixRecipes(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	recipes := ws.Recipes.GetMixRecipes(id)
+	if recipes == nil {
+		recipes = []*models.Recipe{}
+	}
+	c.JSON(http.StatusOK, MixRecipesResponse{Recipes: recipes})
+}
+
+// mixID validates the :id parameter, responding with 400 when it isn't a UUID
+func mixID(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_id",
+			"message": "ID must be a valid UUID",
+		})
+		return "", false
+	}
+	return id, true
+}
+
+func respondMixError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, mix.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "mix_not_found",
+			"message": "Mix does not exist",
+		})
+	case errors.Is(err, mix.ErrNotMember):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "member_not_found",
+			"message": "User is not a member of this mix",
+		})
+	case errors.Is(err, mix.ErrInvalidInvite):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "invite_not_found",
+			"message": err.Error(),
+		})
+	case errors.Is(err, mix.ErrInviteExpired), errors.Is(err, mix.ErrInviteUsedUp):
+		c.JSON(http.StatusGone, gin.H{
+			"error":   "invite_unavailable",
+			"message": err.Error(),
+		})
+	case errors.Is(err, mix.ErrInvalidTitle), errors.Is(err, mix.ErrInvalidDescription),
+		errors.Is(err, mix.ErrInvalidRole), errors.Is(err, mix.ErrInvalidInviteLimits):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_mix",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"message": err.Error(),
+		})
+	}
+}
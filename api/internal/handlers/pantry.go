@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/pantry"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// PantryResponse lists what a mix has in stock, by name
+type PantryResponse struct {
+	Items []models.PantryItem `json:"items"`
+}
+
+// PantryItemRequest is the body of POST /mixes/:id/pantry and PUT
+// /mixes/:id/pantry/:itemId. A null quantity makes the item a staple that is
+// always in stock. A grocery item is only kept if a recipe in the mix uses it.
+type PantryItemRequest struct {
+	Name        string              `json:"name" binding:"required"`
+	GroceryItem *models.GroceryItem `json:"groceryItem"`
+	Quantity    *string             `json:"quantity"`
+	Unit        *string             `json:"unit"`
+}
+
+// GetMixPantry lists a mix's pantry
+func GetMixPantry(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, PantryResponse{Items: ws.Pantries.Items(id)})
+}
+
+// AddMixPantryItem stocks the mix's pantry with an item and tells everyone
+// connected to the mix
+func AddMixPantryItem(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	member, ok := authorize(c, id, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	req, ok := bindPantryItem(c)
+	if !ok {
+		return
+	}
+
+	added, err := ws.AddPantryItem(id, req.item(member), "")
+	if err != nil {
+		respondPantryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, added)
+}
+
+// ReplaceMixPantryItem updates an item in the mix's pantry, e.g. as it is
+// used up, and tells everyone connected to the mix
+func ReplaceMixPantryItem(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	member, ok := authorize(c, id, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	req, ok := bindPantryItem(c)
+	if !ok {
+		return
+	}
+
+	replaced, err := ws.ReplacePantryItem(id, c.Param("itemId"), req.item(member), "")
+	if err != nil {
+		respondPantryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, replaced)
+}
+
+// DeleteMixPantryItem takes an item out of the mix's pantry and tells
+// everyone connected to the mix
+func DeleteMixPantryItem(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleEditor); !ok {
+		return
+	}
+
+	if err := ws.RemovePantryItem(id, c.Param("itemId"), ""); err != nil {
+		respondPantryError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func bindPantryItem(c *gin.Context) (PantryItemRequest, bool) {
+	var req PantryItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with a name",
+		})
+		return req, false
+	}
+	return req, true
+}
+
+// item is the pantry item a request describes, last updated by member
+func (r PantryItemRequest) item(member models.MixMember) models.PantryItem {
+	return models.PantryItem{
+		Name:          r.Name,
+		GroceryItem:   r.GroceryItem,
+		Quantity:      r.Quantity,
+		Unit:          r.Unit,
+		UpdatedByID:   member.UserID,
+		UpdatedByName: member.UserName,
+	}
+}
+
+// respondPantryError maps pantry failures to responses
+func respondPantryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, pantry.ErrItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "pantry_item_not_found",
+			"message": "Pantry item is not in this mix",
+		})
+	case errors.Is(err, pantry.ErrInvalidName), errors.Is(err, pantry.ErrInvalidQuantity):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_pantry_item",
+			"message": err.Error(),
+		})
+	case errors.Is(err, pantry.ErrItemExists):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "pantry_item_exists",
+			"message": err.Error(),
+		})
+	case errors.Is(err, pantry.ErrPantryFull):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "pantry_full",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/plan"
+	"kitchenmix/api/internal/services/recipe"
+	"kitchenmix/api/internal/services/shopping"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// defaultShoppingDays is how many days a shopping list covers when no range is given
+const defaultShoppingDays = 7
+
+// PlanResponse lists the entries of a mix's meal plan, by date and meal
+type PlanResponse struct {
+	Entries []models.PlanEntry `json:"entries"`
+}
+
+// PlanShoppingListResponse is what the recipes planned from one date to
+// another need, summed; recipes planned twice count twice. Items the pantry
+// has enough of are listed as stocked instead.
+type PlanShoppingListResponse struct {
+	From    string          `json:"from"`
+	To      string          `json:"to"`
+	Items   []shopping.Item `json:"items"`
+	Stocked []shopping.Item `json:"stocked"`
+}
+
+// AddPlanEntryRequest is the body of POST /mixes/:id/plan
+type AddPlanEntryRequest struct {
+	RecipeID string `json:"recipeId" binding:"required"`
+	Date     string `json:"date" binding:"required"`
+	Meal     string `json:"meal" binding:"required"`
+	Note     string `json:"note"`
+}
+
+// MovePlanEntryRequest is the body of PATCH /mixes/:id/plan/:entryId
+type MovePlanEntryRequest struct {
+	Date string `json:"date" binding:"required"`
+	Meal string `json:"meal" binding:"required"`
+}
+
+// GetMixPlan lists a mix's meal plan, optionally ?from= and ?to= two dates
+func GetMixPlan(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	if err := plan.ValidateRange(from, to); err != nil {
+		respondPlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, PlanResponse{Entries: ws.Plans.Entries(id, from, to)})
+}
+
+// AddMixPlanEntry schedules a recipe in the mix for a meal on a date, added
+// by the caller, and sends the entry to everyone in the mix
+func AddMixPlanEntry(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	member, ok := authorize(c, id, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var req AddPlanEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with a recipeId, date and meal",
+		})
+		return
+	}
+
+	added, err := ws.AddPlanEntry(id, models.PlanEntry{
+		RecipeID:    req.RecipeID,
+		Date:        req.Date,
+		Meal:        req.Meal,
+		Note:        req.Note,
+		AddedByID:   member.UserID,
+		AddedByName: member.UserName,
+	}, "")
+	if err != nil {
+		respondPlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, added)
+}
+
+// MoveMixPlanEntry reschedules a plan entry and tells everyone in the mix
+func MoveMixPlanEntry(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleEditor); !ok {
+		return
+	}
+
+	var req MovePlanEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with a date and meal",
+		})
+		return
+	}
+
+	moved, err := ws.MovePlanEntry(id, c.Param("entryId"), req.Date, req.Meal, "")
+	if err != nil {
+		respondPlanError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, moved)
+}
+
+// DeleteMixPlanEntry takes an entry off the plan and tells everyone in the mix
+func DeleteMixPlanEntry(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleEditor); !ok {
+		return
+	}
+
+	if err := ws.RemovePlanEntry(id, c.Param("entryId"), ""); err != nil {
+		respondPlanError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetMixPlanShoppingList sums the ingredients of the recipes planned ?from=
+// one date ?to= another, by default the week starting today, less what is in
+// the mix's pantry unless ?pantry=false
+func GetMixPlanShoppingList(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	list, ok := planShoppingList(c, id)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// planShoppingList builds the shopping list for the ?from=, ?to= and
+// ?pantry= of a request, responding with an error if the range is invalid
+func planShoppingList(c *gin.Context, id string) (PlanShoppingListResponse, bool) {
+	from, to := c.Query("from"), c.Query("to")
+	if from == "" {
+		from = time.Now().Format(plan.DateLayout)
+	}
+	if to == "" {
+		if start, err := time.Parse(plan.DateLayout, from); err == nil {
+			to = start.AddDate(0, 0, defaultShoppingDays-1).Format(plan.DateLayout)
+		}
+	}
+	if err := plan.ValidateRange(from, to); err != nil {
+		respondPlanError(c, err)
+		return PlanShoppingListResponse{}, false
+	}
+
+	var recipes []*models.Recipe
+	for _, entry := range ws.Plans.Entries(id, from, to) {
+		if planned, err := ws.Recipes.GetMixRecipe(id, entry.RecipeID); err == nil {
+			recipes = append(recipes, planned)
+		}
+	}
+
+	items, stocked := shopping.Build(recipes), []shopping.Item{}
+	if c.Query("pantry") != "false" {
+		items, stocked = shopping.Subtract(items, ws.Pantries.Items(id))
+	}
+
+	return PlanShoppingListResponse{From: from, To: to, Items: items, Stocked: stocked}, true
+}
+
+// GetMixPlanCalendar downloads the meal plan, optionally ?from= and ?to= two
+// dates, as an iCalendar file to subscribe to or import
+func GetMixPlanCalendar(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleViewer); !ok {
+		return
+	}
+
+	from, to := c.Query("from"), c.Query("to")
+	if err := plan.ValidateRange(from, to); err != nil {
+		respondPlanError(c, err)
+		return
+	}
+	mix, err := ws.Mixes.Get(id)
+	if err != nil {
+		respondMixError(c, err)
+		return
+	}
+
+	recipes := make(map[string]*models.Recipe)
+	for _, planned := range ws.Recipes.GetMixRecipes(id) {
+		recipes[planned.ID] = planned
+	}
+
+	var body bytes.Buffer
+	if err := plan.WriteICS(&body, mix.Title, ws.Plans.Entries(id, from, to), recipes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "meal-plan.ics"}))
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", body.Bytes())
+}
+
+// respondPlanError maps meal plan failures to responses
+func respondPlanError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, plan.ErrEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "plan_entry_not_found",
+			"message": "Plan entry is not in this mix",
+		})
+	case errors.Is(err, recipe.ErrRecipeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "recipe_not_found",
+			"message": "Recipe is not in this mix",
+		})
+	case errors.Is(err, plan.ErrInvalidDate), errors.Is(err, plan.ErrInvalidRange):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_date",
+			"message": err.Error(),
+		})
+	case errors.Is(err, plan.ErrInvalidMeal), errors.Is(err, plan.ErrNoteTooLong):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_plan_entry",
+			"message": err.Error(),
+		})
+	case errors.Is(err, plan.ErrPlanFull):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "plan_full",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"message": err.Error(),
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// GetProtocolSchema serves the WebSocket protocol schema the client types are generated from
+func GetProtocolSchema(c *gin.Context) {
+	c.Data(http.StatusOK, "application/schema+json", ws.ProtocolSchema)
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/importer"
+	"kitchenmix/api/internal/services/recipe"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// RecipeTextRequest is the body of POST /mixes/:id/recipes/text. Name
+// overrides the one found in the text; with useAi ingredients are extracted
+// with AI, falling back to the line parser.
+type RecipeTextRequest struct {
+	Text  string `json:"text" binding:"required"`
+	Name  string `json:"name"`
+	UseAI bool   `json:"useAi"`
+}
+
+// AddMixRecipeFromText adds a recipe from pasted text, shared by the caller,
+// and sends it to everyone in the mix
+func AddMixRecipeFromText(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	member, ok := authorize(c, id, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	var req RecipeTextRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with the recipe text",
+		})
+		return
+	}
+
+	added, err := ws.AddRecipeFromText(id, req.Text, req.Name, req.UseAI, member.UserID, member.UserName, "", nil)
+	if err != nil {
+		respondRecipeError(c, err, nil)
+		return
+	}
+
+	c.JSON(http.StatusCreated, added)
+}
+
+// maxImportUploadBytes bounds a whole import upload, however many files it has
+const maxImportUploadBytes = 50 << 20
+
+// ImportMixRecipes adds the recipes in uploaded files to a mix, shared by the
+// caller. Files are sent as multipart "file" fields and may be exports from
+// Paprika, Mealie or Tandoor, schema.org JSON-LD, saved HTML pages, MHTML
+// archives, or zips of them. The files are read straight away, but recipes
+// are extracted in the background: the response is 202 with an importId, and
+// progress is sent to the caller's connections as RECIPE_IMPORT_PROGRESS. The
+// last message accounts for every recipe, as added, skipped because it is
+// already in the mix, or failed.
+func ImportMixRecipes(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	member, ok := authorize(c, id, models.RoleEditor)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportUploadBytes)
+	form, err := c.MultipartForm()
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "upload_too_large",
+				"message": "Uploads are limited to 50 MB",
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request must be a multipart form with one or more file fields",
+		})
+		return
+	}
+	files := form.File["file"]
+	if len(files) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request must be a multipart form with one or more file fields",
+		})
+		return
+	}
+
+	// The limits on pages and recipes apply to the whole request, not each file
+	var (
+		entries []importer.Entry
+		pages   []recipe.SavedPage
+	)
+	exportBudget, pageBudget := importer.NewBudget(), recipe.NewPageBudget()
+	unreadable := []recipe.ImportIssue{}
+	for _, file := range files {
+		foundEntries, foundPages, err := readImportFile(file, exportBudget, pageBudget)
+		if errors.Is(err, importer.ErrImportTooLarge) || errors.Is(err, recipe.ErrImportTooLarge) || len(entries)+len(foundEntries) > importer.MaxEntries {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "import_too_large",
+				"message": fmt.Sprintf("Imports are limited to %d recipes in %d MB of exports, %d saved pages and %d MB of pages", importer.MaxEntries, importer.MaxImportBytes>>20, recipe.MaxImportPages, recipe.MaxImportPageBytes>>20),
+			})
+			return
+		}
+		if err != nil {
+			unreadable = append(unreadable, recipe.ImportIssue{Name: file.Filename, Reason: err.Error()})
+			continue
+		}
+		entries = append(entries, foundEntries...)
+		pages = append(pages, foundPages...)
+	}
+
+	importID := uuid.New().String()
+	go ws.ImportRecipes(id, importID, entries, pages, unreadable, member.UserID, member.UserName)
+	c.JSON(http.StatusAccepted, gin.H{"importId": importID})
+}
+
+// readImportFile reads one uploaded file as a recipe manager export or,
+// failing that, as saved pages, spending the budget for each
+func readImportFile(file *multipart.FileHeader, exportBudget *importer.Budget, pageBudget *recipe.PageBudget) ([]importer.Entry, []recipe.SavedPage, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := exportBudget.Read(file.Filename, data)
+	if !errors.Is(err, importer.ErrUnknownFormat) {
+		return entries, nil, err
+	}
+	pages, err := pageBudget.Read(file.Filename, data)
+	if errors.Is(err, recipe.ErrUnsupportedPageFile) {
+		return nil, nil, errors.New("file is not a recipe export, saved page or zip of them")
+	}
+	return nil, pages, err
+}
+
+// UpdateRecipeRequest is the body of PATCH /mixes/:id/recipes/:recipeId.
+// Version is the version the edit is based on; omitted fields are unchanged
+// and ingredients replaces the whole list.
+type UpdateRecipeRequest struct {
+	Version     int                 `json:"version" binding:"required"`
+	Name        *string             `json:"name"`
+	Ingredients []models.Ingredient `json:"ingredients"`
+}
+
+// UpdateMixRecipe corrects a recipe and sends its new state to everyone in the
+// mix. Edits based on an outdated version get 409 with the current recipe.
+func UpdateMixRecipe(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleEditor); !ok {
+		return
+	}
+
+	var req UpdateRecipeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Request body must be JSON with the version being edited",
+		})
+		return
+	}
+
+	updated, err := ws.UpdateRecipe(id, c.Param("recipeId"), req.Version, recipe.RecipeUpdate{
+		Name:        req.Name,
+		Ingredients: req.Ingredients,
+	}, "")
+	if err != nil {
+		respondRecipeError(c, err, updated)
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteMixRecipe removes a recipe and tells everyone in the mix. The
+// ?version= the client last saw is required; if the recipe has changed since,
+// it gets 409 with the current recipe.
+func DeleteMixRecipe(c *gin.Context) {
+	id, ok := mixID(c)
+	if !ok {
+		return
+	}
+	if _, ok := authorize(c, id, models.RoleEditor); !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Query("version"))
+	if err != nil || version <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_version",
+			"message": "version must be the positive recipe version being removed",
+		})
+		return
+	}
+
+	if current, err := ws.RemoveRecipe(id, c.Param("recipeId"), version, ""); err != nil {
+		respondRecipeError(c, err, current)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondRecipeError maps recipe failures to responses; current is the
+// recipe as it is now, sent back on version conflicts
+func respondRecipeError(c *gin.Context, err error, current *models.Recipe) {
+	switch {
+	case errors.Is(err, recipe.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "version_conflict",
+			"message": err.Error(),
+			"recipe":  current,
+		})
+	case errors.Is(err, recipe.ErrRecipeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "recipe_not_found",
+			"message": "Recipe is not in this mix",
+		})
+	case errors.Is(err, recipe.ErrNoIngredients):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "no_ingredients",
+			"message": "No ingredients found in the text",
+		})
+	case errors.Is(err, recipe.ErrInvalidRecipeName), errors.Is(err, recipe.ErrInvalidIngredient), errors.Is(err, recipe.ErrInvalidIngredients), errors.Is(err, recipe.ErrInvalidRecipeText):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_recipe",
+			"message": err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "internal_error",
+			"message": err.Error(),
+		})
+	}
+}
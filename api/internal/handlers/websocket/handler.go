@@ -1,8 +1,11 @@
 package websocket
 
 import (
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -29,20 +32,50 @@ func HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// Mixes are created through the REST API, not by connecting
+	if !ws.Mixes.Exists(id) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "mix_not_found",
+			"message": "Mix does not exist",
+		})
+		return
+	}
+
+	// Clients that predate versioning don't send one and speak version 1
+	version := 1
+	if v := c.Query("version"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "invalid_version",
+				"message": "version must be an integer",
+			})
+			return
+		}
+		version = parsed
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade WebSocket: %v", err)
 		return
 	}
 
+	if version < ws.MinProtocolVersion || version > ws.ProtocolVersion {
+		rejectIncompatible(conn, version)
+		return
+	}
+
 	connectionID := uuid.New().String()
 	connection := ws.NewConnection(connectionID, id, conn)
 
 	ws.Pool.Register(connection)
 
-	ackMsg, err := ws.NewMessage(ws.MessageTypeConnectionAck, map[string]string{
-		"id":      id,
-		"message": "Connected to session",
+	ackMsg, err := ws.NewMessage(ws.MessageTypeConnectionAck, ws.ConnectionAckPayload{
+		ID:                 id,
+		Message:            "Connected to session",
+		ProtocolVersion:    ws.ProtocolVersion,
+		MinProtocolVersion: ws.MinProtocolVersion,
 	})
 	if err != nil {
 		log.Printf("Failed to create ack message: %v", err)
@@ -53,3 +86,32 @@ func HandleWebSocket(c *gin.Context) {
 	go connection.WritePump()
 	go connection.ReadPump()
 }
+
+// rejectIncompatible tells the client which versions are supported and closes the connection
+func rejectIncompatible(conn *websocket.Conn, version int) {
+	defer conn.Close()
+
+	message := fmt.Sprintf("Protocol version %d is not supported; server supports versions %d to %d",
+		version, ws.MinProtocolVersion, ws.ProtocolVersion)
+	log.Printf("Rejected WebSocket connection: %s", message)
+
+	errorMsg, err := ws.NewMessage(ws.MessageTypeError, ws.ErrorPayload{
+		Code:    ws.ErrorCodeIncompatibleProtocol,
+		Message: message,
+	})
+	if err != nil {
+		log.Printf("Failed to create error message: %v", err)
+		return
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if err := conn.WriteJSON(errorMsg); err != nil {
+		return
+	}
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(ws.CloseIncompatibleProtocol, "incompatible protocol version"))
+}
+
+// GetStats exposes connection counts and slow-consumer counters for monitoring
+func GetStats(c *gin.Context) {
+	c.JSON(http.StatusOK, ws.Pool.Stats())
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ChatUser identifies the sender of a chat message
+type ChatUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"imageUrl,omitempty"`
+}
+
+// ChatChannel is the channel within a mix a message was posted to
+type ChatChannel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ChatMessage is a chat message as stored and relayed by the server
+type ChatMessage struct {
+	ID      string      `json:"id"`
+	Sender  ChatUser    `json:"sender"`
+	Channel ChatChannel `json:"channel"`
+	Text    string      `json:"text"`
+	SentAt  time.Time   `json:"sentAt"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// GroceryListItem is a line of a mix's shared grocery list, ticked off by
+// whoever is at the shop. Version starts at 1 and increases with every
+// change; edits name the version they were based on, as for recipes.
+type GroceryListItem struct {
+	ID            string       `json:"id"`
+	Name          string       `json:"name"`
+	GroceryItem   *GroceryItem `json:"groceryItem,omitempty"`
+	Quantity      *string      `json:"quantity"`
+	Unit          *string      `json:"unit"`
+	Checked       bool         `json:"checked"`
+	CheckedByID   string       `json:"checkedById,omitempty"`
+	CheckedByName string       `json:"checkedByName,omitempty"`
+	AssigneeID    string       `json:"assigneeId,omitempty"`
+	AssigneeName  string       `json:"assigneeName,omitempty"`
+	AddedByID     string       `json:"addedById"`
+	AddedByName   string       `json:"addedByName"`
+	CreatedAt     time.Time    `json:"createdAt"`
+	UpdatedAt     time.Time    `json:"updatedAt"`
+	Version       int          `json:"version"`
+}
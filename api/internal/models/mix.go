@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Mix is a shared space where people collect recipes and chat. Invites are
+// only shown to the owner since their tokens grant membership.
+type Mix struct {
+	ID          string      `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Members     []MixMember `json:"members"`
+	Invites     []Invite    `json:"invites,omitempty"`
+	CreatedAt   time.Time   `json:"createdAt"`
+	UpdatedAt   time.Time   `json:"updatedAt"`
+}
+
+// Roles a member can have in a mix, from most to least privileged
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// MixMember is a user who has joined a mix. InviteID is the invite they
+// joined through; revoking it removes them.
+type MixMember struct {
+	UserID   string    `json:"userId"`
+	UserName string    `json:"userName"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+	InviteID string    `json:"inviteId,omitempty"`
+}
+
+// Invite grants membership of a mix with a role to whoever presents its
+// token, until it expires, runs out of uses or is revoked. MaxUses 0 is unlimited.
+type Invite struct {
+	ID        string     `json:"id"`
+	Token     string     `json:"token"`
+	Role      string     `json:"role"`
+	CreatedBy string     `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	MaxUses   int        `json:"maxUses,omitempty"`
+	Uses      int        `json:"uses"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+}
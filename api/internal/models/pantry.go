@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// PantryItem is something a mix has in stock. Without a quantity it is a
+// staple, such as salt, that shopping lists assume there is always enough of.
+type PantryItem struct {
+	ID            string       `json:"id"`
+	Name          string       `json:"name"`
+	GroceryItem   *GroceryItem `json:"groceryItem,omitempty"`
+	Quantity      *string      `json:"quantity"`
+	Unit          *string      `json:"unit"`
+	UpdatedByID   string       `json:"updatedById"`
+	UpdatedByName string       `json:"updatedByName"`
+	UpdatedAt     time.Time    `json:"updatedAt"`
+}
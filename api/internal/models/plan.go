@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Meal slots a recipe can be planned for, in the order they come in a day
+const (
+	MealBreakfast = "breakfast"
+	MealLunch     = "lunch"
+	MealDinner    = "dinner"
+	MealSnack     = "snack"
+)
+
+// PlanEntry schedules a recipe from a mix for a meal on a date. Date is a
+// calendar day, YYYY-MM-DD, with no time zone.
+type PlanEntry struct {
+	ID          string    `json:"id"`
+	RecipeID    string    `json:"recipeId"`
+	Date        string    `json:"date"`
+	Meal        string    `json:"meal"`
+	Note        string    `json:"note,omitempty"`
+	AddedByID   string    `json:"addedById"`
+	AddedByName string    `json:"addedByName"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
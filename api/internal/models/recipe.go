@@ -17,17 +17,22 @@ type Ingredient struct {
 	Unit        *string      `json:"unit"`
 }
 
-// Recipe represents a complete recipe
+// Recipe represents a complete recipe. Version starts at 1 and increases with
+// every edit; edits name the version they were based on so concurrent ones
+// can be detected.
 type Recipe struct {
 	ID          string       `json:"id"`
 	Name        string       `json:"name"`
 	URL         string       `json:"url"`
 	Image       *string      `json:"image,omitempty"`
+	Thumbnail   *string      `json:"thumbnail,omitempty"`
+	ImageSource *string      `json:"imageSource,omitempty"` // Original remote URL of Image
 	Ingredients []Ingredient `json:"ingredients"`
 	SharerID    string       `json:"sharerId"`
 	SharerName  string       `json:"sharerName"`
 	CreatedAt   time.Time    `json:"createdAt"`
 	UpdatedAt   time.Time    `json:"updatedAt"`
+	Version     int          `json:"version"`
 }
 
 // OllamaRecipeResponse represents the AI response structure for recipe extraction
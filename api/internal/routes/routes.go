@@ -17,5 +17,43 @@ func Setup(router *gin.Engine) {
 	api := router.Group("api/v1")
 	{
 		api.GET("/ws/:id", wsHandlers.HandleWebSocket)
+		api.GET("/websocket/stats", wsHandlers.GetStats)
+		api.POST("/identity/guest", handlers.MintGuestIdentity)
+		api.POST("/mixes", handlers.CreateMix)
+		api.GET("/mixes/:id", handlers.GetMix)
+		api.PATCH("/mixes/:id", handlers.UpdateMix)
+		api.DELETE("/mixes/:id", handlers.DeleteMix)
+		api.PATCH("/mixes/:id/members/:userId", handlers.UpdateMember)
+		api.POST("/mixes/:id/join", handlers.JoinMix)
+		api.POST("/mixes/:id/invites", handlers.CreateInvite)
+		api.GET("/mixes/:id/invites", handlers.ListInvites)
+		api.DELETE("/mixes/:id/invites/:inviteId", handlers.RevokeInvite)
+		api.GET("/mixes/:id/recipes", handlers.GetMixRecipes)
+		api.POST("/mixes/:id/recipes/text", handlers.AddMixRecipeFromText)
+		api.POST("/mixes/:id/recipes/import", handlers.ImportMixRecipes)
+		api.PATCH("/mixes/:id/recipes/:recipeId", handlers.UpdateMixRecipe)
+		api.DELETE("/mixes/:id/recipes/:recipeId", handlers.DeleteMixRecipe)
+		api.GET("/mixes/:id/export", handlers.ExportMix)
+		api.GET("/mixes/:id/cookbook", handlers.GetMixCookbook)
+		api.GET("/mixes/:id/plan", handlers.GetMixPlan)
+		api.POST("/mixes/:id/plan", handlers.AddMixPlanEntry)
+		api.GET("/mixes/:id/plan/shopping-list", handlers.GetMixPlanShoppingList)
+		api.GET("/mixes/:id/plan/calendar.ics", handlers.GetMixPlanCalendar)
+		api.PATCH("/mixes/:id/plan/:entryId", handlers.MoveMixPlanEntry)
+		api.DELETE("/mixes/:id/plan/:entryId", handlers.DeleteMixPlanEntry)
+		api.GET("/mixes/:id/grocery-list", handlers.GetMixGroceryList)
+		api.POST("/mixes/:id/grocery-list", handlers.AddMixGroceryItem)
+		api.POST("/mixes/:id/grocery-list/clear-checked", handlers.ClearMixGroceryListChecked)
+		api.POST("/mixes/:id/grocery-list/from-plan", handlers.AddMixGroceryItemsFromPlan)
+		api.PATCH("/mixes/:id/grocery-list/:itemId", handlers.UpdateMixGroceryItem)
+		api.PUT("/mixes/:id/grocery-list/:itemId/checked", handlers.CheckMixGroceryItem)
+		api.DELETE("/mixes/:id/grocery-list/:itemId", handlers.DeleteMixGroceryItem)
+		api.GET("/mixes/:id/pantry", handlers.GetMixPantry)
+		api.POST("/mixes/:id/pantry", handlers.AddMixPantryItem)
+		api.PUT("/mixes/:id/pantry/:itemId", handlers.ReplaceMixPantryItem)
+		api.DELETE("/mixes/:id/pantry/:itemId", handlers.DeleteMixPantryItem)
+		api.GET("/mixes/:id/messages", handlers.GetChatHistory)
+		api.GET("/images/:id", handlers.GetImage)
+		api.GET("/protocol", handlers.GetProtocolSchema)
 	}
 }
@@ -0,0 +1,152 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/mixstore"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxTextLength is the longest chat message accepted, in characters
+	MaxTextLength = 4000
+	// DefaultPageSize is the number of messages returned when no limit is given
+	DefaultPageSize = 50
+	// MaxPageSize caps a single history page
+	MaxPageSize = 200
+)
+
+var (
+	// ErrEmptyMessage is returned for messages without text
+	ErrEmptyMessage = errors.New("message text is empty")
+	// ErrMessageTooLong is returned for messages over MaxTextLength characters
+	ErrMessageTooLong = fmt.Errorf("message text is longer than %d characters", MaxTextLength)
+	// ErrInvalidMix is returned when the mix ID is not a UUID
+	ErrInvalidMix = errors.New("invalid mix ID")
+)
+
+// Config controls how much chat history is kept and where it is stored
+type Config struct {
+	// Dir enables disk persistence when non-empty
+	Dir string
+	// MaxMessages is how many of the most recent messages are kept per mix
+	MaxMessages int
+}
+
+// ConfigFromEnv reads CHAT_DIR and CHAT_MAX_MESSAGES
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Dir:         "tmp/chat",
+		MaxMessages: 1000,
+	}
+
+	if v, ok := os.LookupEnv("CHAT_DIR"); ok {
+		cfg.Dir = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("CHAT_MAX_MESSAGES")); err == nil && v > 0 {
+		cfg.MaxMessages = v
+	}
+
+	return cfg
+}
+
+// ChatService stores the chat history of each mix, oldest message first
+type ChatService struct {
+	config Config
+
+	mu    sync.Mutex
+	mixes *mixstore.Store[[]models.ChatMessage]
+}
+
+// NewChatService creates a chat service. Histories are loaded from disk lazily.
+func NewChatService(config Config) *ChatService {
+	return &ChatService{
+		config: config,
+		mixes:  mixstore.New[[]models.ChatMessage](config.Dir, "chat history"),
+	}
+}
+
+// Add validates and stores a message, assigning its ID and timestamp
+func (s *ChatService) Add(mixID string, message models.ChatMessage) (models.ChatMessage, error) {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return models.ChatMessage{}, ErrInvalidMix
+	}
+
+	message.Text = strings.TrimSpace(message.Text)
+	if message.Text == "" {
+		return models.ChatMessage{}, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(message.Text) > MaxTextLength {
+		return models.ChatMessage{}, ErrMessageTooLong
+	}
+
+	message.ID = uuid.New().String()
+	message.SentAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	messages := append(s.load(mixID), message)
+	if len(messages) > s.config.MaxMessages {
+		messages = slices.Clone(messages[len(messages)-s.config.MaxMessages:])
+	}
+	s.mixes.Put(mixID, messages)
+
+	return message, nil
+}
+
+// History returns up to limit messages older than the message with ID before,
+// or the most recent ones when before is empty. hasMore reports whether older
+// messages remain.
+func (s *ChatService) History(mixID string, before string, limit int) (messages []models.ChatMessage, hasMore bool) {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return []models.ChatMessage{}, false
+	}
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+	limit = min(limit, MaxPageSize)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all := s.load(mixID)
+	end := len(all)
+	if before != "" {
+		end = slices.IndexFunc(all, func(m models.ChatMessage) bool { return m.ID == before })
+		if end < 0 {
+			return []models.ChatMessage{}, false
+		}
+	}
+	start := max(0, end-limit)
+
+	return append([]models.ChatMessage{}, all[start:end]...), start > 0
+}
+
+// Delete removes the history of a mix
+func (s *ChatService) Delete(mixID string) {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mixes.Delete(mixID)
+}
+
+// load returns the chat history of a mix, reading it from disk on first use. Callers hold s.mu.
+func (s *ChatService) load(mixID string) []models.ChatMessage {
+	value, _ := s.mixes.Get(mixID)
+	return value
+}
@@ -0,0 +1,123 @@
+// Package cookbook renders recipes as a standalone HTML document laid out for
+// printing: a contents page, a page per recipe and a shopping list.
+package cookbook
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"regexp"
+	"time"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/shopping"
+)
+
+//go:embed cookbook.html
+var cookbookTemplate string
+
+var page = template.Must(template.New("cookbook").Parse(cookbookTemplate))
+
+// MaxScale bounds how far quantities can be scaled, either way
+const MaxScale = 20
+
+// Options control how a cookbook is rendered
+type Options struct {
+	// Title heads the cookbook, usually the mix's title
+	Title string
+	// Scale multiplies every quantity that can be read as a number; 0 is 1
+	Scale float64
+	// ShoppingList adds a page with the ingredients of every recipe summed
+	ShoppingList bool
+	// Pantry is taken off the shopping list; what it covers is listed apart
+	Pantry []models.PantryItem
+	// Image returns the source of a recipe's picture, e.g. a data: URL so the
+	// document stands alone, or "" to leave the picture out. Nil leaves out
+	// every picture. Only base64 data: URLs of images are used as they are;
+	// other sources are escaped like any link, so a scraped javascript: URL
+	// can't run.
+	Image func(*models.Recipe) string
+}
+
+// recipePage is a recipe as it is printed
+type recipePage struct {
+	Anchor     string
+	Name       string
+	URL        string
+	SharerName string
+	// ImageData is an inlined picture; ImageURL one to be fetched
+	ImageData   template.URL
+	ImageURL    string
+	Ingredients []string
+}
+
+// embeddedImage matches the base64 data: URLs of pictures, the only image
+// sources trusted as they are
+var embeddedImage = regexp.MustCompile(`^data:image/[a-z0-9.+-]+;base64,[A-Za-z0-9+/]*={0,2}$`)
+
+// Render writes recipes as a printable HTML document
+func Render(w io.Writer, options Options, recipes []*models.Recipe) error {
+	scale := options.Scale
+	if scale == 0 {
+		scale = 1
+	}
+
+	pages := make([]recipePage, len(recipes))
+	scaled := make([]*models.Recipe, len(recipes))
+	for i, recipe := range recipes {
+		scaled[i] = scaleRecipe(recipe, scale)
+		pages[i] = recipePage{
+			Anchor:      "recipe-" + recipe.ID,
+			Name:        recipe.Name,
+			URL:         recipe.URL,
+			SharerName:  recipe.SharerName,
+			Ingredients: ingredientLines(scaled[i]),
+		}
+		if options.Image != nil {
+			if src := options.Image(recipe); embeddedImage.MatchString(src) {
+				pages[i].ImageData = template.URL(src)
+			} else {
+				pages[i].ImageURL = src
+			}
+		}
+	}
+
+	var list, stocked []shopping.Item
+	if options.ShoppingList {
+		list, stocked = shopping.Subtract(shopping.Build(scaled), options.Pantry)
+	}
+
+	return page.Execute(w, map[string]any{
+		"Title":        options.Title,
+		"Scale":        shopping.FormatQuantity(scale),
+		"Scaled":       scale != 1,
+		"Recipes":      pages,
+		"ShoppingList": options.ShoppingList,
+		"Items":        list,
+		"Stocked":      stocked,
+		"Printed":      time.Now().Format("2 January 2006"),
+	})
+}
+
+// scaleRecipe copies a recipe with its quantities multiplied by scale
+func scaleRecipe(recipe *models.Recipe, scale float64) *models.Recipe {
+	copied := *recipe
+	copied.Ingredients = make([]models.Ingredient, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		if ingredient.Quantity != nil {
+			quantity := shopping.ScaleQuantity(*ingredient.Quantity, scale)
+			ingredient.Quantity = &quantity
+		}
+		copied.Ingredients[i] = ingredient
+	}
+	return &copied
+}
+
+// ingredientLines writes each ingredient as recipes list it, e.g. "2 cups flour"
+func ingredientLines(recipe *models.Recipe) []string {
+	lines := make([]string, len(recipe.Ingredients))
+	for i, ingredient := range recipe.Ingredients {
+		lines[i] = shopping.FormatIngredient(ingredient)
+	}
+	return lines
+}
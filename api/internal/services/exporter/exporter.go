@@ -0,0 +1,88 @@
+// Package exporter writes the recipes of a mix out in formats other apps and
+// people can use: schema.org JSON-LD, Markdown, a plain-text shopping list,
+// CSV of ingredients and a Paprika archive. The JSON-LD and Paprika exports
+// can be read back by the importer package; the others are meant for people
+// and spreadsheets.
+package exporter
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+
+	"kitchenmix/api/internal/models"
+)
+
+// ErrUnknownFormat is returned for export formats this package doesn't write
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Format is a way recipes can be exported
+type Format struct {
+	// ContentType is sent with the export when downloaded
+	ContentType string
+	// Extension is the file extension of the export, including the dot
+	Extension string
+	write     func(w io.Writer, title string, recipes []*models.Recipe) error
+}
+
+// formats are the export formats by the name they are requested with
+var formats = map[string]Format{
+	"jsonld":        {"application/ld+json", ".jsonld", writeJSONLD},
+	"markdown":      {"text/markdown; charset=utf-8", ".md", writeMarkdown},
+	"shopping-list": {"text/plain; charset=utf-8", ".txt", writeShoppingList},
+	"csv":           {"text/csv; charset=utf-8", ".csv", writeCSV},
+	"paprika":       {"application/zip", ".paprikarecipes", writePaprika},
+}
+
+// Formats lists the names of the export formats
+func Formats() []string {
+	return []string{"jsonld", "markdown", "shopping-list", "csv", "paprika"}
+}
+
+// Lookup returns the export format with the given name
+func Lookup(name string) (Format, error) {
+	format, ok := formats[name]
+	if !ok {
+		return Format{}, ErrUnknownFormat
+	}
+	return format, nil
+}
+
+// Export writes recipes to w in the named format; title names the collection,
+// usually after the mix they are in
+func Export(w io.Writer, name string, title string, recipes []*models.Recipe) error {
+	format, err := Lookup(name)
+	if err != nil {
+		return err
+	}
+	return format.Write(w, title, recipes)
+}
+
+// Write writes recipes to w in this format
+func (f Format) Write(w io.Writer, title string, recipes []*models.Recipe) error {
+	return f.write(w, title, recipes)
+}
+
+// unsafeFilename matches runs of characters that don't belong in a filename
+var unsafeFilename = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// Filename names the file a collection is exported to, e.g.
+// "sunday-lunch.md" for a Markdown export of the mix "Sunday lunch!"
+func (f Format) Filename(title string) string {
+	base := strings.Trim(unsafeFilename.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if base == "" {
+		base = "recipes"
+	}
+	return base + f.Extension
+}
+
+// imageURL is the address a recipe's picture can be fetched from outside
+// KitchenMix: where it was originally found, since stored images are served
+// by ID relative to the API
+func imageURL(recipe *models.Recipe) string {
+	if recipe.ImageSource != nil {
+		return *recipe.ImageSource
+	}
+	return ""
+}
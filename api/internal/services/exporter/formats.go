@@ -0,0 +1,208 @@
+package exporter
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/shopping"
+)
+
+// writeJSONLD writes a schema.org document with one Recipe per recipe in its @graph
+func writeJSONLD(w io.Writer, title string, recipes []*models.Recipe) error {
+	graph := make([]map[string]any, len(recipes))
+	for i, recipe := range recipes {
+		ingredients := make([]string, len(recipe.Ingredients))
+		for n, ingredient := range recipe.Ingredients {
+			ingredients[n] = shopping.FormatIngredient(ingredient)
+		}
+
+		node := map[string]any{
+			"@type":            "Recipe",
+			"identifier":       recipe.ID,
+			"name":             recipe.Name,
+			"recipeIngredient": ingredients,
+			"dateCreated":      recipe.CreatedAt,
+			"dateModified":     recipe.UpdatedAt,
+		}
+		if recipe.URL != "" {
+			node["url"] = recipe.URL
+		}
+		if image := imageURL(recipe); image != "" {
+			node["image"] = image
+		}
+		graph[i] = node
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(map[string]any{
+		"@context": "https://schema.org",
+		"name":     title,
+		"@graph":   graph,
+	})
+}
+
+// markdownEscaper backslash-escapes the characters that would otherwise
+// start headings, links, emphasis, HTML or code in Markdown
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"#", `\#`, "<", `\<`, ">", `\>`, "!", `\!`, "|", `\|`,
+)
+
+// markdownText makes scraped or user-entered text safe to put on one line of
+// Markdown, e.g. a recipe name that would otherwise end its heading early
+func markdownText(s string) string {
+	return markdownEscaper.Replace(strings.Join(strings.Fields(s), " "))
+}
+
+// markdownURL makes a URL safe to put in an autolink or <...> link destination
+func markdownURL(s string) string {
+	return strings.NewReplacer("<", "%3C", ">", "%3E", " ", "%20", "\n", "", "\r", "").Replace(s)
+}
+
+// writeMarkdown writes a document with a section per recipe
+func writeMarkdown(w io.Writer, title string, recipes []*models.Recipe) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", markdownText(title))
+	for _, recipe := range recipes {
+		name := markdownText(recipe.Name)
+		fmt.Fprintf(&b, "\n## %s\n\n", name)
+		if image := imageURL(recipe); image != "" {
+			fmt.Fprintf(&b, "![%s](<%s>)\n\n", name, markdownURL(image))
+		}
+		if recipe.URL != "" {
+			fmt.Fprintf(&b, "Source: <%s>\n\n", markdownURL(recipe.URL))
+		}
+		if recipe.SharerName != "" {
+			fmt.Fprintf(&b, "Shared by %s\n\n", markdownText(recipe.SharerName))
+		}
+		b.WriteString("### Ingredients\n\n")
+		for _, ingredient := range recipe.Ingredients {
+			fmt.Fprintf(&b, "- %s\n", markdownText(shopping.FormatIngredient(ingredient)))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeShoppingList writes every ingredient the recipes need, summed across
+// them, grouped under their grocery category when they have one
+func writeShoppingList(w io.Writer, title string, recipes []*models.Recipe) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Shopping list for %s\n", title)
+
+	category := "\x00"
+	for _, item := range shopping.Build(recipes) {
+		if item.Category != category {
+			category = item.Category
+			if category != "" {
+				fmt.Fprintf(&b, "\n%s\n", category)
+			} else {
+				b.WriteString("\n")
+			}
+		}
+		fmt.Fprintf(&b, "[ ] %s\n", item)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// csvCell keeps spreadsheets from running a cell as a formula, by quoting
+// values that start like one
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// writeCSV writes one row per ingredient of every recipe, under a header row
+func writeCSV(w io.Writer, title string, recipes []*models.Recipe) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"recipe", "url", "ingredient", "quantity", "unit", "category"})
+	for _, recipe := range recipes {
+		for _, ingredient := range recipe.Ingredients {
+			var quantity, unit, category string
+			if ingredient.Quantity != nil {
+				quantity = *ingredient.Quantity
+			}
+			if ingredient.Unit != nil {
+				unit = *ingredient.Unit
+			}
+			if ingredient.GroceryItem != nil {
+				category = ingredient.GroceryItem.Category
+			}
+			writer.Write([]string{csvCell(recipe.Name), csvCell(recipe.URL), csvCell(ingredient.Name), csvCell(quantity), csvCell(unit), csvCell(category)})
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// paprikaRecipe is the JSON of a .paprikarecipe file, with the fields Paprika
+// needs to import it. Ingredients are one newline-separated string.
+type paprikaRecipe struct {
+	UID         string `json:"uid"`
+	Name        string `json:"name"`
+	Ingredients string `json:"ingredients"`
+	Directions  string `json:"directions"`
+	Notes       string `json:"notes"`
+	Source      string `json:"source"`
+	SourceURL   string `json:"source_url"`
+	ImageURL    string `json:"image_url"`
+	Created     string `json:"created"`
+	Hash        string `json:"hash"`
+	Categories  []any  `json:"categories"`
+}
+
+// writePaprika writes a .paprikarecipes archive: a zip with a gzipped JSON
+// .paprikarecipe file per recipe
+func writePaprika(w io.Writer, title string, recipes []*models.Recipe) error {
+	archive := zip.NewWriter(w)
+	used := make(map[string]int)
+	for _, recipe := range recipes {
+		lines := make([]string, len(recipe.Ingredients))
+		for i, ingredient := range recipe.Ingredients {
+			lines[i] = shopping.FormatIngredient(ingredient)
+		}
+		exported := paprikaRecipe{
+			UID:         strings.ToUpper(recipe.ID),
+			Name:        recipe.Name,
+			Ingredients: strings.Join(lines, "\n"),
+			Source:      recipe.SharerName,
+			SourceURL:   recipe.URL,
+			ImageURL:    imageURL(recipe),
+			Created:     recipe.CreatedAt.Format("2006-01-02 15:04:05"),
+			Categories:  []any{},
+		}
+		sum := sha256.Sum256([]byte(exported.Name + exported.Ingredients + exported.SourceURL))
+		exported.Hash = strings.ToUpper(hex.EncodeToString(sum[:]))
+
+		// Paprika names entries after recipes, which needn't be unique
+		name := Format{Extension: ".paprikarecipe"}.Filename(recipe.Name)
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s-%d.paprikarecipe", strings.TrimSuffix(name, ".paprikarecipe"), used[name])
+		}
+
+		file, err := archive.Create(name)
+		if err != nil {
+			return err
+		}
+		gz := gzip.NewWriter(file)
+		if err := json.NewEncoder(gz).Encode(exported); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
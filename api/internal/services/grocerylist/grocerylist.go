@@ -0,0 +1,303 @@
+// Package grocerylist keeps the shared grocery list of each mix: what to buy,
+// who is buying it and what has been ticked off.
+package grocerylist
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/mixstore"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxNameLength is the longest item name, in characters
+	MaxNameLength = 100
+	// MaxQuantityLength is the longest quantity or unit, in characters
+	MaxQuantityLength = 50
+	// MaxItems is the most items a mix's list holds
+	MaxItems = 500
+)
+
+var (
+	// ErrInvalidMix is returned when the mix ID is not a UUID
+	ErrInvalidMix = errors.New("invalid mix ID")
+	// ErrInvalidName is returned for empty names and names over MaxNameLength characters
+	ErrInvalidName = fmt.Errorf("name must be 1 to %d characters", MaxNameLength)
+	// ErrInvalidQuantity is returned for quantities or units over MaxQuantityLength characters
+	ErrInvalidQuantity = fmt.Errorf("quantity and unit must be at most %d characters", MaxQuantityLength)
+	// ErrItemNotFound is returned for items that aren't on the mix's list
+	ErrItemNotFound = errors.New("grocery list item not found")
+	// ErrVersionConflict is returned when an edit is based on an outdated version of an item
+	ErrVersionConflict = errors.New("grocery list item has changed since it was read")
+	// ErrListFull is returned when a list already has MaxItems items
+	ErrListFull = fmt.Errorf("grocery list already has %d items", MaxItems)
+)
+
+// ItemUpdate holds changes to an item; nil fields are left unchanged. An
+// empty quantity or unit clears it, and an empty AssigneeID unassigns the
+// item.
+type ItemUpdate struct {
+	Name         *string
+	Quantity     *string
+	Unit         *string
+	AssigneeID   *string
+	AssigneeName string
+}
+
+// Config controls where grocery lists are stored
+type Config struct {
+	// Dir enables disk persistence when non-empty
+	Dir string
+}
+
+// ConfigFromEnv reads GROCERY_LIST_DIR
+func ConfigFromEnv() Config {
+	cfg := Config{Dir: "tmp/grocery-lists"}
+
+	if v, ok := os.LookupEnv("GROCERY_LIST_DIR"); ok {
+		cfg.Dir = v
+	}
+
+	return cfg
+}
+
+// GroceryListService stores the grocery list of each mix in the order items
+// were added
+type GroceryListService struct {
+	config Config
+
+	mu    sync.Mutex
+	lists *mixstore.Store[[]models.GroceryListItem]
+}
+
+// NewGroceryListService creates a grocery list service. Lists are loaded
+// from disk lazily.
+func NewGroceryListService(config Config) *GroceryListService {
+	return &GroceryListService{
+		config: config,
+		lists:  mixstore.New[[]models.GroceryListItem](config.Dir, "grocery list"),
+	}
+}
+
+// Items returns the grocery list of a mix
+func (s *GroceryListService) Items(mixID string) []models.GroceryListItem {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return []models.GroceryListItem{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.GroceryListItem{}, s.load(mixID)...)
+}
+
+// Add validates and stores an unchecked item, assigning its ID, timestamps
+// and first version. The caller checks that any assignee is in the mix.
+func (s *GroceryListService) Add(mixID string, item models.GroceryListItem) (models.GroceryListItem, error) {
+	added, err := s.AddAll(mixID, []models.GroceryListItem{item})
+	if err != nil {
+		return models.GroceryListItem{}, err
+	}
+	return added[0], nil
+}
+
+// AddAll adds several items as Add does. Either all of them are added or,
+// if any is invalid or the list has no room for them all, none are.
+func (s *GroceryListService) AddAll(mixID string, items []models.GroceryListItem) ([]models.GroceryListItem, error) {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return nil, ErrInvalidMix
+	}
+
+	now := time.Now().UTC()
+	added := make([]models.GroceryListItem, len(items))
+	for i, item := range items {
+		if err := Validate(item); err != nil {
+			return nil, err
+		}
+		name := item.Name
+		ItemUpdate{Name: &name, Quantity: item.Quantity, Unit: item.Unit}.apply(&item)
+
+		item.ID = uuid.New().String()
+		item.Checked, item.CheckedByID, item.CheckedByName = false, "", ""
+		item.CreatedAt = now
+		item.UpdatedAt = now
+		item.Version = 1
+		added[i] = item
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := s.load(mixID)
+	if len(list)+len(added) > MaxItems {
+		return nil, ErrListFull
+	}
+	s.save(mixID, append(slices.Clone(list), added...))
+	return added, nil
+}
+
+// Update changes an item's name, quantity, unit or assignee. The caller
+// checks that the assignee is in the mix. On ErrVersionConflict the current
+// item is returned.
+func (s *GroceryListService) Update(mixID string, itemID string, version int, update ItemUpdate) (models.GroceryListItem, error) {
+	if err := update.validate(); err != nil {
+		return models.GroceryListItem{}, err
+	}
+
+	return s.change(mixID, itemID, func(item *models.GroceryListItem) error {
+		if item.Version != version {
+			return ErrVersionConflict
+		}
+		update.apply(item)
+		return nil
+	})
+}
+
+// Check ticks an item off as bought by a user, or unticks it. Checking
+// doesn't name a version: whoever ticks last wins.
+func (s *GroceryListService) Check(mixID string, itemID string, checked bool, userID string, userName string) (models.GroceryListItem, error) {
+	return s.change(mixID, itemID, func(item *models.GroceryListItem) error {
+		item.Checked = checked
+		item.CheckedByID, item.CheckedByName = "", ""
+		if checked {
+			item.CheckedByID, item.CheckedByName = userID, userName
+		}
+		return nil
+	})
+}
+
+// Remove takes an item off the list
+func (s *GroceryListService) Remove(mixID string, itemID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.load(mixID)
+	i := slices.IndexFunc(items, func(item models.GroceryListItem) bool { return item.ID == itemID })
+	if i < 0 {
+		return ErrItemNotFound
+	}
+	s.save(mixID, slices.Delete(slices.Clone(items), i, i+1))
+	return nil
+}
+
+// RemoveChecked takes every checked item off the list, e.g. after a trip to
+// the shop, and returns their IDs
+func (s *GroceryListService) RemoveChecked(mixID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed []string
+	kept := slices.DeleteFunc(slices.Clone(s.load(mixID)), func(item models.GroceryListItem) bool {
+		if item.Checked {
+			removed = append(removed, item.ID)
+			return true
+		}
+		return false
+	})
+	if len(removed) > 0 {
+		s.save(mixID, kept)
+	}
+	return removed
+}
+
+// Delete removes the grocery list of a mix
+func (s *GroceryListService) Delete(mixID string) {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lists.Delete(mixID)
+}
+
+// change applies edit to an item, stamping it as a new version when edit
+// succeeds. On failure the item as it is is returned.
+func (s *GroceryListService) change(mixID string, itemID string, edit func(*models.GroceryListItem) error) (models.GroceryListItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := slices.Clone(s.load(mixID))
+	i := slices.IndexFunc(items, func(item models.GroceryListItem) bool { return item.ID == itemID })
+	if i < 0 {
+		return models.GroceryListItem{}, ErrItemNotFound
+	}
+	if err := edit(&items[i]); err != nil {
+		return s.load(mixID)[i], err
+	}
+
+	items[i].Version++
+	items[i].UpdatedAt = time.Now().UTC()
+	s.save(mixID, items)
+	return items[i], nil
+}
+
+// Validate checks an item's name, quantity and unit as Add does
+func Validate(item models.GroceryListItem) error {
+	name := item.Name
+	return ItemUpdate{Name: &name, Quantity: item.Quantity, Unit: item.Unit}.validate()
+}
+
+func (u ItemUpdate) validate() error {
+	if u.Name != nil {
+		name := strings.TrimSpace(*u.Name)
+		if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+			return ErrInvalidName
+		}
+	}
+	for _, field := range []*string{u.Quantity, u.Unit} {
+		if field != nil && utf8.RuneCountInString(strings.TrimSpace(*field)) > MaxQuantityLength {
+			return ErrInvalidQuantity
+		}
+	}
+	return nil
+}
+
+// apply makes a validated update to an item, tidying its fields
+func (u ItemUpdate) apply(item *models.GroceryListItem) {
+	if u.Name != nil {
+		item.Name = strings.TrimSpace(*u.Name)
+	}
+	if u.Quantity != nil {
+		item.Quantity = optional(*u.Quantity)
+	}
+	if u.Unit != nil {
+		item.Unit = optional(*u.Unit)
+	}
+	if u.AssigneeID != nil {
+		item.AssigneeID, item.AssigneeName = *u.AssigneeID, u.AssigneeName
+		if item.AssigneeID == "" {
+			item.AssigneeName = ""
+		}
+	}
+}
+
+// optional trims a value, making it nil when nothing is left
+func optional(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// save stores a list. Callers hold s.mu.
+func (s *GroceryListService) save(mixID string, items []models.GroceryListItem) {
+	s.lists.Put(mixID, items)
+}
+
+// load returns the grocery list of a mix, reading it from disk on first use. Callers hold s.mu.
+func (s *GroceryListService) load(mixID string) []models.GroceryListItem {
+	value, _ := s.lists.Get(mixID)
+	return value
+}
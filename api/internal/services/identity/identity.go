@@ -0,0 +1,169 @@
+package identity
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// MaxNameLength is the longest display name accepted, in characters
+const MaxNameLength = 32
+
+var (
+	// ErrInvalidToken is returned for tokens that are malformed or fail signature checks
+	ErrInvalidToken = errors.New("invalid identity token")
+	// ErrExpiredToken is returned for correctly signed tokens past their expiry
+	ErrExpiredToken = errors.New("identity token has expired")
+	// ErrInvalidName is returned for display names that are empty, too long or contain disallowed characters
+	ErrInvalidName = fmt.Errorf("names must be 1 to %d letters, digits, spaces or - _ . '", MaxNameLength)
+)
+
+// Config holds the signing key and lifetime of identity tokens
+type Config struct {
+	Secret []byte
+	TTL    time.Duration
+}
+
+// ConfigFromEnv reads IDENTITY_SECRET and IDENTITY_TOKEN_TTL. Without a secret a
+// random one is generated, so tokens don't survive a restart.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Secret: []byte(os.Getenv("IDENTITY_SECRET")),
+		TTL:    30 * 24 * time.Hour,
+	}
+
+	if len(cfg.Secret) == 0 {
+		log.Printf("IDENTITY_SECRET is not set; identity tokens will be invalidated on restart")
+		cfg.Secret = make([]byte, 32)
+		rand.Read(cfg.Secret)
+	}
+	if v, err := time.ParseDuration(os.Getenv("IDENTITY_TOKEN_TTL")); err == nil && v > 0 {
+		cfg.TTL = v
+	}
+
+	return cfg
+}
+
+// Identity is a server-issued user identity
+type Identity struct {
+	UserID    string    `json:"userId"`
+	UserName  string    `json:"userName"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// claims is the signed part of a token
+type claims struct {
+	Subject   string `json:"sub"`
+	Name      string `json:"name"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Issuer mints and verifies HMAC-signed identity tokens
+type Issuer struct {
+	config Config
+}
+
+func NewIssuer(config Config) *Issuer {
+	return &Issuer{config: config}
+}
+
+// MintGuest issues a token for a new guest user
+func (i *Issuer) MintGuest(name string) (Identity, string, error) {
+	return i.Mint(uuid.New().String(), name)
+}
+
+// Mint issues a token binding userID to name, e.g. to rename an existing user
+func (i *Issuer) Mint(userID string, name string) (Identity, string, error) {
+	name, err := ValidateName(name)
+	if err != nil {
+		return Identity{}, "", err
+	}
+
+	now := time.Now()
+	c := claims{
+		Subject:   userID,
+		Name:      name,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(i.config.TTL).Unix(),
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return Identity{}, "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	token := encoded + "." + base64.RawURLEncoding.EncodeToString(i.sign(encoded))
+
+	return c.identity(), token, nil
+}
+
+// Verify checks a token's signature and expiry and returns the identity it carries
+func (i *Issuer) Verify(token string) (Identity, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Identity{}, ErrInvalidToken
+	}
+
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(got, i.sign(encoded)) {
+		return Identity{}, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Identity{}, ErrInvalidToken
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil || c.Subject == "" {
+		return Identity{}, ErrInvalidToken
+	}
+	if time.Now().Unix() >= c.ExpiresAt {
+		return Identity{}, ErrExpiredToken
+	}
+
+	return c.identity(), nil
+}
+
+func (i *Issuer) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, i.config.Secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+func (c claims) identity() Identity {
+	return Identity{
+		UserID:    c.Subject,
+		UserName:  c.Name,
+		ExpiresAt: time.Unix(c.ExpiresAt, 0).UTC(),
+	}
+}
+
+// ValidateName trims and collapses whitespace in a display name and checks its
+// length and characters
+func ValidateName(name string) (string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+
+	if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+		return "", ErrInvalidName
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_.'", r) {
+			return "", ErrInvalidName
+		}
+	}
+
+	return name, nil
+}
@@ -0,0 +1,446 @@
+package images
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	// Register decoders for the formats recipe sites commonly serve
+	_ "image/gif"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// Anything smaller is a tracking pixel, spacer or icon
+	minImageBytes     = 2 * 1024
+	minImageDimension = 100
+	// Decoding allocates memory for every pixel, so a small, highly compressed
+	// file claiming huge dimensions is rejected before it is decoded
+	defaultMaxPixels = 40_000_000
+)
+
+var (
+	// ErrNotFound is returned when a stored image does not exist
+	ErrNotFound = errors.New("image not found")
+	// ErrNoValidImage is returned when none of the candidate URLs produced a usable image
+	ErrNoValidImage = errors.New("no valid image found")
+	// ErrPrivateAddress is returned for image URLs that resolve to loopback,
+	// private or link-local addresses
+	ErrPrivateAddress = errors.New("image host is not a public address")
+
+	idPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	// storedTypePattern matches the types of the formats images are decoded as
+	storedTypePattern = regexp.MustCompile(`^image/(jpeg|png|gif|webp)$`)
+)
+
+// Limiter paces requests to each host and enforces robots.txt. It is
+// satisfied by the page fetcher's politeness layer.
+type Limiter interface {
+	Acquire(ctx context.Context, targetURL string) (release func(), err error)
+}
+
+// Config controls where images are stored and how they are validated
+type Config struct {
+	Dir            string
+	MaxBytes       int64
+	MaxPixels      int
+	ThumbnailWidth int
+	UserAgent      string
+	Timeout        time.Duration
+	// Limiter, if set, is asked before every request to an image host
+	Limiter Limiter
+	// AllowPrivateAddresses lets images be downloaded from loopback, private
+	// and link-local addresses, which is only wanted in tests
+	AllowPrivateAddresses bool
+}
+
+// ConfigFromEnv reads IMAGE_DIR, IMAGE_MAX_BYTES, IMAGE_MAX_PIXELS and IMAGE_THUMBNAIL_WIDTH
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Dir:            "tmp/images",
+		MaxBytes:       10 * 1024 * 1024,
+		MaxPixels:      defaultMaxPixels,
+		ThumbnailWidth: 320,
+		Timeout:        15 * time.Second,
+	}
+
+	if v := os.Getenv("IMAGE_DIR"); v != "" {
+		cfg.Dir = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("IMAGE_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		cfg.MaxBytes = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("IMAGE_MAX_PIXELS")); err == nil && v > 0 {
+		cfg.MaxPixels = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("IMAGE_THUMBNAIL_WIDTH")); err == nil && v > 0 {
+		cfg.ThumbnailWidth = v
+	}
+
+	return cfg
+}
+
+// StoredImage describes an image downloaded into local storage
+type StoredImage struct {
+	ID          string
+	SourceURL   string
+	ContentType string
+	Width       int
+	Height      int
+}
+
+// URL is the API path serving the full-size image
+func (i *StoredImage) URL() string {
+	return "/api/v1/images/" + i.ID
+}
+
+// ThumbnailURL is the API path serving the thumbnail
+func (i *StoredImage) ThumbnailURL() string {
+	return i.URL() + "?size=thumb"
+}
+
+// IDFromURL returns the ID of the stored image an API path such as URL or
+// ThumbnailURL serves
+func IDFromURL(url string) (string, bool) {
+	id, found := strings.CutPrefix(url, "/api/v1/images/")
+	id, _, _ = strings.Cut(id, "?")
+	if !found || !idPattern.MatchString(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// ImageService validates, downloads, stores and serves recipe images
+type ImageService struct {
+	config Config
+	client *http.Client
+}
+
+// NewImageService creates an image service storing files under config.Dir
+func NewImageService(config Config) *ImageService {
+	if config.MaxPixels <= 0 {
+		config.MaxPixels = defaultMaxPixels
+	}
+
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateAddresses {
+		// Checked on the resolved address of every connection, redirects included
+		dialer.Control = refusePrivateAddress
+	}
+	return &ImageService{
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+		},
+	}
+}
+
+// refusePrivateAddress is a net.Dialer Control function rejecting addresses
+// that aren't on the public internet, so scraped image URLs can't reach the
+// server's own network
+func refusePrivateAddress(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrPrivateAddress, host)
+	}
+	return nil
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// Store tries each candidate URL in order and keeps the first one that is a real,
+// reasonably sized image. pageURL is sent as Referer to get past hotlink protection.
+func (s *ImageService) Store(ctx context.Context, pageURL string, candidates []string) (*StoredImage, error) {
+	for _, candidate := range candidates {
+		stored, err := s.storeOne(ctx, pageURL, candidate)
+		if err != nil {
+			log.Printf("Rejected image candidate %s: %v", candidate, err)
+			continue
+		}
+		return stored, nil
+	}
+	return nil, ErrNoValidImage
+}
+
+func (s *ImageService) storeOne(ctx context.Context, pageURL string, imageURL string) (*StoredImage, error) {
+	if err := s.validateHeaders(ctx, pageURL, imageURL); err != nil {
+		return nil, err
+	}
+
+	data, err := s.download(ctx, pageURL, imageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not a decodable image: %w", err)
+	}
+	if header.Width < minImageDimension || header.Height < minImageDimension {
+		return nil, fmt.Errorf("image too small (%dx%d)", header.Width, header.Height)
+	}
+	if int64(header.Width)*int64(header.Height) > int64(s.config.MaxPixels) {
+		return nil, fmt.Errorf("image too large (%dx%d)", header.Width, header.Height)
+	}
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("not a decodable image: %w", err)
+	}
+	bounds := img.Bounds()
+
+	sum := sha256.Sum256(data)
+	id := hex.EncodeToString(sum[:16])
+
+	// The type served is the format decoded, whatever the server labelled it
+	contentType := "image/" + format
+
+	if err := s.writeFile(s.originalPath(id), data); err != nil {
+		return nil, err
+	}
+	if err := s.writeFile(s.contentTypePath(id), []byte(contentType)); err != nil {
+		return nil, err
+	}
+	if err := s.writeThumbnail(id, img); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Stored image %s from %s (%dx%d %s)", id, imageURL, bounds.Dx(), bounds.Dy(), format)
+	return &StoredImage{
+		ID:          id,
+		SourceURL:   imageURL,
+		ContentType: contentType,
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
+	}, nil
+}
+
+// validateHeaders issues a HEAD request to reject non-images and oversized or tiny files
+// without downloading them. Servers that don't support HEAD are given the benefit of the doubt.
+func (s *ImageService) validateHeaders(ctx context.Context, pageURL string, imageURL string) error {
+	req, err := s.newRequest(ctx, http.MethodHead, pageURL, imageURL)
+	if err != nil {
+		return err
+	}
+
+	release, err := s.acquire(ctx, imageURL)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("HEAD failed: %w", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented:
+		return nil
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("HEAD returned status %d", resp.StatusCode)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" && !strings.HasPrefix(contentType, "image/") {
+		return fmt.Errorf("content type %q is not an image", contentType)
+	}
+	if resp.ContentLength > s.config.MaxBytes {
+		return fmt.Errorf("image too large (%d bytes)", resp.ContentLength)
+	}
+	if resp.ContentLength >= 0 && resp.ContentLength < minImageBytes {
+		return fmt.Errorf("image too small (%d bytes)", resp.ContentLength)
+	}
+	return nil
+}
+
+func (s *ImageService) download(ctx context.Context, pageURL string, imageURL string) ([]byte, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, pageURL, imageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.acquire(ctx, imageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.config.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) > s.config.MaxBytes {
+		return nil, fmt.Errorf("image larger than %d bytes", s.config.MaxBytes)
+	}
+	if len(data) < minImageBytes {
+		return nil, fmt.Errorf("image too small (%d bytes)", len(data))
+	}
+
+	return data, nil
+}
+
+func (s *ImageService) newRequest(ctx context.Context, method string, pageURL string, imageURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, imageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image URL: %w", err)
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported image URL scheme %q", req.URL.Scheme)
+	}
+	if s.config.UserAgent != "" {
+		req.Header.Set("User-Agent", s.config.UserAgent)
+	}
+	req.Header.Set("Accept", "image/webp,image/png,image/jpeg,image/*;q=0.8")
+	if pageURL != "" {
+		req.Header.Set("Referer", pageURL)
+	}
+	return req, nil
+}
+
+// acquire waits for the Limiter to allow a request to imageURL. Private hosts
+// are refused first, so the Limiter doesn't fetch their robots.txt either.
+func (s *ImageService) acquire(ctx context.Context, imageURL string) (release func(), err error) {
+	if !s.config.AllowPrivateAddresses {
+		if err := s.checkHost(ctx, imageURL); err != nil {
+			return nil, err
+		}
+	}
+	if s.config.Limiter == nil {
+		return func() {}, nil
+	}
+	return s.config.Limiter.Acquire(ctx, imageURL)
+}
+
+// checkHost resolves imageURL's host and rejects it if any address isn't public
+func (s *ImageService) checkHost(ctx context.Context, imageURL string) error {
+	parsed, err := url.Parse(imageURL)
+	if err != nil {
+		return fmt.Errorf("invalid image URL: %w", err)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("failed to resolve image host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !isPublicIP(addr.IP) {
+			return fmt.Errorf("%w: %s", ErrPrivateAddress, addr.IP)
+		}
+	}
+	return nil
+}
+
+// writeThumbnail scales img down to the configured width and stores it as JPEG
+func (s *ImageService) writeThumbnail(id string, img image.Image) error {
+	bounds := img.Bounds()
+	width := min(s.config.ThumbnailWidth, bounds.Dx())
+	height := bounds.Dy() * width / bounds.Dx()
+
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(thumb, thumb.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 85}); err != nil {
+		return fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return s.writeFile(s.thumbnailPath(id), buf.Bytes())
+}
+
+// Open returns the stored image or its thumbnail along with its content type
+func (s *ImageService) Open(id string, thumbnail bool) (*os.File, string, error) {
+	if !idPattern.MatchString(id) {
+		return nil, "", ErrNotFound
+	}
+
+	if thumbnail {
+		file, err := os.Open(s.thumbnailPath(id))
+		if err != nil {
+			return nil, "", ErrNotFound
+		}
+		return file, "image/jpeg", nil
+	}
+
+	file, err := os.Open(s.originalPath(id))
+	if err != nil {
+		return nil, "", ErrNotFound
+	}
+	if data, err := os.ReadFile(s.contentTypePath(id)); err == nil && storedTypePattern.Match(data) {
+		return file, string(data), nil
+	}
+
+	// Images stored before the decoded format was recorded may have kept
+	// whatever type the server sent; serve them as what they decode as
+	contentType := "application/octet-stream"
+	if _, format, err := image.DecodeConfig(file); err == nil {
+		contentType = "image/" + format
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, "", err
+	}
+	return file, contentType, nil
+}
+
+func (s *ImageService) originalPath(id string) string {
+	return filepath.Join(s.config.Dir, id)
+}
+
+func (s *ImageService) contentTypePath(id string) string {
+	return filepath.Join(s.config.Dir, id+".type")
+}
+
+func (s *ImageService) thumbnailPath(id string) string {
+	return filepath.Join(s.config.Dir, id+"_thumb.jpg")
+}
+
+func (s *ImageService) writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(s.config.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
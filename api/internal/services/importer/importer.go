@@ -0,0 +1,371 @@
+// Package importer reads recipes exported from other recipe managers -
+// Paprika, Mealie, Tandoor and plain schema.org JSON-LD - and maps them onto
+// models.Recipe so they can be bulk-added to a mix.
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/recipe"
+)
+
+var (
+	// ErrUnknownFormat is returned for files that aren't an export this package reads
+	ErrUnknownFormat = errors.New("file is not a Paprika, Mealie, Tandoor or schema.org export")
+	// ErrEntryTooLarge is returned for archive entries over MaxEntryBytes
+	ErrEntryTooLarge = fmt.Errorf("export entry is larger than %d bytes", MaxEntryBytes)
+	// ErrTooManyEntries is returned for exports with more than MaxEntries recipes
+	ErrTooManyEntries = fmt.Errorf("export has more than %d recipes", MaxEntries)
+	// ErrImportTooLarge is returned once the exports of one import decompress
+	// to more than MaxImportBytes
+	ErrImportTooLarge = fmt.Errorf("exports decompress to more than %d bytes", MaxImportBytes)
+)
+
+const (
+	// MaxEntryBytes is the largest single file read from an archive, after decompressing
+	MaxEntryBytes = 10 << 20
+	// MaxEntries is the most recipes read from one export
+	MaxEntries = 2000
+	// MaxImportBytes bounds everything decompressed from the exports imported
+	// at once, counting entries that turn out not to be recipes
+	MaxImportBytes = 100 << 20
+	// maxArchiveDepth bounds zips inside zips, as Tandoor exports them
+	maxArchiveDepth = 2
+)
+
+// Entry is one recipe read from an export, or the reason it couldn't be.
+// Skipped entries aren't recipes at all, e.g. other documents in an archive.
+type Entry struct {
+	// Name identifies the entry in import summaries
+	Name    string
+	Recipe  *models.Recipe
+	Err     error
+	Skipped bool
+}
+
+// Budget bounds the bytes decompressed from all the files of one import, so
+// archives that compress well can't cost more to read than an upload should
+type Budget struct {
+	bytes int64
+}
+
+// NewBudget returns a budget of MaxImportBytes
+func NewBudget() *Budget {
+	return &Budget{bytes: MaxImportBytes}
+}
+
+// readAll decompresses one entry, spending the budget on it. Entries over
+// MaxEntryBytes fail with ErrEntryTooLarge; ErrImportTooLarge means the
+// budget has run out.
+func (b *Budget) readAll(r io.Reader) ([]byte, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, min(MaxEntryBytes, b.bytes)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) > MaxEntryBytes {
+		return nil, ErrEntryTooLarge
+	}
+	if int64(len(raw)) > b.bytes {
+		return nil, ErrImportTooLarge
+	}
+	b.bytes -= int64(len(raw))
+	return raw, nil
+}
+
+// Read reads the recipes in an exported file: a Paprika .paprikarecipes
+// archive or single .paprikarecipe, Mealie or Tandoor JSON, schema.org JSON-LD,
+// or a zip of any of these. Files that aren't such an export, including zips
+// without any recipes in them, return ErrUnknownFormat.
+func Read(filename string, data []byte) ([]Entry, error) {
+	return NewBudget().Read(filename, data)
+}
+
+// Read reads the recipes in an exported file like Read, spending the budget
+// on what it decompresses. It returns ErrImportTooLarge once the budget runs
+// out.
+func (b *Budget) Read(filename string, data []byte) ([]Entry, error) {
+	entries, err := b.read(filename, data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, ErrUnknownFormat
+	}
+	if len(entries) > MaxEntries {
+		return nil, ErrTooManyEntries
+	}
+	return entries, nil
+}
+
+func (b *Budget) read(filename string, data []byte, depth int) ([]Entry, error) {
+	switch {
+	case isZip(data):
+		if depth == maxArchiveDepth {
+			return nil, nil
+		}
+		return b.readZip(data, depth+1)
+	case isGzip(data):
+		entry, err := b.readPaprikaRecipe(filename, data)
+		if err != nil {
+			return nil, err
+		}
+		return []Entry{entry}, nil
+	case isJSON(filename, data):
+		return readJSON(filename, data)
+	}
+	return nil, ErrUnknownFormat
+}
+
+// readZip reads every export inside a zip. Other files, such as the images
+// Tandoor and Mealie export next to each recipe, are ignored.
+func (b *Budget) readZip(data []byte, depth int) ([]Entry, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+
+	var entries []Entry
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		switch strings.ToLower(path.Ext(file.Name)) {
+		case ".paprikarecipe", ".json", ".zip":
+		default:
+			continue
+		}
+
+		raw, err := b.readZipFile(file)
+		if errors.Is(err, ErrImportTooLarge) {
+			return nil, err
+		}
+		if err != nil {
+			entries = append(entries, Entry{Name: file.Name, Err: err})
+			continue
+		}
+		found, err := b.read(file.Name, raw, depth)
+		if errors.Is(err, ErrImportTooLarge) {
+			return nil, err
+		}
+		if err != nil {
+			entries = append(entries, Entry{Name: file.Name, Err: err})
+			continue
+		}
+		entries = append(entries, found...)
+		if len(entries) > MaxEntries {
+			return nil, ErrTooManyEntries
+		}
+	}
+	return entries, nil
+}
+
+// readZipFile reads one file from a zip, limiting its uncompressed size so a
+// small archive can't expand without bound
+func (b *Budget) readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return b.readAll(rc)
+}
+
+// readJSON maps each recipe in a JSON document onto the format it looks like.
+// Documents may hold one recipe, an array of them, or a Mealie page of items.
+func readJSON(filename string, data []byte) ([]Entry, error) {
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+	}
+
+	// Mealie's API wraps pages of recipes in {"items": [...]}
+	if page, ok := doc.(map[string]any); ok && page["@type"] == nil {
+		if items, ok := page["items"].([]any); ok {
+			doc = items
+		}
+	}
+
+	var objects []map[string]any
+	switch v := doc.(type) {
+	case []any:
+		for _, item := range v {
+			if object, ok := item.(map[string]any); ok {
+				objects = append(objects, object)
+			}
+		}
+	case map[string]any:
+		objects = append(objects, v)
+	}
+
+	var entries []Entry
+	for i, object := range objects {
+		name := filename
+		if len(objects) > 1 {
+			name = fmt.Sprintf("%s #%d", filename, i+1)
+		}
+		entries = append(entries, readObject(name, object)...)
+	}
+	return entries, nil
+}
+
+// readObject recognises a single JSON object by the fields each app exports
+func readObject(name string, object map[string]any) []Entry {
+	switch {
+	case object["@type"] != nil || object["@graph"] != nil:
+		return readJSONLD(name, object)
+	case isTandoor(object):
+		imported, err := readTandoor(object)
+		return []Entry{newEntry(name, imported, err)}
+	case object["recipeIngredient"] != nil:
+		imported, err := readMealie(object)
+		return []Entry{newEntry(name, imported, err)}
+	case isPaprika(object):
+		imported, err := readPaprika(object)
+		return []Entry{newEntry(name, imported, err)}
+	}
+	return []Entry{{Name: name, Err: errors.New("not a recipe"), Skipped: true}}
+}
+
+// newEntry names an entry after its recipe once it has been read. Names
+// longer than MaxRecipeNameLength fail, as they would on an edit.
+func newEntry(name string, imported *models.Recipe, err error) Entry {
+	if err == nil && utf8.RuneCountInString(imported.Name) > recipe.MaxRecipeNameLength {
+		err = recipe.ErrInvalidRecipeName
+	}
+	if err != nil {
+		return Entry{Name: name, Err: err}
+	}
+	imported.URL = importedURL(imported.URL)
+	return Entry{Name: imported.Name, Recipe: imported}
+}
+
+// importedURL is the canonical form of a recipe's source URL, or empty if
+// it isn't a web page. Exports may hold any string there, and the URL ends
+// up in links, calendars and other exports.
+func importedURL(raw string) string {
+	if raw == "" || strings.ContainsFunc(raw, unicode.IsControl) {
+		return ""
+	}
+	canonical, err := recipe.CanonicalizeURL(raw)
+	if err != nil {
+		return ""
+	}
+	return canonical
+}
+
+// Import adds the recipes read from exports to a mix, shared by the given
+// user. Images aren't imported, so a large export doesn't download hundreds
+// of pictures; duplicates of recipes already in the mix are skipped.
+func Import(service *recipe.RecipeService, entries []Entry, mixId string, sharerID string, sharerName string) recipe.ImportResult {
+	result := recipe.ImportResult{Added: []*models.Recipe{}, Skipped: []recipe.ImportIssue{}, Failed: []recipe.ImportIssue{}}
+	for _, entry := range entries {
+		switch {
+		case entry.Skipped:
+			result.Skipped = append(result.Skipped, recipe.ImportIssue{Name: entry.Name, Reason: entry.Err.Error()})
+		case entry.Err != nil:
+			result.Failed = append(result.Failed, recipe.ImportIssue{Name: entry.Name, Reason: entry.Err.Error()})
+		default:
+			imported := entry.Recipe
+			imported.SharerID = sharerID
+			imported.SharerName = sharerName
+			imported.Image = nil
+			stored, duplicate := service.AddRecipe(mixId, imported)
+			if duplicate {
+				result.Skipped = append(result.Skipped, recipe.ImportIssue{Name: entry.Name, Reason: fmt.Sprintf("already in mix as %q", stored.Name)})
+			} else {
+				result.Added = append(result.Added, stored)
+			}
+		}
+	}
+	return result
+}
+
+// newRecipe builds a recipe from an export, checking it has what every recipe needs
+func newRecipe(name string, url string, ingredients []models.Ingredient) (*models.Recipe, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("recipe has no name")
+	}
+	if len(ingredients) == 0 {
+		return nil, recipe.ErrNoIngredients
+	}
+	return &models.Recipe{
+		Name:        name,
+		URL:         strings.TrimSpace(url),
+		Ingredients: ingredients,
+	}, nil
+}
+
+// parseLines parses one ingredient per non-blank line
+func parseLines(text string) []models.Ingredient {
+	var ingredients []models.Ingredient
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			ingredients = append(ingredients, recipe.ParseIngredient(line))
+		}
+	}
+	return ingredients
+}
+
+// formatAmount renders an exported number, which may be a JSON number or a
+// string such as "1.000", without trailing zeros. Zero means no amount.
+func formatAmount(value any) *string {
+	var amount float64
+	switch v := value.(type) {
+	case float64:
+		amount = v
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return nil
+		}
+		amount = parsed
+	default:
+		return nil
+	}
+	if amount <= 0 {
+		return nil
+	}
+	formatted := strconv.FormatFloat(amount, 'f', -1, 64)
+	return &formatted
+}
+
+// nestedName returns object[key]["name"], as used for foods and units
+func nestedName(object map[string]any, key string) string {
+	nested, _ := object[key].(map[string]any)
+	name, _ := nested["name"].(string)
+	return strings.TrimSpace(name)
+}
+
+func stringField(object map[string]any, key string) string {
+	value, _ := object[key].(string)
+	return value
+}
+
+func isZip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+func isGzip(data []byte) bool {
+	return bytes.HasPrefix(data, []byte{0x1f, 0x8b})
+}
+
+func isJSON(filename string, data []byte) bool {
+	if strings.EqualFold(path.Ext(filename), ".json") {
+		return true
+	}
+	trimmed := bytes.TrimSpace(data)
+	return bytes.HasPrefix(trimmed, []byte("{")) || bytes.HasPrefix(trimmed, []byte("["))
+}
@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+
+	"kitchenmix/api/internal/services/recipe"
+)
+
+// readJSONLD reads every schema.org Recipe in a JSON-LD document, whether it
+// is the document itself or one of the nodes in its @graph
+func readJSONLD(name string, object map[string]any) []Entry {
+	nodes := []any{object}
+	if graph, ok := object["@graph"].([]any); ok {
+		nodes = graph
+	}
+
+	var entries []Entry
+	for _, node := range nodes {
+		n, ok := node.(map[string]any)
+		if !ok || !isRecipeType(n["@type"]) {
+			continue
+		}
+		imported, err := recipe.RecipeFromJSONLD(n)
+		entries = append(entries, newEntry(name, imported, err))
+	}
+
+	if len(entries) == 0 {
+		return []Entry{{Name: name, Err: errors.New("no schema.org Recipe in the document"), Skipped: true}}
+	}
+	// Tell apart recipes in the same @graph that couldn't be read
+	if len(entries) > 1 {
+		for i := range entries {
+			if entries[i].Err != nil {
+				entries[i].Name = fmt.Sprintf("%s #%d", name, i+1)
+			}
+		}
+	}
+	return entries
+}
+
+// isRecipeType reports whether a JSON-LD @type is or includes Recipe
+func isRecipeType(value any) bool {
+	switch v := value.(type) {
+	case string:
+		return v == "Recipe"
+	case []any:
+		for _, t := range v {
+			if t == "Recipe" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"strings"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/recipe"
+)
+
+// readMealie maps a Mealie recipe onto a recipe. Mealie exports parsed
+// ingredients as objects with a food, unit and quantity, and unparsed ones
+// as a note or the original text; older versions export plain strings.
+func readMealie(object map[string]any) (*models.Recipe, error) {
+	items, _ := object["recipeIngredient"].([]any)
+
+	var ingredients []models.Ingredient
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			if line := strings.TrimSpace(v); line != "" {
+				ingredients = append(ingredients, recipe.ParseIngredient(line))
+			}
+		case map[string]any:
+			if ingredient, ok := mealieIngredient(v); ok {
+				ingredients = append(ingredients, ingredient)
+			}
+		}
+	}
+
+	url := stringField(object, "orgURL")
+	if url == "" {
+		url = stringField(object, "org_url")
+	}
+	return newRecipe(stringField(object, "name"), url, ingredients)
+}
+
+// mealieIngredient maps one parsed or unparsed Mealie ingredient
+func mealieIngredient(item map[string]any) (models.Ingredient, bool) {
+	if food := nestedName(item, "food"); food != "" {
+		ingredient := models.Ingredient{Name: food, Quantity: formatAmount(item["quantity"])}
+		if unit := nestedName(item, "unit"); unit != "" {
+			ingredient.Unit = &unit
+		}
+		return ingredient, true
+	}
+
+	for _, key := range []string{"originalText", "note", "display"} {
+		if text := strings.TrimSpace(stringField(item, key)); text != "" {
+			return recipe.ParseIngredient(text), true
+		}
+	}
+	return models.Ingredient{}, false
+}
@@ -0,0 +1,55 @@
+package importer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"kitchenmix/api/internal/models"
+)
+
+// A .paprikarecipes export is a zip of .paprikarecipe files, each a gzipped
+// JSON object with the ingredients as one newline-separated string
+
+// isPaprika recognises a decompressed Paprika recipe by its fields
+func isPaprika(object map[string]any) bool {
+	_, hasIngredients := object["ingredients"].(string)
+	_, hasUID := object["uid"]
+	_, hasSource := object["source_url"]
+	return hasIngredients && (hasUID || hasSource)
+}
+
+// readPaprikaRecipe decompresses and reads a single .paprikarecipe file. The
+// error is ErrImportTooLarge once the budget runs out; other failures are
+// the entry's.
+func (b *Budget) readPaprikaRecipe(name string, data []byte) (Entry, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return Entry{Name: name, Err: fmt.Errorf("%w: %v", ErrUnknownFormat, err)}, nil
+	}
+	defer gz.Close()
+
+	raw, err := b.readAll(gz)
+	switch {
+	case errors.Is(err, ErrImportTooLarge):
+		return Entry{}, err
+	case errors.Is(err, ErrEntryTooLarge):
+		return Entry{Name: name, Err: err}, nil
+	case err != nil:
+		return Entry{Name: name, Err: fmt.Errorf("%w: %v", ErrUnknownFormat, err)}, nil
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return Entry{Name: name, Err: fmt.Errorf("%w: %v", ErrUnknownFormat, err)}, nil
+	}
+	imported, err := readPaprika(object)
+	return newEntry(name, imported, err), nil
+}
+
+// readPaprika maps a Paprika recipe onto a recipe
+func readPaprika(object map[string]any) (*models.Recipe, error) {
+	return newRecipe(stringField(object, "name"), stringField(object, "source_url"), parseLines(stringField(object, "ingredients")))
+}
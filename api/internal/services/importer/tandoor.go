@@ -0,0 +1,69 @@
+package importer
+
+import (
+	"strings"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/recipe"
+)
+
+// Tandoor exports a zip with one zip per recipe, each holding a recipe.json
+// whose ingredients are listed under the steps that use them
+
+// isTandoor recognises a Tandoor recipe by its steps with ingredients
+func isTandoor(object map[string]any) bool {
+	steps, ok := object["steps"].([]any)
+	if !ok {
+		return false
+	}
+	for _, step := range steps {
+		if s, ok := step.(map[string]any); ok && s["ingredients"] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readTandoor maps a Tandoor recipe onto a recipe, collecting the ingredients
+// of every step in order and leaving out section headers
+func readTandoor(object map[string]any) (*models.Recipe, error) {
+	steps, _ := object["steps"].([]any)
+
+	var ingredients []models.Ingredient
+	for _, step := range steps {
+		s, _ := step.(map[string]any)
+		items, _ := s["ingredients"].([]any)
+		for _, item := range items {
+			v, ok := item.(map[string]any)
+			if !ok || v["is_header"] == true {
+				continue
+			}
+			if ingredient, ok := tandoorIngredient(v); ok {
+				ingredients = append(ingredients, ingredient)
+			}
+		}
+	}
+
+	return newRecipe(stringField(object, "name"), stringField(object, "source_url"), ingredients)
+}
+
+// tandoorIngredient maps one Tandoor ingredient, falling back to its original
+// text when it has no food
+func tandoorIngredient(item map[string]any) (models.Ingredient, bool) {
+	food := nestedName(item, "food")
+	if food == "" {
+		if text := strings.TrimSpace(stringField(item, "original_text")); text != "" {
+			return recipe.ParseIngredient(text), true
+		}
+		return models.Ingredient{}, false
+	}
+
+	ingredient := models.Ingredient{Name: food}
+	if item["no_amount"] != true {
+		ingredient.Quantity = formatAmount(item["amount"])
+		if unit := nestedName(item, "unit"); unit != "" {
+			ingredient.Unit = &unit
+		}
+	}
+	return ingredient, true
+}
@@ -0,0 +1,419 @@
+package mix
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/mixstore"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxTitleLength is the longest mix title accepted, in characters
+	MaxTitleLength = 100
+	// MaxDescriptionLength is the longest mix description accepted, in characters
+	MaxDescriptionLength = 1000
+)
+
+var (
+	// ErrNotFound is returned for mixes that were never created or have been deleted
+	ErrNotFound = errors.New("mix not found")
+	// ErrInvalidTitle is returned for empty or overlong titles
+	ErrInvalidTitle = fmt.Errorf("title must be 1 to %d characters", MaxTitleLength)
+	// ErrInvalidDescription is returned for overlong descriptions
+	ErrInvalidDescription = fmt.Errorf("description must be at most %d characters", MaxDescriptionLength)
+	// ErrInvalidRole is returned for roles other than editor or viewer where one of those is expected
+	ErrInvalidRole = errors.New("role must be editor or viewer")
+	// ErrNotMember is returned for users who haven't joined a mix
+	ErrNotMember = errors.New("not a member of this mix")
+	// ErrInvalidInvite is returned for invite tokens that don't exist or have been revoked
+	ErrInvalidInvite = errors.New("invite is invalid or has been revoked")
+	// ErrInviteExpired is returned for invites past their expiry
+	ErrInviteExpired = errors.New("invite has expired")
+	// ErrInviteUsedUp is returned for invites that have reached their maximum uses
+	ErrInviteUsedUp = errors.New("invite has been used the maximum number of times")
+	// ErrInvalidInviteLimits is returned for negative expiries or use limits
+	ErrInvalidInviteLimits = errors.New("expiry and max uses must not be negative")
+)
+
+// roleRanks orders roles by privilege
+var roleRanks = map[string]int{
+	models.RoleViewer: 1,
+	models.RoleEditor: 2,
+	models.RoleOwner:  3,
+}
+
+// RoleAllows reports whether role grants at least the privileges of required
+func RoleAllows(role string, required string) bool {
+	return roleRanks[role] > 0 && roleRanks[role] >= roleRanks[required]
+}
+
+// ValidateRole checks that role can be granted to a member or an invite. Owner
+// can't be granted; a mix has exactly one, its creator.
+func ValidateRole(role string) error {
+	if role != models.RoleEditor && role != models.RoleViewer {
+		return ErrInvalidRole
+	}
+	return nil
+}
+
+// Config controls where mixes are stored
+type Config struct {
+	// Dir enables disk persistence when non-empty
+	Dir string
+}
+
+// ConfigFromEnv reads MIX_DIR
+func ConfigFromEnv() Config {
+	cfg := Config{Dir: "tmp/mixes"}
+
+	if v, ok := os.LookupEnv("MIX_DIR"); ok {
+		cfg.Dir = v
+	}
+
+	return cfg
+}
+
+// MixService keeps track of which mixes exist and their metadata
+type MixService struct {
+	config Config
+
+	mu    sync.Mutex
+	mixes *mixstore.Store[*models.Mix]
+}
+
+// NewMixService creates a mix service. Mixes are loaded from disk lazily.
+func NewMixService(config Config) *MixService {
+	return &MixService{
+		config: config,
+		mixes:  mixstore.New[*models.Mix](config.Dir, "mix"),
+	}
+}
+
+// Create validates and stores a new mix owned by the given user
+func (s *MixService) Create(title string, description string, ownerID string, ownerName string) (*models.Mix, error) {
+	title, description, err := validate(title, description)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	mix := &models.Mix{
+		ID:          uuid.New().String(),
+		Title:       title,
+		Description: description,
+		Members: []models.MixMember{{
+			UserID:   ownerID,
+			UserName: ownerName,
+			Role:     models.RoleOwner,
+			JoinedAt: now,
+		}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.persist(mix)
+
+	return clone(mix), nil
+}
+
+// Get returns a copy of a mix
+func (s *MixService) Get(id string) (*models.Mix, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mix := s.load(id)
+	if mix == nil {
+		return nil, ErrNotFound
+	}
+
+	return clone(mix), nil
+}
+
+// Exists reports whether a mix has been created and not deleted
+func (s *MixService) Exists(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(id) != nil
+}
+
+// Update changes the title and/or description of a mix; nil leaves a field unchanged
+func (s *MixService) Update(id string, title *string, description *string) (*models.Mix, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mix := s.load(id)
+	if mix == nil {
+		return nil, ErrNotFound
+	}
+
+	newTitle, newDescription := mix.Title, mix.Description
+	if title != nil {
+		newTitle = *title
+	}
+	if description != nil {
+		newDescription = *description
+	}
+	newTitle, newDescription, err := validate(newTitle, newDescription)
+	if err != nil {
+		return nil, err
+	}
+	mix.Title = newTitle
+	mix.Description = newDescription
+	mix.UpdatedAt = time.Now().UTC()
+	s.persist(mix)
+
+	return clone(mix), nil
+}
+
+// Join returns the user's membership of a mix, failing with ErrNotMember for
+// users who haven't redeemed an invite. The stored name follows renames.
+func (s *MixService) Join(id string, userID string, userName string) (models.MixMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mix := s.load(id)
+	if mix == nil {
+		return models.MixMember{}, ErrNotFound
+	}
+
+	i := slices.IndexFunc(mix.Members, func(m models.MixMember) bool { return m.UserID == userID })
+	if i < 0 {
+		return models.MixMember{}, ErrNotMember
+	}
+	if mix.Members[i].UserName != userName {
+		mix.Members[i].UserName = userName
+		s.persist(mix)
+	}
+	return mix.Members[i], nil
+}
+
+// CreateInvite adds an invite granting role. A zero ttl never expires and zero
+// maxUses is unlimited.
+func (s *MixService) CreateInvite(id string, createdBy string, role string, ttl time.Duration, maxUses int) (models.Invite, error) {
+	if err := ValidateRole(role); err != nil {
+		return models.Invite{}, err
+	}
+	if ttl < 0 || maxUses < 0 {
+		return models.Invite{}, ErrInvalidInviteLimits
+	}
+
+	secret := make([]byte, 18)
+	if _, err := rand.Read(secret); err != nil {
+		return models.Invite{}, err
+	}
+
+	now := time.Now().UTC()
+	invite := models.Invite{
+		ID:        uuid.New().String(),
+		Token:     base64.RawURLEncoding.EncodeToString(secret),
+		Role:      role,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		MaxUses:   maxUses,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		invite.ExpiresAt = &expiresAt
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mix := s.load(id)
+	if mix == nil {
+		return models.Invite{}, ErrNotFound
+	}
+
+	mix.Invites = append(mix.Invites, invite)
+	s.persist(mix)
+
+	return invite, nil
+}
+
+// Redeem exchanges an invite token for membership of a mix. Existing members
+// keep their role and don't use up the invite.
+func (s *MixService) Redeem(id string, token string, userID string, userName string) (models.MixMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mix := s.load(id)
+	if mix == nil {
+		return models.MixMember{}, ErrNotFound
+	}
+
+	if i := slices.IndexFunc(mix.Members, func(m models.MixMember) bool { return m.UserID == userID }); i >= 0 {
+		return mix.Members[i], nil
+	}
+
+	i := slices.IndexFunc(mix.Invites, func(inv models.Invite) bool {
+		return subtle.ConstantTimeCompare([]byte(inv.Token), []byte(token)) == 1
+	})
+	if token == "" || i < 0 || mix.Invites[i].RevokedAt != nil {
+		return models.MixMember{}, ErrInvalidInvite
+	}
+	invite := &mix.Invites[i]
+	now := time.Now().UTC()
+	if invite.ExpiresAt != nil && !now.Before(*invite.ExpiresAt) {
+		return models.MixMember{}, ErrInviteExpired
+	}
+	if invite.MaxUses > 0 && invite.Uses >= invite.MaxUses {
+		return models.MixMember{}, ErrInviteUsedUp
+	}
+
+	invite.Uses++
+	member := models.MixMember{
+		UserID:   userID,
+		UserName: userName,
+		Role:     invite.Role,
+		JoinedAt: now,
+		InviteID: invite.ID,
+	}
+	mix.Members = append(mix.Members, member)
+	s.persist(mix)
+
+	return member, nil
+}
+
+// RevokeInvite stops an invite from being redeemed and removes the members who
+// joined through it, returning their user IDs
+func (s *MixService) RevokeInvite(id string, inviteID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mix := s.load(id)
+	if mix == nil {
+		return nil, ErrNotFound
+	}
+
+	i := slices.IndexFunc(mix.Invites, func(inv models.Invite) bool { return inv.ID == inviteID })
+	if i < 0 {
+		return nil, ErrInvalidInvite
+	}
+	if mix.Invites[i].RevokedAt == nil {
+		now := time.Now().UTC()
+		mix.Invites[i].RevokedAt = &now
+	}
+
+	var removed []string
+	mix.Members = slices.DeleteFunc(mix.Members, func(m models.MixMember) bool {
+		if m.InviteID == inviteID && m.Role != models.RoleOwner {
+			removed = append(removed, m.UserID)
+			return true
+		}
+		return false
+	})
+	mix.UpdatedAt = time.Now().UTC()
+	s.persist(mix)
+
+	return removed, nil
+}
+
+// Member returns a user's membership of a mix
+func (s *MixService) Member(id string, userID string) (models.MixMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mix := s.load(id)
+	if mix == nil {
+		return models.MixMember{}, ErrNotFound
+	}
+
+	i := slices.IndexFunc(mix.Members, func(m models.MixMember) bool { return m.UserID == userID })
+	if i < 0 {
+		return models.MixMember{}, ErrNotMember
+	}
+	return mix.Members[i], nil
+}
+
+// SetRole changes the role of a member other than the owner
+func (s *MixService) SetRole(id string, userID string, role string) (models.MixMember, error) {
+	if err := ValidateRole(role); err != nil {
+		return models.MixMember{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mix := s.load(id)
+	if mix == nil {
+		return models.MixMember{}, ErrNotFound
+	}
+
+	i := slices.IndexFunc(mix.Members, func(m models.MixMember) bool { return m.UserID == userID })
+	if i < 0 {
+		return models.MixMember{}, ErrNotMember
+	}
+	if mix.Members[i].Role == models.RoleOwner {
+		return models.MixMember{}, ErrInvalidRole
+	}
+
+	mix.Members[i].Role = role
+	mix.UpdatedAt = time.Now().UTC()
+	s.persist(mix)
+
+	return mix.Members[i], nil
+}
+
+// Delete removes a mix
+func (s *MixService) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.load(id) == nil {
+		return ErrNotFound
+	}
+
+	s.mixes.Delete(id)
+	return nil
+}
+
+// clone copies a mix so callers can't modify the stored one
+func clone(mix *models.Mix) *models.Mix {
+	copied := *mix
+	copied.Members = slices.Clone(mix.Members)
+	copied.Invites = slices.Clone(mix.Invites)
+	return &copied
+}
+
+func validate(title string, description string) (string, string, error) {
+	title = strings.TrimSpace(title)
+	description = strings.TrimSpace(description)
+
+	if title == "" || utf8.RuneCountInString(title) > MaxTitleLength {
+		return "", "", ErrInvalidTitle
+	}
+	if utf8.RuneCountInString(description) > MaxDescriptionLength {
+		return "", "", ErrInvalidDescription
+	}
+	return title, description, nil
+}
+
+// load returns a mix, reading it from disk if it isn't in memory. Callers hold s.mu.
+func (s *MixService) load(id string) *models.Mix {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil
+	}
+	mix, _ := s.mixes.Get(id)
+	return mix
+}
+
+// persist stores a mix and writes it to disk. Callers hold s.mu.
+func (s *MixService) persist(mix *models.Mix) {
+	s.mixes.Put(mix.ID, mix)
+}
@@ -0,0 +1,114 @@
+// Package mixstore keeps a value for each mix in memory, backed by one JSON
+// file per mix. It holds the state of the services that persist per mix.
+package mixstore
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+// Store holds one T per mix, loaded from disk lazily. Mix IDs that aren't
+// UUIDs are kept in memory only, so they can't name files outside the
+// directory. It is not safe for concurrent use; services guard it with their
+// own lock.
+type Store[T any] struct {
+	// dir enables disk persistence when non-empty
+	dir string
+	// name says what a value is in log messages, such as "plan"
+	name   string
+	values map[string]T
+}
+
+// New creates a store persisting to dir, which may be empty to keep values in
+// memory only
+func New[T any](dir string, name string) *Store[T] {
+	return &Store[T]{
+		dir:    dir,
+		name:   name,
+		values: make(map[string]T),
+	}
+}
+
+// Get returns the value of a mix, reading it from disk on first use. ok is
+// false when there is none, in which case the zero value is returned.
+func (s *Store[T]) Get(mixID string) (value T, ok bool) {
+	if value, ok := s.values[mixID]; ok {
+		return value, true
+	}
+	if !s.persisted(mixID) {
+		return value, false
+	}
+
+	data, err := os.ReadFile(s.path(mixID))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read %s for mix %s: %v", s.name, mixID, err)
+		}
+		return value, false
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		log.Printf("Failed to read %s for mix %s: %v", s.name, mixID, err)
+		return value, false
+	}
+
+	s.values[mixID] = value
+	return value, true
+}
+
+// Put stores the value of a mix and writes it to disk
+func (s *Store[T]) Put(mixID string, value T) {
+	s.values[mixID] = value
+	if !s.persisted(mixID) {
+		return
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		log.Printf("Failed to create %s directory %s: %v", s.name, s.dir, err)
+		return
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Failed to encode %s for mix %s: %v", s.name, mixID, err)
+		return
+	}
+
+	path := s.path(mixID)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		log.Printf("Failed to write %s for mix %s: %v", s.name, mixID, err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("Failed to write %s for mix %s: %v", s.name, mixID, err)
+	}
+}
+
+// Delete forgets the value of a mix and removes it from disk
+func (s *Store[T]) Delete(mixID string) {
+	delete(s.values, mixID)
+	if !s.persisted(mixID) {
+		return
+	}
+
+	if err := os.Remove(s.path(mixID)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to delete %s for mix %s: %v", s.name, mixID, err)
+	}
+}
+
+// persisted reports whether the value of a mix is kept on disk
+func (s *Store[T]) persisted(mixID string) bool {
+	if s.dir == "" {
+		return false
+	}
+	_, err := uuid.Parse(mixID)
+	return err == nil
+}
+
+func (s *Store[T]) path(mixID string) string {
+	return filepath.Join(s.dir, mixID+".json")
+}
@@ -0,0 +1,223 @@
+// Package pantry keeps what each mix has in stock, so shopping lists can
+// leave out what doesn't need buying.
+package pantry
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/mixstore"
+	"kitchenmix/api/internal/services/shopping"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxNameLength is the longest item name, in characters
+	MaxNameLength = 100
+	// MaxQuantityLength is the longest quantity or unit, in characters
+	MaxQuantityLength = 50
+	// MaxItems is the most items a mix's pantry holds
+	MaxItems = 500
+)
+
+var (
+	// ErrInvalidMix is returned when the mix ID is not a UUID
+	ErrInvalidMix = errors.New("invalid mix ID")
+	// ErrInvalidName is returned for empty names and names over MaxNameLength characters
+	ErrInvalidName = fmt.Errorf("name must be 1 to %d characters", MaxNameLength)
+	// ErrInvalidQuantity is returned for quantities that can't be read as numbers
+	// and for quantities or units over MaxQuantityLength characters
+	ErrInvalidQuantity = fmt.Errorf("quantity must be a number such as 2, 1.5 or 1 1/2, with quantity and unit at most %d characters", MaxQuantityLength)
+	// ErrItemExists is returned when the pantry already has an item by that name
+	ErrItemExists = errors.New("pantry already has an item by that name")
+	// ErrItemNotFound is returned for items that aren't in the mix's pantry
+	ErrItemNotFound = errors.New("pantry item not found")
+	// ErrPantryFull is returned when a pantry already has MaxItems items
+	ErrPantryFull = fmt.Errorf("pantry already has %d items", MaxItems)
+)
+
+// Config controls where pantries are stored
+type Config struct {
+	// Dir enables disk persistence when non-empty
+	Dir string
+}
+
+// ConfigFromEnv reads PANTRY_DIR
+func ConfigFromEnv() Config {
+	cfg := Config{Dir: "tmp/pantries"}
+
+	if v, ok := os.LookupEnv("PANTRY_DIR"); ok {
+		cfg.Dir = v
+	}
+
+	return cfg
+}
+
+// PantryService stores the pantry of each mix, ordered by name
+type PantryService struct {
+	config Config
+
+	mu       sync.Mutex
+	pantries *mixstore.Store[[]models.PantryItem]
+}
+
+// NewPantryService creates a pantry service. Pantries are loaded from disk lazily.
+func NewPantryService(config Config) *PantryService {
+	return &PantryService{
+		config:   config,
+		pantries: mixstore.New[[]models.PantryItem](config.Dir, "pantry"),
+	}
+}
+
+// Items returns the pantry of a mix
+func (s *PantryService) Items(mixID string) []models.PantryItem {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return []models.PantryItem{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.PantryItem{}, s.load(mixID)...)
+}
+
+// Add validates and stores an item, assigning its ID and timestamp
+func (s *PantryService) Add(mixID string, item models.PantryItem) (models.PantryItem, error) {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return models.PantryItem{}, ErrInvalidMix
+	}
+	if err := validate(&item); err != nil {
+		return models.PantryItem{}, err
+	}
+
+	item.ID = uuid.New().String()
+	item.UpdatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.load(mixID)
+	if len(items) >= MaxItems {
+		return models.PantryItem{}, ErrPantryFull
+	}
+	if named(items, item.Name, "") {
+		return models.PantryItem{}, ErrItemExists
+	}
+	s.save(mixID, append(slices.Clone(items), item))
+	return item, nil
+}
+
+// Replace updates an item's name, grocery item, quantity and unit, e.g. as
+// stock is used up or restocked
+func (s *PantryService) Replace(mixID string, itemID string, item models.PantryItem) (models.PantryItem, error) {
+	if err := validate(&item); err != nil {
+		return models.PantryItem{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := slices.Clone(s.load(mixID))
+	i := slices.IndexFunc(items, func(p models.PantryItem) bool { return p.ID == itemID })
+	if i < 0 {
+		return models.PantryItem{}, ErrItemNotFound
+	}
+	if named(items, item.Name, itemID) {
+		return models.PantryItem{}, ErrItemExists
+	}
+
+	item.ID = itemID
+	item.UpdatedAt = time.Now().UTC()
+	items[i] = item
+	s.save(mixID, items)
+	return item, nil
+}
+
+// Remove takes an item out of the pantry
+func (s *PantryService) Remove(mixID string, itemID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := s.load(mixID)
+	i := slices.IndexFunc(items, func(p models.PantryItem) bool { return p.ID == itemID })
+	if i < 0 {
+		return ErrItemNotFound
+	}
+	s.save(mixID, slices.Delete(slices.Clone(items), i, i+1))
+	return nil
+}
+
+// Delete removes the pantry of a mix
+func (s *PantryService) Delete(mixID string) {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pantries.Delete(mixID)
+}
+
+// validate checks an item's name and quantity, tidying them. An empty
+// quantity or unit is none.
+func validate(item *models.PantryItem) error {
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" || utf8.RuneCountInString(item.Name) > MaxNameLength {
+		return ErrInvalidName
+	}
+	if item.Quantity != nil {
+		quantity := strings.TrimSpace(*item.Quantity)
+		if quantity == "" {
+			item.Quantity = nil
+		} else if utf8.RuneCountInString(quantity) > MaxQuantityLength {
+			return ErrInvalidQuantity
+		} else if value, ok := shopping.ParseQuantity(quantity); !ok || value <= 0 {
+			return ErrInvalidQuantity
+		} else {
+			item.Quantity = &quantity
+		}
+	}
+	if item.Unit != nil {
+		unit := strings.TrimSpace(*item.Unit)
+		if unit == "" {
+			item.Unit = nil
+		} else if utf8.RuneCountInString(unit) > MaxQuantityLength {
+			return ErrInvalidQuantity
+		} else {
+			item.Unit = &unit
+		}
+	}
+	return nil
+}
+
+// named reports whether an item other than exceptID has the given name,
+// compared as shopping lists compare ingredients
+func named(items []models.PantryItem, name string, exceptID string) bool {
+	return slices.ContainsFunc(items, func(p models.PantryItem) bool {
+		return p.ID != exceptID && shopping.NormalizeName(p.Name) == shopping.NormalizeName(name)
+	})
+}
+
+// save orders a pantry and stores it. Callers hold s.mu.
+func (s *PantryService) save(mixID string, items []models.PantryItem) {
+	slices.SortStableFunc(items, func(a, b models.PantryItem) int {
+		return cmp.Compare(shopping.NormalizeName(a.Name), shopping.NormalizeName(b.Name))
+	})
+	s.pantries.Put(mixID, items)
+}
+
+// load returns the pantry of a mix, reading it from disk on first use. Callers hold s.mu.
+func (s *PantryService) load(mixID string) []models.PantryItem {
+	value, _ := s.pantries.Get(mixID)
+	return value
+}
@@ -0,0 +1,119 @@
+package plan
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/shopping"
+)
+
+// mealTimes are when each meal is put in calendars, as local "floating" times
+// so they stay at the same hour wherever the calendar is opened
+var mealTimes = map[string]string{
+	models.MealBreakfast: "080000",
+	models.MealLunch:     "123000",
+	models.MealSnack:     "160000",
+	models.MealDinner:    "190000",
+}
+
+// WriteICS writes entries as an iCalendar (RFC 5545) calendar named title,
+// with an hour-long event per entry at the time of its meal. recipes maps
+// recipe IDs to the recipes the entries are for.
+func WriteICS(w io.Writer, title string, entries []models.PlanEntry, recipes map[string]*models.Recipe) error {
+	var b strings.Builder
+	line := func(name string, value string) {
+		writeFolded(&b, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", "-//KitchenMix//Meal plan//EN")
+	line("CALSCALE", "GREGORIAN")
+	line("X-WR-CALNAME", escapeText(title))
+	for _, entry := range entries {
+		day, err := time.Parse(DateLayout, entry.Date)
+		if err != nil {
+			continue
+		}
+		start := day.Format("20060102") + "T" + mealTimes[entry.Meal]
+
+		name := "Recipe"
+		var description []string
+		link := ""
+		if recipe, ok := recipes[entry.RecipeID]; ok {
+			name = recipe.Name
+			link = calendarURL(recipe.URL)
+			for _, ingredient := range recipe.Ingredients {
+				description = append(description, "- "+shopping.FormatIngredient(ingredient))
+			}
+		}
+		if entry.Note != "" {
+			description = append([]string{entry.Note, ""}, description...)
+		}
+
+		line("BEGIN", "VEVENT")
+		line("UID", entry.ID+"@kitchenmix")
+		line("DTSTAMP", entry.UpdatedAt.UTC().Format("20060102T150405Z"))
+		line("DTSTART", start)
+		line("DURATION", "PT1H")
+		line("SUMMARY", escapeText(fmt.Sprintf("%s: %s", strings.ToUpper(entry.Meal[:1])+entry.Meal[1:], name)))
+		if len(description) > 0 {
+			line("DESCRIPTION", escapeText(strings.Join(description, "\n")))
+		}
+		if link != "" {
+			line("URL", link)
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// calendarURL is a recipe's URL as a URI value, or empty unless it is an
+// absolute http(s) URL. URI values aren't escaped, so control characters
+// such as line breaks could otherwise start properties of their own.
+func calendarURL(raw string) string {
+	if strings.ContainsFunc(raw, unicode.IsControl) {
+		return ""
+	}
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return ""
+	}
+	return parsed.String()
+}
+
+// escapeText escapes a TEXT value: backslashes, separators and newlines
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// writeFolded writes a content line, folding it onto continuation lines so
+// none is longer than 75 octets, without splitting a UTF-8 character
+func writeFolded(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts towards their length
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func isRuneStart(c byte) bool {
+	return c&0xC0 != 0x80
+}
@@ -0,0 +1,251 @@
+// Package plan keeps the meal plan of each mix: which of its recipes are
+// cooked for which meal on which day.
+package plan
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/mixstore"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DateLayout is how plan dates are written
+	DateLayout = "2006-01-02"
+	// MaxNoteLength is the longest note on an entry, in characters
+	MaxNoteLength = 500
+	// MaxEntries is the most entries a mix's plan holds
+	MaxEntries = 1000
+	// MaxRangeDays is the longest date range a plan can be read for at once
+	MaxRangeDays = 366
+)
+
+var (
+	// ErrInvalidMix is returned when the mix ID is not a UUID
+	ErrInvalidMix = errors.New("invalid mix ID")
+	// ErrInvalidDate is returned for dates that aren't YYYY-MM-DD
+	ErrInvalidDate = errors.New("date must be YYYY-MM-DD")
+	// ErrInvalidRange is returned for ranges that end before they start or are too long
+	ErrInvalidRange = fmt.Errorf("range must end on or after its start and span at most %d days", MaxRangeDays)
+	// ErrInvalidMeal is returned for meals other than the slots in Meals
+	ErrInvalidMeal = errors.New("meal must be breakfast, lunch, dinner or snack")
+	// ErrNoteTooLong is returned for notes over MaxNoteLength characters
+	ErrNoteTooLong = fmt.Errorf("note is longer than %d characters", MaxNoteLength)
+	// ErrEntryNotFound is returned for entries that aren't in the mix's plan
+	ErrEntryNotFound = errors.New("plan entry not found")
+	// ErrPlanFull is returned when a plan already has MaxEntries entries
+	ErrPlanFull = fmt.Errorf("plan already has %d entries", MaxEntries)
+)
+
+// Meals are the meal slots of a day, in order
+var Meals = []string{models.MealBreakfast, models.MealLunch, models.MealDinner, models.MealSnack}
+
+// Config controls where plans are stored
+type Config struct {
+	// Dir enables disk persistence when non-empty
+	Dir string
+}
+
+// ConfigFromEnv reads PLAN_DIR
+func ConfigFromEnv() Config {
+	cfg := Config{Dir: "tmp/plans"}
+
+	if v, ok := os.LookupEnv("PLAN_DIR"); ok {
+		cfg.Dir = v
+	}
+
+	return cfg
+}
+
+// PlanService stores the meal plan of each mix, ordered by date and meal
+type PlanService struct {
+	config Config
+
+	mu    sync.Mutex
+	plans *mixstore.Store[[]models.PlanEntry]
+}
+
+// NewPlanService creates a plan service. Plans are loaded from disk lazily.
+func NewPlanService(config Config) *PlanService {
+	return &PlanService{
+		config: config,
+		plans:  mixstore.New[[]models.PlanEntry](config.Dir, "plan"),
+	}
+}
+
+// Add validates and stores an entry, assigning its ID and timestamps. The
+// caller checks that its recipe is in the mix.
+func (s *PlanService) Add(mixID string, entry models.PlanEntry) (models.PlanEntry, error) {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return models.PlanEntry{}, ErrInvalidMix
+	}
+	if err := validate(&entry); err != nil {
+		return models.PlanEntry{}, err
+	}
+
+	entry.ID = uuid.New().String()
+	entry.CreatedAt = time.Now().UTC()
+	entry.UpdatedAt = entry.CreatedAt
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.load(mixID)
+	if len(entries) >= MaxEntries {
+		return models.PlanEntry{}, ErrPlanFull
+	}
+	s.save(mixID, append(slices.Clone(entries), entry))
+	return entry, nil
+}
+
+// Move reschedules an entry for another date and meal
+func (s *PlanService) Move(mixID string, entryID string, date string, meal string) (models.PlanEntry, error) {
+	moved := models.PlanEntry{Date: date, Meal: meal}
+	if err := validate(&moved); err != nil {
+		return models.PlanEntry{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := slices.Clone(s.load(mixID))
+	i := slices.IndexFunc(entries, func(e models.PlanEntry) bool { return e.ID == entryID })
+	if i < 0 {
+		return models.PlanEntry{}, ErrEntryNotFound
+	}
+	entries[i].Date = moved.Date
+	entries[i].Meal = moved.Meal
+	entries[i].UpdatedAt = time.Now().UTC()
+	entry := entries[i]
+	s.save(mixID, entries)
+	return entry, nil
+}
+
+// Remove takes an entry off the plan
+func (s *PlanService) Remove(mixID string, entryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.load(mixID)
+	i := slices.IndexFunc(entries, func(e models.PlanEntry) bool { return e.ID == entryID })
+	if i < 0 {
+		return ErrEntryNotFound
+	}
+	s.save(mixID, slices.Delete(slices.Clone(entries), i, i+1))
+	return nil
+}
+
+// RemoveRecipe takes every entry for a recipe off the plan, e.g. when the
+// recipe is removed from the mix, and returns their IDs
+func (s *PlanService) RemoveRecipe(mixID string, recipeID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed []string
+	kept := slices.DeleteFunc(slices.Clone(s.load(mixID)), func(e models.PlanEntry) bool {
+		if e.RecipeID == recipeID {
+			removed = append(removed, e.ID)
+			return true
+		}
+		return false
+	})
+	if len(removed) > 0 {
+		s.save(mixID, kept)
+	}
+	return removed
+}
+
+// Entries returns the entries from one date to another, inclusive, ordered
+// by date and meal. Empty bounds are open.
+func (s *PlanService) Entries(mixID string, from string, to string) []models.PlanEntry {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return []models.PlanEntry{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := []models.PlanEntry{}
+	for _, entry := range s.load(mixID) {
+		if (from == "" || entry.Date >= from) && (to == "" || entry.Date <= to) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// Delete removes the plan of a mix
+func (s *PlanService) Delete(mixID string) {
+	if _, err := uuid.Parse(mixID); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.plans.Delete(mixID)
+}
+
+// ValidateRange checks a date range, either end of which may be empty.
+// Ranges with both ends may span at most MaxRangeDays.
+func ValidateRange(from string, to string) error {
+	var start, end time.Time
+	var err error
+	if from != "" {
+		if start, err = time.Parse(DateLayout, from); err != nil {
+			return ErrInvalidDate
+		}
+	}
+	if to != "" {
+		if end, err = time.Parse(DateLayout, to); err != nil {
+			return ErrInvalidDate
+		}
+	}
+	if from != "" && to != "" && (end.Before(start) || end.Sub(start) >= MaxRangeDays*24*time.Hour) {
+		return ErrInvalidRange
+	}
+	return nil
+}
+
+// validate checks an entry's date, meal and note, tidying them
+func validate(entry *models.PlanEntry) error {
+	if _, err := time.Parse(DateLayout, entry.Date); err != nil {
+		return ErrInvalidDate
+	}
+	entry.Meal = strings.ToLower(strings.TrimSpace(entry.Meal))
+	if !slices.Contains(Meals, entry.Meal) {
+		return ErrInvalidMeal
+	}
+	entry.Note = strings.TrimSpace(entry.Note)
+	if utf8.RuneCountInString(entry.Note) > MaxNoteLength {
+		return ErrNoteTooLong
+	}
+	return nil
+}
+
+// save orders a plan and stores it. Callers hold s.mu.
+func (s *PlanService) save(mixID string, entries []models.PlanEntry) {
+	slices.SortStableFunc(entries, func(a, b models.PlanEntry) int {
+		if c := cmp.Compare(a.Date, b.Date); c != 0 {
+			return c
+		}
+		return cmp.Compare(slices.Index(Meals, a.Meal), slices.Index(Meals, b.Meal))
+	})
+	s.plans.Put(mixID, entries)
+}
+
+// load returns the plan of a mix, reading it from disk on first use. Callers hold s.mu.
+func (s *PlanService) load(mixID string) []models.PlanEntry {
+	value, _ := s.plans.Get(mixID)
+	return value
+}
@@ -0,0 +1,298 @@
+package recipe
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"kitchenmix/api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// PageCacheConfig controls the shared fetched-page cache
+type PageCacheConfig struct {
+	TTL      time.Duration
+	MaxBytes int64
+	// Dir enables disk persistence when non-empty
+	Dir string
+}
+
+// PageCacheConfigFromEnv reads RECIPE_CACHE_* environment variables
+func PageCacheConfigFromEnv() PageCacheConfig {
+	cfg := PageCacheConfig{
+		TTL:      24 * time.Hour,
+		MaxBytes: 64 * 1024 * 1024,
+		Dir:      os.Getenv("RECIPE_CACHE_DIR"),
+	}
+
+	if v, err := time.ParseDuration(os.Getenv("RECIPE_CACHE_TTL")); err == nil && v > 0 {
+		cfg.TTL = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("RECIPE_CACHE_MAX_BYTES"), 10, 64); err == nil && v > 0 {
+		cfg.MaxBytes = v
+	}
+
+	return cfg
+}
+
+// CachedPage is a fetched page identified by the hash of its extracted content.
+// Several URLs may resolve to the same page.
+type CachedPage struct {
+	ContentHash string         `json:"contentHash"`
+	URLs        []string       `json:"urls"`
+	Content     string         `json:"content"`
+	Recipe      *models.Recipe `json:"recipe,omitempty"`
+	FetchedAt   time.Time      `json:"fetchedAt"`
+}
+
+// PageCache is a content-addressed, size-bounded LRU of fetched pages and
+// the recipes extracted from them, shared by every mix
+type PageCache struct {
+	config PageCacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // content hash -> LRU element
+	urls    map[string]string        // URL -> content hash
+	lru     *list.List
+	size    int64
+}
+
+// NewPageCache creates a cache, loading persisted entries when a directory is configured
+func NewPageCache(config PageCacheConfig) *PageCache {
+	cache := &PageCache{
+		config:  config,
+		entries: make(map[string]*list.Element),
+		urls:    make(map[string]string),
+		lru:     list.New(),
+	}
+
+	if config.Dir != "" {
+		if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+			log.Printf("Failed to create page cache directory %s: %v", config.Dir, err)
+			cache.config.Dir = ""
+		} else {
+			cache.load()
+		}
+	}
+
+	return cache
+}
+
+// HashContent returns the content address used by the cache
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetByURL returns the cached page for url if it exists and has not expired
+func (c *PageCache) GetByURL(url string) (*CachedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	hash, exists := c.urls[url]
+	if !exists {
+		return nil, false
+	}
+	return c.getLocked(hash)
+}
+
+// GetByContent returns the cached page whose content matches, regardless of URL
+func (c *PageCache) GetByContent(content string) (*CachedPage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.getLocked(HashContent(content))
+}
+
+// Put stores content fetched from url together with the recipe extracted from it
+func (c *PageCache) Put(url string, content string, recipe *models.Recipe) {
+	hash := HashContent(content)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Unlink the URL from any previous content it pointed to
+	if previous, exists := c.urls[url]; exists && previous != hash {
+		if elem, ok := c.entries[previous]; ok {
+			page := elem.Value.(*CachedPage)
+			page.URLs = slices.DeleteFunc(page.URLs, func(u string) bool { return u == url })
+		}
+	}
+
+	if elem, exists := c.entries[hash]; exists {
+		page := elem.Value.(*CachedPage)
+		if !slices.Contains(page.URLs, url) {
+			page.URLs = append(page.URLs, url)
+		}
+		if recipe != nil {
+			page.Recipe = recipe
+		}
+		c.urls[url] = hash
+		c.lru.MoveToFront(elem)
+		c.persist(page)
+		return
+	}
+
+	page := &CachedPage{
+		ContentHash: hash,
+		URLs:        []string{url},
+		Content:     content,
+		Recipe:      recipe,
+		FetchedAt:   time.Now(),
+	}
+	c.insertLocked(page)
+	c.persist(page)
+	c.evictLocked()
+}
+
+// Len returns the number of cached pages
+func (c *PageCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *PageCache) getLocked(hash string) (*CachedPage, bool) {
+	elem, exists := c.entries[hash]
+	if !exists {
+		return nil, false
+	}
+
+	page := elem.Value.(*CachedPage)
+	if time.Since(page.FetchedAt) > c.config.TTL {
+		c.removeLocked(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+
+	// Hand out a copy so callers never race with later Puts
+	snapshot := *page
+	snapshot.URLs = slices.Clone(page.URLs)
+	return &snapshot, true
+}
+
+func (c *PageCache) insertLocked(page *CachedPage) {
+	c.entries[page.ContentHash] = c.lru.PushFront(page)
+	for _, url := range page.URLs {
+		c.urls[url] = page.ContentHash
+	}
+	c.size += int64(len(page.Content))
+}
+
+func (c *PageCache) removeLocked(elem *list.Element) {
+	page := c.lru.Remove(elem).(*CachedPage)
+	delete(c.entries, page.ContentHash)
+	for _, url := range page.URLs {
+		if c.urls[url] == page.ContentHash {
+			delete(c.urls, url)
+		}
+	}
+	c.size -= int64(len(page.Content))
+
+	if c.config.Dir != "" {
+		if err := os.Remove(c.pagePath(page.ContentHash)); err != nil && !os.IsNotExist(err) {
+			log.Printf("Failed to remove cached page %s: %v", page.ContentHash, err)
+		}
+	}
+}
+
+// evictLocked drops least recently used pages until the cache fits in MaxBytes
+func (c *PageCache) evictLocked() {
+	for c.size > c.config.MaxBytes && c.lru.Len() > 1 {
+		oldest := c.lru.Back()
+		log.Printf("Evicting cached page %s", oldest.Value.(*CachedPage).ContentHash)
+		c.removeLocked(oldest)
+	}
+}
+
+func (c *PageCache) pagePath(hash string) string {
+	return filepath.Join(c.config.Dir, hash+".json")
+}
+
+// persist writes page to disk when persistence is enabled
+func (c *PageCache) persist(page *CachedPage) {
+	if c.config.Dir == "" {
+		return
+	}
+
+	data, err := json.Marshal(page)
+	if err != nil {
+		log.Printf("Failed to encode cached page %s: %v", page.ContentHash, err)
+		return
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated entry
+	tmp := c.pagePath(page.ContentHash) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		log.Printf("Failed to write cached page %s: %v", page.ContentHash, err)
+		return
+	}
+	if err := os.Rename(tmp, c.pagePath(page.ContentHash)); err != nil {
+		log.Printf("Failed to persist cached page %s: %v", page.ContentHash, err)
+	}
+}
+
+// load restores unexpired pages from disk
+func (c *PageCache) load() {
+	files, err := filepath.Glob(filepath.Join(c.config.Dir, "*.json"))
+	if err != nil {
+		log.Printf("Failed to list page cache directory %s: %v", c.config.Dir, err)
+		return
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Printf("Failed to read cached page %s: %v", file, err)
+			continue
+		}
+
+		var page CachedPage
+		if err := json.Unmarshal(data, &page); err != nil || page.ContentHash == "" {
+			log.Printf("Discarding unreadable cached page %s", file)
+			os.Remove(file)
+			continue
+		}
+		if time.Since(page.FetchedAt) > c.config.TTL {
+			os.Remove(file)
+			continue
+		}
+
+		c.insertLocked(&page)
+	}
+
+	c.evictLocked()
+	log.Printf("Loaded %d cached pages from %s", c.lru.Len(), c.config.Dir)
+}
+
+// copyRecipeForMix clones a cached recipe so each mix keeps its own ID, sharer and timestamps
+func copyRecipeForMix(source *models.Recipe, url string, sharerID string, sharerName string) *models.Recipe {
+	ingredients := make([]models.Ingredient, len(source.Ingredients))
+	copy(ingredients, source.Ingredients)
+
+	now := time.Now()
+	return &models.Recipe{
+		ID:          uuid.New().String(),
+		Name:        source.Name,
+		URL:         url,
+		Image:       source.Image,
+		Thumbnail:   source.Thumbnail,
+		ImageSource: source.ImageSource,
+		Ingredients: ingredients,
+		SharerID:    sharerID,
+		SharerName:  sharerName,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		Version:     1,
+	}
+}
@@ -0,0 +1,213 @@
+package recipe
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"kitchenmix/api/internal/models"
+)
+
+// trackingParams are query parameters that never change the page content
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "msclkid": true, "yclid": true,
+	"igshid": true, "mc_cid": true, "mc_eid": true, "_ga": true, "_gl": true,
+	"ref": true, "ref_src": true, "cmpid": true, "share": true,
+	"amp": true, "print": true,
+}
+
+// viewSuffixes are path suffixes used for AMP and print views of a page
+var viewSuffixes = []string{"/amp", "/print", "/printable", "/wprm_print"}
+
+var (
+	canonicalLinkRe = regexp.MustCompile(`(?is)<link\b[^>]*\brel=["']?canonical["']?[^>]*>`)
+	ogURLRe         = regexp.MustCompile(`(?is)<meta\b[^>]*\bproperty=["']og:url["'][^>]*>`)
+	hrefAttrRe      = regexp.MustCompile(`(?is)\bhref=["']([^"']+)["']`)
+	ogImageRe       = regexp.MustCompile(`(?is)<meta\b[^>]*\bproperty=["']og:image(?::url|:secure_url)?["'][^>]*>`)
+	contentAttrRe   = regexp.MustCompile(`(?is)\bcontent=["']([^"']+)["']`)
+)
+
+// CanonicalizeURL normalises a recipe URL so different views of the same page share a key.
+// It forces https, lowercases the host, drops "www."/"m."/"amp." prefixes, default ports,
+// fragments, tracking parameters and AMP/print path suffixes, and sorts the query.
+func CanonicalizeURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("unsupported URL scheme %q", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("URL has no host")
+	}
+
+	host := strings.ToLower(parsed.Hostname())
+	for _, prefix := range []string{"www.", "m.", "amp."} {
+		host = strings.TrimPrefix(host, prefix)
+	}
+	if port := parsed.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	path := strings.TrimSuffix(parsed.EscapedPath(), "/")
+	for _, suffix := range viewSuffixes {
+		if strings.HasSuffix(path, suffix) {
+			path = strings.TrimSuffix(path, suffix)
+			break
+		}
+	}
+	path = strings.TrimSuffix(path, "/")
+
+	query := parsed.Query()
+	for key := range query {
+		lower := strings.ToLower(key)
+		if strings.HasPrefix(lower, "utm_") || trackingParams[lower] {
+			query.Del(key)
+		}
+	}
+
+	canonical := "https://" + host + path
+	if len(query) > 0 {
+		// url.Values.Encode sorts by key
+		canonical += "?" + query.Encode()
+	}
+	return canonical, nil
+}
+
+// canonicalKey returns the canonical form of rawURL, or rawURL itself if it can't be parsed
+func canonicalKey(rawURL string) string {
+	if canonical, err := CanonicalizeURL(rawURL); err == nil {
+		return canonical
+	}
+	return rawURL
+}
+
+// extractDeclaredURL finds the page's own <link rel="canonical"> or og:url,
+// resolved against pageURL. Returns "" if neither is present.
+func extractDeclaredURL(htmlContent string, pageURL string) string {
+	var declared string
+	if tag := canonicalLinkRe.FindString(htmlContent); tag != "" {
+		if m := hrefAttrRe.FindStringSubmatch(tag); m != nil {
+			declared = m[1]
+		}
+	}
+	if declared == "" {
+		if tag := ogURLRe.FindString(htmlContent); tag != "" {
+			if m := contentAttrRe.FindStringSubmatch(tag); m != nil {
+				declared = m[1]
+			}
+		}
+	}
+	if declared == "" {
+		return ""
+	}
+
+	return resolveURL(pageURL, declared)
+}
+
+// resolveURL resolves ref against base, returning "" unless the result is an http(s) URL
+func resolveURL(base string, ref string) string {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ""
+	}
+	refURL, err := url.Parse(strings.TrimSpace(html.UnescapeString(ref)))
+	if err != nil {
+		return ""
+	}
+	resolved := baseURL.ResolveReference(refURL)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
+
+const (
+	duplicateNameThreshold       = 0.6
+	duplicateIngredientThreshold = 0.7
+	duplicateStrongIngredient    = 0.9
+	// duplicateWeakName is the name similarity still needed when the
+	// ingredient lists are nearly identical, so that dishes sharing a base
+	// such as "Chocolate cake" and "Lemon cake" stay apart
+	duplicateWeakName = 0.5
+)
+
+// findDuplicateRecipe returns a recipe in candidates that looks like the same dish as recipe.
+// Recipes match when both their names and ingredient lists are similar, or when the
+// ingredient lists are nearly identical and the names still share at least half their words.
+func findDuplicateRecipe(recipe *models.Recipe, candidates []*models.Recipe) *models.Recipe {
+	nameTokens := tokenSet(recipe.Name)
+	ingredientTokens := ingredientSet(recipe.Ingredients)
+
+	var best *models.Recipe
+	bestScore := 0.0
+	for _, candidate := range candidates {
+		if candidate.ID == recipe.ID {
+			continue
+		}
+
+		nameSim := jaccard(nameTokens, tokenSet(candidate.Name))
+		ingredientSim := jaccard(ingredientTokens, ingredientSet(candidate.Ingredients))
+
+		isDuplicate := (nameSim >= duplicateNameThreshold && ingredientSim >= duplicateIngredientThreshold) ||
+			(len(ingredientTokens) >= 3 && ingredientSim >= duplicateStrongIngredient && nameSim >= duplicateWeakName)
+		if !isDuplicate {
+			continue
+		}
+
+		if score := nameSim + ingredientSim; score > bestScore {
+			best = candidate
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// ingredientSet returns the normalised ingredient names of a recipe
+func ingredientSet(ingredients []models.Ingredient) map[string]bool {
+	set := make(map[string]bool, len(ingredients))
+	for _, ing := range ingredients {
+		if name := strings.Join(sortedTokens(ing.Name), " "); name != "" {
+			set[name] = true
+		}
+	}
+	return set
+}
+
+// tokenSet splits text into lowercase alphanumeric words
+func tokenSet(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, token := range sortedTokens(text) {
+		set[token] = true
+	}
+	return set
+}
+
+func sortedTokens(text string) []string {
+	tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	sort.Strings(tokens)
+	return tokens
+}
+
+// jaccard returns |a ∩ b| / |a ∪ b|
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+
+	intersection := 0
+	for token := range a {
+		if b[token] {
+			intersection++
+		}
+	}
+	union := len(a) + len(b) - intersection
+	return float64(intersection) / float64(union)
+}
@@ -0,0 +1,353 @@
+package recipe
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	defaultUserAgent    = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/141.0.0.0 Safari/537.36 KitchenMix/1.0"
+	defaultMaxBodyBytes = 5 * 1024 * 1024
+	defaultMaxRetries   = 3
+	defaultMaxCached    = 32 * 1024 * 1024
+	maxValidatorEntries = 512
+)
+
+// ErrBodyTooLarge is returned when a response exceeds FetcherConfig.MaxBodyBytes
+var ErrBodyTooLarge = errors.New("response body exceeds maximum size")
+
+// FetcherConfig controls how the HTTP fetcher talks to recipe sites
+type FetcherConfig struct {
+	UserAgent    string
+	Timeout      time.Duration
+	MaxBodyBytes int64
+	// MaxCachedBytes bounds the bodies kept to answer 304 Not Modified with,
+	// across every URL
+	MaxCachedBytes int64
+	MaxRetries     int
+	BaseBackoff    time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultFetcherConfig returns the settings used when nothing is configured
+func DefaultFetcherConfig() FetcherConfig {
+	return FetcherConfig{
+		UserAgent:      defaultUserAgent,
+		Timeout:        30 * time.Second,
+		MaxBodyBytes:   defaultMaxBodyBytes,
+		MaxCachedBytes: defaultMaxCached,
+		MaxRetries:     defaultMaxRetries,
+		BaseBackoff:    500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+	}
+}
+
+// FetcherConfigFromEnv overrides the defaults with FETCH_* environment variables
+func FetcherConfigFromEnv() FetcherConfig {
+	cfg := DefaultFetcherConfig()
+
+	if v := os.Getenv("FETCH_USER_AGENT"); v != "" {
+		cfg.UserAgent = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("FETCH_MAX_BODY_BYTES"), 10, 64); err == nil && v > 0 {
+		cfg.MaxBodyBytes = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("FETCH_MAX_CACHED_BYTES"), 10, 64); err == nil && v >= 0 {
+		cfg.MaxCachedBytes = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("FETCH_MAX_RETRIES")); err == nil && v >= 0 {
+		cfg.MaxRetries = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("FETCH_TIMEOUT")); err == nil && v > 0 {
+		cfg.Timeout = v
+	}
+
+	return cfg
+}
+
+// validators holds the conditional request headers and body of a previous response
+type validators struct {
+	etag         string
+	lastModified string
+	body         string
+}
+
+// Fetcher downloads pages over plain HTTP with size limits, decompression,
+// charset transcoding, retries and conditional requests
+type Fetcher struct {
+	config FetcherConfig
+	client *http.Client
+
+	mu         sync.Mutex
+	validators map[string]*validators
+	// cachedBytes is the size of the bodies in validators
+	cachedBytes int64
+}
+
+// NewFetcher creates a fetcher with the given configuration
+func NewFetcher(config FetcherConfig) *Fetcher {
+	return &Fetcher{
+		config: config,
+		client: &http.Client{
+			Timeout: config.Timeout,
+			// We advertise encodings ourselves, so the transport must not
+			// transparently decompress (it only understands gzip)
+			Transport: &http.Transport{
+				Proxy:              http.ProxyFromEnvironment,
+				DisableCompression: true,
+			},
+		},
+		validators: make(map[string]*validators),
+	}
+}
+
+// UserAgent returns the User-Agent header sent with every request
+func (f *Fetcher) UserAgent() string {
+	return f.config.UserAgent
+}
+
+// Fetch downloads targetURL and returns its body transcoded to UTF-8.
+// 429 and 5xx responses are retried with exponential backoff, honouring Retry-After.
+func (f *Fetcher) Fetch(ctx context.Context, targetURL string) (string, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= f.config.MaxRetries; attempt++ {
+		body, retryAfter, err := f.fetchOnce(ctx, targetURL)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+
+		var statusErr *HTTPStatusError
+		if errors.As(err, &statusErr) && !statusErr.Retryable() {
+			return "", err
+		}
+		if errors.Is(err, ErrBodyTooLarge) || ctx.Err() != nil {
+			return "", err
+		}
+		if attempt == f.config.MaxRetries {
+			break
+		}
+
+		wait := f.backoff(attempt, retryAfter)
+		log.Printf("Fetch of %s failed (attempt %d/%d): %v - retrying in %s", targetURL, attempt+1, f.config.MaxRetries+1, err, wait)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	return "", lastErr
+}
+
+// HTTPStatusError reports an unexpected response status
+type HTTPStatusError struct {
+	StatusCode int
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("HTTP request failed with status code: %d", e.StatusCode)
+}
+
+// Retryable reports whether the status is worth retrying
+func (e *HTTPStatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// fetchOnce performs a single request, returning any Retry-After delay the server asked for
+func (f *Fetcher) fetchOnce(ctx context.Context, targetURL string) (string, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.config.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+
+	cached := f.getValidators(targetURL)
+	if cached != nil {
+		if cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+		if cached.lastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.lastModified)
+		}
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		log.Printf("Fetch of %s not modified, using previous body", targetURL)
+		return cached.body, 0, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", parseRetryAfter(resp.Header.Get("Retry-After")), &HTTPStatusError{StatusCode: resp.StatusCode}
+	}
+
+	body, err := f.readBody(resp)
+	if err != nil {
+		return "", 0, err
+	}
+
+	f.storeValidators(targetURL, resp.Header, body)
+	return body, 0, nil
+}
+
+// readBody decompresses, size-limits and transcodes the response body to UTF-8
+func (f *Fetcher) readBody(resp *http.Response) (string, error) {
+	if resp.ContentLength > f.config.MaxBodyBytes {
+		return "", fmt.Errorf("%w: %d bytes", ErrBodyTooLarge, resp.ContentLength)
+	}
+
+	decoded, err := decompress(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	// The limit applies to the decompressed size so a small compressed
+	// payload can't expand without bound
+	raw, err := io.ReadAll(io.LimitReader(decoded, f.config.MaxBodyBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+	if int64(len(raw)) > f.config.MaxBodyBytes {
+		return "", fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, f.config.MaxBodyBytes)
+	}
+
+	utf8Reader, err := charset.NewReader(bytes.NewReader(raw), resp.Header.Get("Content-Type"))
+	if err != nil {
+		// Unknown charset - return the bytes untouched rather than failing
+		log.Printf("Unable to detect charset (%v), using raw body", err)
+		return string(raw), nil
+	}
+
+	body, err := io.ReadAll(utf8Reader)
+	if err != nil {
+		return "", fmt.Errorf("failed to transcode response body: %w", err)
+	}
+	return string(body), nil
+}
+
+// decompress wraps body according to the Content-Encoding header
+func decompress(encoding string, body io.Reader) (io.Reader, error) {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "", "identity":
+		return body, nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "br":
+		return brotli.NewReader(body), nil
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but some servers send raw DEFLATE
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		if zr, err := zlib.NewReader(bytes.NewReader(raw)); err == nil {
+			return zr, nil
+		}
+		return flate.NewReader(bytes.NewReader(raw)), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+}
+
+// backoff returns the delay before the next attempt
+func (f *Fetcher) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return min(retryAfter, f.config.MaxBackoff)
+	}
+
+	wait := f.config.BaseBackoff << attempt
+	if wait <= 0 || wait > f.config.MaxBackoff {
+		wait = f.config.MaxBackoff
+	}
+	// Up to 20% jitter so parallel requests don't retry in lockstep
+	if jitter := int64(wait) / 5; jitter > 0 {
+		wait += time.Duration(rand.Int64N(jitter))
+	}
+	return wait
+}
+
+// parseRetryAfter understands both the delay-seconds and HTTP-date forms
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(when); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+func (f *Fetcher) getValidators(targetURL string) *validators {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.validators[targetURL]
+}
+
+// storeValidators remembers a response for conditional requests, within
+// maxValidatorEntries URLs and MaxCachedBytes of bodies. Bodies too large for
+// the budget aren't kept at all, since a 304 could then not be answered.
+func (f *Fetcher) storeValidators(targetURL string, header http.Header, body string) {
+	etag := header.Get("ETag")
+	lastModified := header.Get("Last-Modified")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if previous, exists := f.validators[targetURL]; exists {
+		f.cachedBytes -= int64(len(previous.body))
+		delete(f.validators, targetURL)
+	}
+	size := int64(len(body))
+	if (etag == "" && lastModified == "") || size > f.config.MaxCachedBytes {
+		return
+	}
+
+	// Drop arbitrary entries until this one fits; they are only an optimisation
+	for key, entry := range f.validators {
+		if len(f.validators) < maxValidatorEntries && f.cachedBytes+size <= f.config.MaxCachedBytes {
+			break
+		}
+		f.cachedBytes -= int64(len(entry.body))
+		delete(f.validators, key)
+	}
+	f.cachedBytes += size
+	f.validators[targetURL] = &validators{
+		etag:         etag,
+		lastModified: lastModified,
+		body:         body,
+	}
+}
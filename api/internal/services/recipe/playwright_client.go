@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -43,33 +42,40 @@ func checkCDPAvailable(cdpURL string) bool {
 	return resp.StatusCode == 200
 }
 
-// simpleHTTPFetch fetches content using a basic HTTP client as a fallback
-func simpleHTTPFetch(url string) (string, error) {
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch URL: %w", err)
-	}
-	defer resp.Body.Close()
+// defaultFetcher is shared by all HTTP fallback fetches so conditional
+// request validators are reused between calls
+var defaultFetcher = NewFetcher(FetcherConfigFromEnv())
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("HTTP request failed with status code: %d", resp.StatusCode)
-	}
+// defaultPoliteness paces every page fetch, whether through Chrome or plain HTTP
+var defaultPoliteness = NewPoliteness(PolitenessConfigFromEnv(), defaultFetcher)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
-	}
+// simpleHTTPFetch fetches content using a basic HTTP client as a fallback
+func simpleHTTPFetch(url string) (string, error) {
+	return defaultFetcher.Fetch(context.Background(), url)
+}
 
-	return string(body), nil
+// fetchedPage is the reduced recipe content of a page plus the metadata we
+// need from the full HTML before it is discarded
+type fetchedPage struct {
+	content string
+	// declaredURL is the page's own <link rel="canonical"> or og:url
+	declaredURL string
+	// imageURLs are absolute og:image and JSON-LD image URLs, best first
+	imageURLs []string
 }
 
 // getPageHTML fetches the fully rendered HTML of targetURL using the
 // best available method (Chrome DevTools Protocol or simple HTTP).
 // It then extracts recipe-specific content to reduce size.
-func getPageHTML(targetURL string) (string, error) {
+func getPageHTML(targetURL string) (*fetchedPage, error) {
 	var html string
-	var err error
+
+	// Honour robots.txt and per-host rate limits before touching the site
+	release, err := defaultPoliteness.Acquire(context.Background(), targetURL)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 
 	// First, try to use Chrome DevTools Protocol (handles JavaScript rendering)
 	cdpURL := getCDPEndpoint()
@@ -82,12 +88,16 @@ func getPageHTML(targetURL string) (string, error) {
 	}
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	// Extract recipe content to reduce size
 	content, _ := extractRecipeContent(html)
-	return content, nil
+	return &fetchedPage{
+		content:     content,
+		declaredURL: extractDeclaredURL(html, targetURL),
+		imageURLs:   extractImageURLs(html, targetURL),
+	}, nil
 }
 
 // getPageHTMLWithChrome uses Chromium instance via CDP to render JavaScript
@@ -129,7 +139,11 @@ func getPageHTMLSimpleHTTP(targetURL string) (string, error) {
 
 // TestGetPageHTML is exported for testing purposes
 func TestGetPageHTML(url string) (string, error) {
-	return getPageHTML(url)
+	page, err := getPageHTML(url)
+	if err != nil {
+		return "", err
+	}
+	return page.content, nil
 }
 
 // extractJSONLD extracts JSON-LD recipe schema if present
@@ -158,6 +172,83 @@ func extractJSONLD(htmlContent string) string {
 	return ""
 }
 
+// extractImageURLs collects the page's declared recipe images, og:image first and
+// then any JSON-LD Recipe image, resolved to absolute URLs against pageURL
+func extractImageURLs(htmlContent string, pageURL string) []string {
+	var raw []string
+
+	if tag := ogImageRe.FindString(htmlContent); tag != "" {
+		if m := contentAttrRe.FindStringSubmatch(tag); m != nil {
+			raw = append(raw, m[1])
+		}
+	}
+
+	if jsonLD := extractJSONLD(htmlContent); jsonLD != "" {
+		raw = append(raw, jsonLDImages(jsonLD)...)
+	}
+
+	seen := make(map[string]bool)
+	var urls []string
+	for _, candidate := range raw {
+		if resolved := resolveURL(pageURL, candidate); resolved != "" && !seen[resolved] {
+			seen[resolved] = true
+			urls = append(urls, resolved)
+		}
+	}
+	return urls
+}
+
+// jsonLDImages returns the "image" values of the Recipe object in a JSON-LD block.
+// schema.org allows a URL string, an ImageObject, or an array of either.
+func jsonLDImages(jsonLD string) []string {
+	var data interface{}
+	if err := json.Unmarshal([]byte(jsonLD), &data); err != nil {
+		return nil
+	}
+
+	var recipes []map[string]interface{}
+	collect := func(items []interface{}) {
+		for _, item := range items {
+			if m, ok := item.(map[string]interface{}); ok && isRecipeType(m["@type"]) {
+				recipes = append(recipes, m)
+			}
+		}
+	}
+	switch v := data.(type) {
+	case []interface{}:
+		collect(v)
+	case map[string]interface{}:
+		if graph, ok := v["@graph"].([]interface{}); ok {
+			collect(graph)
+		} else {
+			collect([]interface{}{v})
+		}
+	}
+
+	var urls []string
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			urls = append(urls, v)
+		case map[string]interface{}:
+			if u, ok := v["url"].(string); ok {
+				urls = append(urls, u)
+			} else if u, ok := v["contentUrl"].(string); ok {
+				urls = append(urls, u)
+			}
+		case []interface{}:
+			for _, item := range v {
+				walk(item)
+			}
+		}
+	}
+	for _, recipe := range recipes {
+		walk(recipe["image"])
+	}
+	return urls
+}
+
 // validateJSONLD checks if JSON-LD contains actual ingredient data
 // Returns true if the JSON-LD has recipeIngredient field with data
 func validateJSONLD(jsonLD string) bool {
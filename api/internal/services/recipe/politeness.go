@@ -0,0 +1,428 @@
+package recipe
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBlockedByRobots is returned when a site's robots.txt disallows fetching a URL
+var ErrBlockedByRobots = errors.New("blocked by robots.txt")
+
+// PolitenessConfig controls how considerate the fetcher is towards each host
+type PolitenessConfig struct {
+	// RobotsAgent is the product token matched against robots.txt User-agent lines
+	RobotsAgent string
+	// MinDelay is the minimum time between two requests to the same host
+	MinDelay time.Duration
+	// MaxPerHost is the maximum number of concurrent requests to the same host
+	MaxPerHost int
+	// RobotsTTL is how long a fetched robots.txt is trusted
+	RobotsTTL time.Duration
+	// RobotsRetryTTL is how long a host is treated as disallowed after its
+	// robots.txt couldn't be fetched, before trying again
+	RobotsRetryTTL time.Duration
+	// MaxHosts is how many hosts' robots.txt and pacing are remembered. The
+	// least recently used idle host is forgotten to make room for another.
+	MaxHosts int
+}
+
+const (
+	// defaultRobotsRetryTTL is used when PolitenessConfig.RobotsRetryTTL is unset
+	defaultRobotsRetryTTL = 5 * time.Minute
+	// defaultMaxHosts is used when PolitenessConfig.MaxHosts is unset
+	defaultMaxHosts = 1000
+)
+
+// PolitenessConfigFromEnv reads FETCH_ROBOTS_AGENT, FETCH_HOST_DELAY and FETCH_HOST_CONCURRENCY
+func PolitenessConfigFromEnv() PolitenessConfig {
+	cfg := PolitenessConfig{
+		RobotsAgent:    "KitchenMix",
+		MinDelay:       time.Second,
+		MaxPerHost:     2,
+		RobotsTTL:      24 * time.Hour,
+		RobotsRetryTTL: defaultRobotsRetryTTL,
+		MaxHosts:       defaultMaxHosts,
+	}
+
+	if v := os.Getenv("FETCH_ROBOTS_AGENT"); v != "" {
+		cfg.RobotsAgent = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("FETCH_HOST_DELAY")); err == nil && v >= 0 {
+		cfg.MinDelay = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("FETCH_HOST_CONCURRENCY")); err == nil && v > 0 {
+		cfg.MaxPerHost = v
+	}
+
+	return cfg
+}
+
+// robotsRule is a single Allow or Disallow line
+type robotsRule struct {
+	allow   bool
+	pattern string
+	re      *regexp.Regexp
+}
+
+// robotsRules are the rules from the robots.txt group that applies to us
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	// disallowAll is set when robots.txt was unreachable, which RFC 9309
+	// treats as a complete disallow
+	disallowAll bool
+	expiresAt   time.Time
+}
+
+// hostState tracks robots.txt and request pacing for one host
+type hostState struct {
+	slots chan struct{}
+
+	mu     sync.Mutex
+	robots *robotsRules
+	// robotsLoaded is closed when an in-flight robots.txt fetch finishes, so
+	// concurrent requests wait for it rather than fetching it again
+	robotsLoaded chan struct{}
+	nextRequest  time.Time
+
+	// users counts the Acquire calls holding this state and lastUsed is when
+	// the last one finished, both guarded by Politeness.mu
+	users    int
+	lastUsed time.Time
+}
+
+// Politeness enforces robots.txt, per-host concurrency and a minimum delay between requests
+type Politeness struct {
+	config  PolitenessConfig
+	fetcher *Fetcher
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// NewPoliteness creates a politeness layer that downloads robots.txt with fetcher
+func NewPoliteness(config PolitenessConfig, fetcher *Fetcher) *Politeness {
+	if config.MaxPerHost <= 0 {
+		config.MaxPerHost = 1
+	}
+	if config.RobotsRetryTTL <= 0 {
+		config.RobotsRetryTTL = min(defaultRobotsRetryTTL, config.RobotsTTL)
+	}
+	if config.MaxHosts <= 0 {
+		config.MaxHosts = defaultMaxHosts
+	}
+	return &Politeness{
+		config:  config,
+		fetcher: fetcher,
+		hosts:   make(map[string]*hostState),
+	}
+}
+
+// Acquire blocks until targetURL may be fetched. The returned release function
+// must be called once the request has finished. It fails with ErrBlockedByRobots
+// when robots.txt disallows the URL.
+func (p *Politeness) Acquire(ctx context.Context, targetURL string) (release func(), err error) {
+	parsed, err := url.Parse(targetURL)
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", targetURL)
+	}
+
+	host := p.host(parsed.Scheme + "://" + parsed.Host)
+
+	rules, err := p.robotsFor(ctx, host, parsed)
+	if err != nil {
+		p.done(host)
+		return nil, err
+	}
+	if !rules.allowed(parsed.EscapedPath(), parsed.RawQuery) {
+		p.done(host)
+		return nil, fmt.Errorf("%w: %s", ErrBlockedByRobots, targetURL)
+	}
+
+	select {
+	case host.slots <- struct{}{}:
+	case <-ctx.Done():
+		p.done(host)
+		return nil, ctx.Err()
+	}
+
+	// Reserve the next start time for this host, then wait for it
+	delay := max(p.config.MinDelay, rules.crawlDelay)
+	host.mu.Lock()
+	start := time.Now()
+	if host.nextRequest.After(start) {
+		start = host.nextRequest
+	}
+	host.nextRequest = start.Add(delay)
+	host.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			<-host.slots
+			p.done(host)
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() {
+		<-host.slots
+		p.done(host)
+	}, nil
+}
+
+// host returns the state for origin, counting the caller as a user until it
+// calls done
+func (p *Politeness) host(origin string) *hostState {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state, exists := p.hosts[origin]
+	if !exists {
+		if len(p.hosts) >= p.config.MaxHosts {
+			p.evictIdleHost()
+		}
+		state = &hostState{slots: make(chan struct{}, p.config.MaxPerHost)}
+		p.hosts[origin] = state
+	}
+	state.users++
+	return state
+}
+
+// done records that a caller of host has finished with its state
+func (p *Politeness) done(state *hostState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state.users--
+	state.lastUsed = time.Now()
+}
+
+// evictIdleHost forgets the least recently used host that no one is using and
+// whose next request slot has passed, so its pacing can't be undercut. If
+// every host is busy none is forgotten. The caller holds p.mu.
+func (p *Politeness) evictIdleHost() {
+	now := time.Now()
+	var oldest string
+	var oldestUsed time.Time
+	for origin, state := range p.hosts {
+		if state.users > 0 || (oldest != "" && !state.lastUsed.Before(oldestUsed)) {
+			continue
+		}
+		state.mu.Lock()
+		paced := state.nextRequest.After(now)
+		state.mu.Unlock()
+		if !paced {
+			oldest, oldestUsed = origin, state.lastUsed
+		}
+	}
+	if oldest != "" {
+		delete(p.hosts, oldest)
+	}
+}
+
+// robotsFor returns the cached robots.txt rules for a host, fetching them if
+// stale. The fetch runs without holding the host lock; concurrent callers wait
+// for it to finish.
+func (p *Politeness) robotsFor(ctx context.Context, host *hostState, target *url.URL) (*robotsRules, error) {
+	for {
+		host.mu.Lock()
+		if host.robots != nil && time.Now().Before(host.robots.expiresAt) {
+			rules := host.robots
+			host.mu.Unlock()
+			return rules, nil
+		}
+		loaded := host.robotsLoaded
+		if loaded == nil {
+			host.robotsLoaded = make(chan struct{})
+			host.mu.Unlock()
+			break
+		}
+		host.mu.Unlock()
+
+		select {
+		case <-loaded:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	rules := p.fetchRobots(ctx, target)
+
+	host.mu.Lock()
+	defer host.mu.Unlock()
+	if rules != nil {
+		host.robots = rules
+	}
+	close(host.robotsLoaded)
+	host.robotsLoaded = nil
+
+	if rules == nil {
+		return nil, ctx.Err()
+	}
+	return rules, nil
+}
+
+// fetchRobots downloads and parses robots.txt for target's host. A missing
+// robots.txt allows everything; one that can't be fetched disallows everything
+// for RobotsRetryTTL. Returns nil if ctx ended first.
+func (p *Politeness) fetchRobots(ctx context.Context, target *url.URL) *robotsRules {
+	robotsURL := target.Scheme + "://" + target.Host + "/robots.txt"
+	body, err := p.fetcher.Fetch(ctx, robotsURL)
+	if err == nil {
+		rules := parseRobots(body, p.config.RobotsAgent)
+		rules.expiresAt = time.Now().Add(p.config.RobotsTTL)
+		log.Printf("Loaded robots.txt for %s: %d rules, crawl-delay %s", target.Host, len(rules.rules), rules.crawlDelay)
+		return rules
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) && !statusErr.Retryable() {
+		// No robots.txt means everything is allowed
+		log.Printf("No robots.txt for %s (status %d)", target.Host, statusErr.StatusCode)
+		return &robotsRules{expiresAt: time.Now().Add(p.config.RobotsTTL)}
+	}
+
+	log.Printf("Failed to fetch robots.txt for %s, disallowing for %s: %v", target.Host, p.config.RobotsRetryTTL, err)
+	return &robotsRules{disallowAll: true, expiresAt: time.Now().Add(p.config.RobotsRetryTTL)}
+}
+
+// parseRobots extracts the group matching agent's product token, compared
+// case-insensitively as RFC 9309 requires, falling back to the "*" group
+func parseRobots(body string, agent string) *robotsRules {
+	token, _, _ := strings.Cut(strings.TrimSpace(agent), "/")
+
+	type group struct {
+		agents     []string
+		rules      []robotsRule
+		crawlDelay time.Duration
+	}
+
+	var groups []*group
+	var current *group
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive User-agent lines share one group
+			if current == nil || !lastWasAgent {
+				current = &group{}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, value)
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if current != nil && value != "" {
+				current.rules = append(current.rules, newRobotsRule(key == "allow", value))
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		}
+		lastWasAgent = false
+	}
+
+	var matched, wildcard *group
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" {
+				if wildcard == nil {
+					wildcard = g
+				}
+			} else if token != "" && strings.EqualFold(a, token) {
+				if matched == nil {
+					matched = g
+				}
+			}
+		}
+	}
+
+	rules := &robotsRules{}
+	if matched == nil {
+		matched = wildcard
+	}
+	if matched != nil {
+		rules.rules = matched.rules
+		rules.crawlDelay = matched.crawlDelay
+	}
+	return rules
+}
+
+// newRobotsRule compiles a path pattern supporting "*" wildcards and a trailing "$" anchor
+func newRobotsRule(allow bool, pattern string) robotsRule {
+	rule := robotsRule{allow: allow, pattern: pattern}
+	if strings.ContainsAny(pattern, "*$") {
+		anchored := strings.HasSuffix(pattern, "$")
+		expr := regexp.QuoteMeta(strings.TrimSuffix(pattern, "$"))
+		expr = "^" + strings.ReplaceAll(expr, `\*`, ".*")
+		if anchored {
+			expr += "$"
+		}
+		rule.re, _ = regexp.Compile(expr)
+	}
+	return rule
+}
+
+func (r robotsRule) matches(path string) bool {
+	if r.re != nil {
+		return r.re.MatchString(path)
+	}
+	return strings.HasPrefix(path, r.pattern)
+}
+
+// allowed applies the longest matching rule, with Allow winning ties
+func (r *robotsRules) allowed(path string, rawQuery string) bool {
+	if r.disallowAll {
+		return false
+	}
+	if path == "" {
+		path = "/"
+	}
+	if rawQuery != "" {
+		path += "?" + rawQuery
+	}
+
+	allowed := true
+	longest := -1
+	for _, rule := range r.rules {
+		if !rule.matches(path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			longest = len(rule.pattern)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
@@ -3,47 +3,147 @@ package recipe
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/images"
+	"kitchenmix/api/internal/services/mixstore"
 	"log"
+	"maps"
+	"os"
+	"slices"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/ollama/ollama/api"
 )
 
+var (
+	// ErrRecipeNotFound is returned for recipe IDs that aren't in the mix
+	ErrRecipeNotFound = errors.New("recipe not found")
+	// ErrInvalidRecipeName is returned for blank or overlong recipe names
+	ErrInvalidRecipeName = fmt.Errorf("recipe name must be 1 to %d characters", MaxRecipeNameLength)
+	// ErrInvalidIngredient is returned for ingredients without a name or with
+	// an overlong one
+	ErrInvalidIngredient = fmt.Errorf("every ingredient needs a name of at most %d characters", MaxIngredientNameLength)
+	// ErrInvalidIngredients is returned for an empty or overlong ingredient list
+	ErrInvalidIngredients = fmt.Errorf("a recipe needs 1 to %d ingredients", MaxIngredients)
+	// ErrVersionConflict is returned when an edit is based on an outdated
+	// version of a recipe, i.e. someone else changed it first
+	ErrVersionConflict = errors.New("recipe has been changed by someone else")
+)
+
+// MaxRecipeNameLength is the longest recipe name accepted on edits, in characters
+const MaxRecipeNameLength = 200
+
+const (
+	// MaxIngredients is the most ingredients accepted on edits
+	MaxIngredients = 200
+	// MaxIngredientNameLength is the longest ingredient name accepted on edits, in characters
+	MaxIngredientNameLength = 200
+)
+
+// RecipeUpdate holds corrections to a recipe; nil fields are left unchanged
+// and Ingredients replaces the whole list
+type RecipeUpdate struct {
+	Name        *string
+	Ingredients []models.Ingredient
+}
+
+func (u RecipeUpdate) validate() error {
+	if u.Name != nil {
+		name := strings.TrimSpace(*u.Name)
+		if name == "" || utf8.RuneCountInString(name) > MaxRecipeNameLength {
+			return ErrInvalidRecipeName
+		}
+	}
+	// A nil list leaves the ingredients alone, but "ingredients": [] would
+	// wipe them
+	if u.Ingredients != nil && (len(u.Ingredients) == 0 || len(u.Ingredients) > MaxIngredients) {
+		return ErrInvalidIngredients
+	}
+	for _, ingredient := range u.Ingredients {
+		name := strings.TrimSpace(ingredient.Name)
+		if name == "" || utf8.RuneCountInString(name) > MaxIngredientNameLength {
+			return ErrInvalidIngredient
+		}
+	}
+	return nil
+}
+
 type RecipeService struct {
+	mu sync.Mutex
 	// Store for recipes indexed by mixId then URL
-	recipeStore map[string]map[string]*models.Recipe
+	recipeStore *mixstore.Store[map[string]*models.Recipe]
+	// Pages and extracted recipes shared by every mix
+	pageCache *PageCache
+	// Local storage for validated recipe images
+	images *images.ImageService
 }
 
+// NewRecipeService creates a recipe service keeping each mix's recipes under
+// RECIPE_DIR, which may be empty to keep them in memory only. They are loaded
+// from disk lazily.
 func NewRecipeService() *RecipeService {
+	dir := "tmp/recipes"
+	if v, ok := os.LookupEnv("RECIPE_DIR"); ok {
+		dir = v
+	}
+
 	service := &RecipeService{
-		recipeStore: make(map[string]map[string]*models.Recipe),
+		recipeStore: mixstore.New[map[string]*models.Recipe](dir, "recipes"),
+		pageCache:   NewPageCache(PageCacheConfigFromEnv()),
+		images:      newImageService(),
 	}
 
 	return service
 }
 
+// newImageService creates the image store, identifying as the same client as
+// the page fetcher and pacing requests with the same politeness
+func newImageService() *images.ImageService {
+	config := images.ConfigFromEnv()
+	config.UserAgent = defaultFetcher.UserAgent()
+	config.Limiter = defaultPoliteness
+	return images.NewImageService(config)
+}
+
+// Images returns the store the service keeps recipe images in
+func (s *RecipeService) Images() *images.ImageService {
+	return s.images
+}
+
 // GetRecipeByURL fetches a recipe from a given URL
 func (s *RecipeService) GetRecipeByURL(url string, mixId string, sharerID string, sharerName string, progressCallback func(string, string, string)) (*models.Recipe, error) {
+	// Tracking parameters, AMP/print views etc. all share one key
+	key := canonicalKey(url)
+
 	// Check if we have it in our store for this mix
-	if mixCache, exists := s.recipeStore[mixId]; exists {
-		if recipe, exists := mixCache[url]; exists {
-			if progressCallback != nil {
-				progressCallback("complete", "completed", "Recipe found in cache")
-			}
-			return recipe, nil
+	if recipe := s.getStoredRecipe(mixId, key); recipe != nil {
+		if progressCallback != nil {
+			progressCallback("complete", "completed", "Recipe found in cache")
 		}
+		return recipe, nil
+	}
+
+	// Another mix may already have extracted this page
+	if page, exists := s.pageCache.GetByURL(key); exists && page.Recipe != nil {
+		return s.addCachedRecipe(page.Recipe, mixId, key, url, sharerID, sharerName, progressCallback), nil
 	}
 
 	// If not found, try to dynamically extract from URL
-	recipe, err := s.extractRecipeFromURL(url, mixId, sharerID, sharerName, progressCallback)
+	recipe, err := s.extractRecipeFromURL(url, key, mixId, sharerID, sharerName, progressCallback)
 	if err != nil {
 		log.Printf("Failed to extract recipe from URL %s: %v", url, err)
 		if progressCallback != nil {
-			progressCallback("error", "failed", fmt.Sprintf("Recipe not found for URL %s: %v", url, err))
+			if errors.Is(err, ErrBlockedByRobots) {
+				progressCallback("error", "blocked", fmt.Sprintf("Blocked by robots.txt: %s does not allow this page to be fetched", url))
+			} else {
+				progressCallback("error", "failed", fmt.Sprintf("Recipe not found for URL %s: %v", url, err))
+			}
 		}
 		return nil, fmt.Errorf("recipe not found for URL %s: %w", url, err)
 	}
@@ -52,14 +152,14 @@ func (s *RecipeService) GetRecipeByURL(url string, mixId string, sharerID string
 }
 
 // extractRecipeFromURL dynamically extracts a recipe from a given URL using web scraping and AI
-func (s *RecipeService) extractRecipeFromURL(url string, mixId string, sharerID string, sharerName string, progressCallback func(string, string, string)) (*models.Recipe, error) {
+func (s *RecipeService) extractRecipeFromURL(url string, key string, mixId string, sharerID string, sharerName string, progressCallback func(string, string, string)) (*models.Recipe, error) {
 	// Send progress update that we're starting web content fetch
 	if progressCallback != nil {
 		progressCallback("fetching", "in_progress", fmt.Sprintf("Fetching recipe from %s", url))
 	}
 
 	// Fetch web content (already optimized with content extraction)
-	content, err := s.fetchWebContent(url)
+	page, err := s.fetchWebContent(url)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch web content: %w", err)
 	}
@@ -69,8 +169,26 @@ func (s *RecipeService) extractRecipeFromURL(url string, mixId string, sharerID
 		progressCallback("fetching", "completed", "Content retrieved successfully")
 	}
 
+	content := page.content
 	log.Printf("content: %+v", content)
 
+	// Prefer the URL the page declares for itself, both as the key and as the recipe link
+	requestedKey := key
+	recipeURL := url
+	if page.declaredURL != "" {
+		recipeURL = page.declaredURL
+		key = canonicalKey(page.declaredURL)
+		if key != requestedKey {
+			log.Printf("Page %s declares canonical URL %s", url, page.declaredURL)
+			if recipe := s.getStoredRecipe(mixId, key); recipe != nil {
+				if progressCallback != nil {
+					progressCallback("complete", "completed", "Recipe found in cache")
+				}
+				return recipe, nil
+			}
+		}
+	}
+
 	// // Try to parse as JSON-LD first (if content looks like JSON)
 	// if strings.HasPrefix(strings.TrimSpace(content), "{") || strings.HasPrefix(strings.TrimSpace(content), "[") {
 	// 	if recipe, err := s.parseJSONLDRecipe(content, url, sharerID, sharerName); err == nil {
@@ -88,13 +206,19 @@ func (s *RecipeService) extractRecipeFromURL(url string, mixId string, sharerID
 	// 	log.Printf("JSON-LD parsing failed, falling back to AI extraction")
 	// }
 
+	// The same content may have been extracted under a different URL
+	if page, exists := s.pageCache.GetByContent(content); exists && page.Recipe != nil {
+		s.cachePage(requestedKey, key, content, nil)
+		return s.addCachedRecipe(page.Recipe, mixId, key, recipeURL, sharerID, sharerName, progressCallback), nil
+	}
+
 	// Send progress update that we're starting AI extraction
 	if progressCallback != nil {
 		progressCallback("extracting", "in_progress", "Extracting ingredients with AI...")
 	}
 
 	// Extract recipe using AI
-	recipe, err := s.extractRecipe(content, url, sharerID, sharerName)
+	recipe, err := s.extractRecipe(content, recipeURL, sharerID, sharerName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract recipe: %w", err)
 	}
@@ -104,25 +228,130 @@ func (s *RecipeService) extractRecipeFromURL(url string, mixId string, sharerID
 		progressCallback("extracting", "completed", fmt.Sprintf("Received recipe with %d ingredients", len(recipe.Ingredients)))
 	}
 
+	// Replace the model's image guess with a validated, locally stored copy
+	s.attachImage(recipe, recipeURL, page.imageURLs, progressCallback)
+
+	// Cache the extracted recipe for every other mix
+	s.cachePage(requestedKey, key, content, recipe)
+
+	recipe, duplicate := s.addToMix(mixId, key, recipe)
+
 	// Send completion progress
 	if progressCallback != nil {
-		progressCallback("complete", "completed", "Recipe processed successfully")
+		if duplicate {
+			progressCallback("complete", "completed", fmt.Sprintf("Recipe already in mix as %q", recipe.Name))
+		} else {
+			progressCallback("complete", "completed", "Recipe processed successfully")
+		}
 	}
 
-	// Cache the extracted recipe
-	if s.recipeStore[mixId] == nil {
-		s.recipeStore[mixId] = make(map[string]*models.Recipe)
+	return recipe, nil
+}
+
+// cachePage stores content in the shared cache under its canonical key and,
+// if different, the key it was requested with
+func (s *RecipeService) cachePage(requestedKey string, key string, content string, recipe *models.Recipe) {
+	s.pageCache.Put(key, content, recipe)
+	if requestedKey != key {
+		s.pageCache.Put(requestedKey, content, nil)
 	}
-	s.recipeStore[mixId][url] = recipe
+}
 
-	return recipe, nil
+// addCachedRecipe copies a recipe from the shared cache into a mix
+func (s *RecipeService) addCachedRecipe(cached *models.Recipe, mixId string, key string, url string, sharerID string, sharerName string, progressCallback func(string, string, string)) *models.Recipe {
+	recipe, duplicate := s.addToMix(mixId, key, copyRecipeForMix(cached, url, sharerID, sharerName))
+	if progressCallback != nil {
+		if duplicate {
+			progressCallback("complete", "completed", fmt.Sprintf("Recipe already in mix as %q", recipe.Name))
+		} else {
+			progressCallback("complete", "completed", "Recipe found in shared cache")
+		}
+	}
+	return recipe
+}
+
+// getStoredRecipe returns the recipe stored under key in a mix, if any
+func (s *RecipeService) getStoredRecipe(mixId string, key string) *models.Recipe {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.mixRecipes(mixId)[key]
 }
 
-// fetchWebContent scrapes the given URL and returns the HTML content
-func (s *RecipeService) fetchWebContent(url string) (string, error) {
+// addToMix stores recipe under key unless the mix already holds the same dish,
+// in which case the existing recipe is returned and duplicate is true
+func (s *RecipeService) addToMix(mixId string, key string, recipe *models.Recipe) (stored *models.Recipe, duplicate bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mixCache := s.mixRecipes(mixId)
+	if existing, exists := mixCache[key]; exists {
+		return existing, true
+	}
+
+	candidates := make([]*models.Recipe, 0, len(mixCache))
+	for _, candidate := range mixCache {
+		candidates = append(candidates, candidate)
+	}
+	if existing := findDuplicateRecipe(recipe, candidates); existing != nil {
+		log.Printf("Recipe %q from %s duplicates %q in mix %s", recipe.Name, recipe.URL, existing.Name, mixId)
+		return existing, true
+	}
+
+	mixCache = maps.Clone(mixCache)
+	if mixCache == nil {
+		mixCache = make(map[string]*models.Recipe)
+	}
+	mixCache[key] = recipe
+	s.recipeStore.Put(mixId, mixCache)
+	return recipe, false
+}
+
+// fetchWebContent scrapes the given URL and returns the extracted recipe content
+// along with the page metadata we keep from the full HTML
+func (s *RecipeService) fetchWebContent(url string) (*fetchedPage, error) {
 	return getPageHTML(url)
 }
 
+// attachImage downloads the best image for recipe, trying the page's declared images
+// before the one the model picked, and points the recipe at the stored copy
+func (s *RecipeService) attachImage(recipe *models.Recipe, pageURL string, declared []string, progressCallback func(string, string, string)) {
+	candidates := slices.Clone(declared)
+	if recipe.Image != nil {
+		if resolved := resolveURL(pageURL, *recipe.Image); resolved != "" && !slices.Contains(candidates, resolved) {
+			candidates = append(candidates, resolved)
+		}
+	}
+
+	recipe.Image = nil
+	if len(candidates) == 0 {
+		return
+	}
+
+	if progressCallback != nil {
+		progressCallback("image", "in_progress", "Validating recipe image...")
+	}
+
+	stored, err := s.images.Store(context.Background(), pageURL, candidates)
+	if err != nil {
+		log.Printf("No usable image for recipe %q: %v", recipe.Name, err)
+		if progressCallback != nil {
+			progressCallback("image", "completed", "No usable recipe image found")
+		}
+		return
+	}
+
+	imageURL := stored.URL()
+	thumbnailURL := stored.ThumbnailURL()
+	recipe.Image = &imageURL
+	recipe.Thumbnail = &thumbnailURL
+	recipe.ImageSource = &stored.SourceURL
+
+	if progressCallback != nil {
+		progressCallback("image", "completed", "Recipe image saved")
+	}
+}
+
 // createExtractionPrompt creates the prompt for AI recipe extraction
 func (s *RecipeService) createExtractionPrompt(htmlContent string) string {
 	prompt := fmt.Sprintf(`
@@ -253,6 +482,7 @@ func (s *RecipeService) convertToRecipe(resp *models.OllamaRecipeResponse, url s
 		SharerName:  sharerName,
 		CreatedAt:   now,
 		UpdatedAt:   now,
+		Version:     1,
 	}
 }
 
@@ -265,8 +495,8 @@ func (s *RecipeService) parseJSONLDRecipe(jsonLD string, url string, sharerID st
 		for _, item := range dataArray {
 			if itemMap, ok := item.(map[string]interface{}); ok {
 				// Check if @type is "Recipe" or contains "Recipe" in array
-				if s.isRecipeType(itemMap["@type"]) {
-					return s.extractRecipeFromJSONLD(itemMap, url, sharerID, sharerName)
+				if isRecipeType(itemMap["@type"]) {
+					return recipeFromJSONLD(itemMap, url, sharerID, sharerName)
 				}
 			}
 		}
@@ -282,16 +512,16 @@ func (s *RecipeService) parseJSONLDRecipe(jsonLD string, url string, sharerID st
 	if graph, ok := dataObj["@graph"].([]interface{}); ok {
 		for _, item := range graph {
 			if itemMap, ok := item.(map[string]interface{}); ok {
-				if s.isRecipeType(itemMap["@type"]) {
-					return s.extractRecipeFromJSONLD(itemMap, url, sharerID, sharerName)
+				if isRecipeType(itemMap["@type"]) {
+					return recipeFromJSONLD(itemMap, url, sharerID, sharerName)
 				}
 			}
 		}
 	}
 
 	// Check if it's a direct Recipe object (Gordon Ramsay format)
-	if s.isRecipeType(dataObj["@type"]) {
-		return s.extractRecipeFromJSONLD(dataObj, url, sharerID, sharerName)
+	if isRecipeType(dataObj["@type"]) {
+		return recipeFromJSONLD(dataObj, url, sharerID, sharerName)
 	}
 
 	return nil, fmt.Errorf("no Recipe object found in JSON-LD")
@@ -299,7 +529,7 @@ func (s *RecipeService) parseJSONLDRecipe(jsonLD string, url string, sharerID st
 
 // isRecipeType checks if the @type field contains "Recipe"
 // Handles: "Recipe", ["Recipe"], ["Recipe", "NewsArticle"], etc.
-func (s *RecipeService) isRecipeType(typeField interface{}) bool {
+func isRecipeType(typeField interface{}) bool {
 	switch v := typeField.(type) {
 	case string:
 		return v == "Recipe"
@@ -313,8 +543,15 @@ func (s *RecipeService) isRecipeType(typeField interface{}) bool {
 	return false
 }
 
-// extractRecipeFromJSONLD extracts recipe data from a JSON-LD Recipe object
-func (s *RecipeService) extractRecipeFromJSONLD(recipeData map[string]interface{}, url string, sharerID string, sharerName string) (*models.Recipe, error) {
+// RecipeFromJSONLD maps a schema.org Recipe object, e.g. from an exported
+// file, onto a recipe linked to its "url" if it has one
+func RecipeFromJSONLD(recipeData map[string]interface{}) (*models.Recipe, error) {
+	url, _ := recipeData["url"].(string)
+	return recipeFromJSONLD(recipeData, url, "", "")
+}
+
+// recipeFromJSONLD extracts recipe data from a JSON-LD Recipe object
+func recipeFromJSONLD(recipeData map[string]interface{}, url string, sharerID string, sharerName string) (*models.Recipe, error) {
 	// Extract recipe name
 	name, _ := recipeData["name"].(string)
 	if name == "" {
@@ -327,7 +564,7 @@ func (s *RecipeService) extractRecipeFromJSONLD(recipeData map[string]interface{
 		for _, ingRaw := range ingredientsRaw {
 			if ingStr, ok := ingRaw.(string); ok {
 				// Parse ingredient string (e.g., "2 cups flour" or "350g sushi rice")
-				ingredient := s.parseIngredientString(ingStr)
+				ingredient := ParseIngredient(ingStr)
 				ingredients = append(ingredients, ingredient)
 			}
 		}
@@ -349,12 +586,13 @@ func (s *RecipeService) extractRecipeFromJSONLD(recipeData map[string]interface{
 		SharerName:  sharerName,
 		CreatedAt:   now,
 		UpdatedAt:   now,
+		Version:     1,
 	}, nil
 }
 
-// parseIngredientString parses an ingredient string into structured data
+// ParseIngredient parses an ingredient string into structured data
 // Examples: "2 cups flour", "350g sushi rice", "Fine sea salt"
-func (s *RecipeService) parseIngredientString(ingredientStr string) models.Ingredient {
+func ParseIngredient(ingredientStr string) models.Ingredient {
 	// This is a simple parser - could be enhanced with more sophisticated parsing
 	parts := strings.Fields(strings.TrimSpace(ingredientStr))
 
@@ -407,10 +645,10 @@ func hasNumberPrefix(s string) bool {
 	return (c >= '0' && c <= '9') || c == '.'
 }
 
-// isNumeric checks if a string is a number
+// isNumeric checks if a string is a number, range or fraction
 func isNumeric(s string) bool {
 	for _, c := range s {
-		if !((c >= '0' && c <= '9') || c == '.' || c == '-') {
+		if !((c >= '0' && c <= '9') || c == '.' || c == '-' || c == '/') {
 			return false
 		}
 	}
@@ -420,7 +658,7 @@ func isNumeric(s string) bool {
 // splitQuantityUnit splits "350g" into "350" and "g"
 func splitQuantityUnit(s string) (quantity, unit string) {
 	i := 0
-	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == '-') {
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == '-' || s[i] == '/') {
 		i++
 	}
 	if i > 0 && i < len(s) {
@@ -431,7 +669,10 @@ func splitQuantityUnit(s string) (quantity, unit string) {
 
 // GetMixRecipes returns all recipes for a given mixId
 func (s *RecipeService) GetMixRecipes(mixId string) []*models.Recipe {
-	mixCache := s.recipeStore[mixId]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mixCache := s.mixRecipes(mixId)
 	if mixCache == nil {
 		return nil
 	}
@@ -443,21 +684,142 @@ func (s *RecipeService) GetMixRecipes(mixId string) []*models.Recipe {
 	return recipes
 }
 
+// AddRecipe stores a recipe that didn't come from GetRecipeByURL, e.g. one
+// imported from a file, filling in its ID and timestamps if missing. Like
+// shared URLs, a recipe duplicating one already in the mix isn't added and the
+// existing recipe is returned instead.
+func (s *RecipeService) AddRecipe(mixId string, recipe *models.Recipe) (stored *models.Recipe, duplicate bool) {
+	if recipe.ID == "" {
+		recipe.ID = uuid.New().String()
+	}
+	if recipe.CreatedAt.IsZero() {
+		recipe.CreatedAt = time.Now()
+	}
+	if recipe.UpdatedAt.IsZero() {
+		recipe.UpdatedAt = recipe.CreatedAt
+	}
+	if recipe.Version == 0 {
+		recipe.Version = 1
+	}
+
+	key := "id:" + recipe.ID
+	if recipe.URL != "" {
+		key = canonicalKey(recipe.URL)
+	}
+	return s.addToMix(mixId, key, recipe)
+}
+
+// GetMixRecipe returns a recipe in a mix by ID
+func (s *RecipeService) GetMixRecipe(mixId string, recipeID string) (*models.Recipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, recipe := s.findInMix(mixId, recipeID)
+	if recipe == nil {
+		return nil, ErrRecipeNotFound
+	}
+	return recipe, nil
+}
+
+// RemoveRecipe forgets a recipe in a mix, provided it is still at version.
+// Sharing its URL again extracts it anew or copies it from the page cache. On
+// ErrVersionConflict the current recipe is returned so the caller can resync.
+func (s *RecipeService) RemoveRecipe(mixId string, recipeID string, version int) (*models.Recipe, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, recipe := s.findInMix(mixId, recipeID)
+	if recipe == nil {
+		return nil, ErrRecipeNotFound
+	}
+	if recipe.Version != version {
+		return recipe, ErrVersionConflict
+	}
+	mixCache := maps.Clone(s.mixRecipes(mixId))
+	delete(mixCache, key)
+	s.recipeStore.Put(mixId, mixCache)
+	return recipe, nil
+}
+
+// UpdateRecipe applies corrections to a recipe in a mix, provided it is still
+// at version, and bumps its version and UpdatedAt. On ErrVersionConflict the
+// current recipe is returned so the caller can resync. Stored recipes are never
+// modified in place since they may be being encoded for a broadcast; the
+// updated copy replaces them instead.
+func (s *RecipeService) UpdateRecipe(mixId string, recipeID string, version int, update RecipeUpdate) (*models.Recipe, error) {
+	if err := update.validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, recipe := s.findInMix(mixId, recipeID)
+	if recipe == nil {
+		return nil, ErrRecipeNotFound
+	}
+	if recipe.Version != version {
+		return recipe, ErrVersionConflict
+	}
+
+	updated := *recipe
+	if update.Name != nil {
+		updated.Name = strings.TrimSpace(*update.Name)
+	}
+	if update.Ingredients != nil {
+		updated.Ingredients = slices.Clone(update.Ingredients)
+		for i := range updated.Ingredients {
+			updated.Ingredients[i].Name = strings.TrimSpace(updated.Ingredients[i].Name)
+		}
+	}
+	updated.UpdatedAt = time.Now()
+	updated.Version++
+
+	mixCache := maps.Clone(s.mixRecipes(mixId))
+	mixCache[key] = &updated
+	s.recipeStore.Put(mixId, mixCache)
+	return &updated, nil
+}
+
+// findInMix returns a recipe in a mix by ID along with its key. Callers hold s.mu.
+func (s *RecipeService) findInMix(mixId string, recipeID string) (string, *models.Recipe) {
+	for key, recipe := range s.mixRecipes(mixId) {
+		if recipe.ID == recipeID {
+			return key, recipe
+		}
+	}
+	return "", nil
+}
+
 // ClearMix removes all recipes for a given mixId
 func (s *RecipeService) ClearMix(mixId string) {
-	delete(s.recipeStore, mixId)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recipeStore.Delete(mixId)
 }
 
 // GetMixRecipeCount returns the number of recipes for a given mixId
 func (s *RecipeService) GetMixRecipeCount(mixId string) int {
-	if mixCache := s.recipeStore[mixId]; mixCache != nil {
-		return len(mixCache)
-	}
-	return 0
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.mixRecipes(mixId))
 }
 
 // HasMix checks if a mixId exists in the cache
 func (s *RecipeService) HasMix(mixId string) bool {
-	_, exists := s.recipeStore[mixId]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, exists := s.recipeStore.Get(mixId)
 	return exists
 }
+
+// mixRecipes returns the recipes of a mix by key, reading them from disk on
+// first use. The map is replaced rather than modified when recipes change, so
+// it may be read after s.mu is released. Callers hold s.mu.
+func (s *RecipeService) mixRecipes(mixId string) map[string]*models.Recipe {
+	recipes, _ := s.recipeStore.Get(mixId)
+	return recipes
+}
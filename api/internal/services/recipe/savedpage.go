@@ -0,0 +1,382 @@
+package recipe
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"path"
+	"regexp"
+	"strings"
+
+	"kitchenmix/api/internal/models"
+
+	"golang.org/x/net/html/charset"
+)
+
+var (
+	// ErrUnsupportedPageFile is returned for files that aren't HTML, MHTML or a zip of them
+	ErrUnsupportedPageFile = errors.New("file is not an HTML page, MHTML archive or zip of pages")
+	// ErrNoSavedPages is returned for archives without any pages in them
+	ErrNoSavedPages = errors.New("no HTML pages found in the file")
+	// ErrSavedPageTooLarge is returned for pages over MaxSavedPageBytes
+	ErrSavedPageTooLarge = fmt.Errorf("saved page is larger than %d bytes", MaxSavedPageBytes)
+	// ErrTooManySavedPages is returned for archives with more than MaxSavedPages pages
+	ErrTooManySavedPages = fmt.Errorf("archive has more than %d pages", MaxSavedPages)
+	// ErrImportTooLarge is returned once the files of one import hold more
+	// than MaxImportPages pages or MaxImportPageBytes of them
+	ErrImportTooLarge = fmt.Errorf("import has more than %d pages or %d bytes of pages", MaxImportPages, MaxImportPageBytes)
+)
+
+const (
+	// MaxSavedPageBytes is the largest single page accepted, after unzipping
+	MaxSavedPageBytes = 10 << 20
+	// MaxSavedPages is the most pages imported from one zip
+	MaxSavedPages = 50
+	// MaxImportPages is the most pages imported at once, across every file
+	MaxImportPages = 100
+	// MaxImportPageBytes bounds the size of all the pages imported at once,
+	// after unzipping
+	MaxImportPageBytes = 50 << 20
+)
+
+// PageBudget bounds the pages read from all the files of one import, so
+// several zips can't add up to more than a single upload should
+type PageBudget struct {
+	pages int
+	bytes int64
+}
+
+// NewPageBudget returns a budget of MaxImportPages pages and MaxImportPageBytes
+func NewPageBudget() *PageBudget {
+	return &PageBudget{pages: MaxImportPages, bytes: MaxImportPageBytes}
+}
+
+// take spends one page of size bytes
+func (b *PageBudget) take(size int) error {
+	if b.pages == 0 || int64(size) > b.bytes {
+		return ErrImportTooLarge
+	}
+	b.pages--
+	b.bytes -= int64(size)
+	return nil
+}
+
+// savedFromRe matches the comment browsers add to pages saved as "HTML only",
+// e.g. <!-- saved from url=(0042)https://example.com/recipe -->
+var savedFromRe = regexp.MustCompile(`<!--\s*saved from url=\(\d+\)(\S+?)\s*-->`)
+
+// SavedPage is a page saved from a browser, to be extracted like a fetched one
+type SavedPage struct {
+	// Name identifies the page in import summaries, e.g. its file name
+	Name string
+	HTML string
+	// URL is where the page was saved from, if the file records it
+	URL string
+}
+
+// ImportIssue describes an entry of a bulk import that wasn't added
+type ImportIssue struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// ImportResult summarises a bulk import into a mix. Skipped entries are
+// recipes already in the mix; failed entries couldn't be read or extracted.
+type ImportResult struct {
+	Added   []*models.Recipe `json:"added"`
+	Skipped []ImportIssue    `json:"skipped"`
+	Failed  []ImportIssue    `json:"failed"`
+}
+
+// Merge adds the outcome of another import to this one
+func (r *ImportResult) Merge(other ImportResult) {
+	r.Added = append(r.Added, other.Added...)
+	r.Skipped = append(r.Skipped, other.Skipped...)
+	r.Failed = append(r.Failed, other.Failed...)
+}
+
+// ReadSavedPages reads the pages in an uploaded file: a saved .html page, an
+// .mhtml/.mht archive, or a .zip of either. Files without a known extension
+// are recognised by their content.
+func ReadSavedPages(filename string, data []byte) ([]SavedPage, error) {
+	return NewPageBudget().Read(filename, data)
+}
+
+// Read reads the pages in an uploaded file like ReadSavedPages, spending the
+// budget on them. It returns ErrImportTooLarge once the budget runs out.
+func (b *PageBudget) Read(filename string, data []byte) ([]SavedPage, error) {
+	var (
+		page SavedPage
+		err  error
+	)
+	switch savedPageKind(filename, data) {
+	case "zip":
+		return b.readZippedPages(data)
+	case "mhtml":
+		page, err = readMHTML(filename, data)
+	case "html":
+		page, err = readHTML(filename, data)
+	default:
+		return nil, ErrUnsupportedPageFile
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := b.take(len(data)); err != nil {
+		return nil, err
+	}
+	return []SavedPage{page}, nil
+}
+
+// savedPageKind tells zips, MHTML archives and HTML pages apart by extension,
+// falling back to sniffing the start of the file
+func savedPageKind(filename string, data []byte) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".zip":
+		return "zip"
+	case ".mhtml", ".mht":
+		return "mhtml"
+	case ".html", ".htm":
+		return "html"
+	}
+
+	head := strings.ToLower(string(data[:min(len(data), 1024)]))
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return "zip"
+	case strings.Contains(head, "multipart/related"):
+		return "mhtml"
+	case strings.Contains(head, "<html"), strings.Contains(head, "<!doctype html"):
+		return "html"
+	}
+	return ""
+}
+
+// readHTML decodes a saved HTML page to UTF-8 using its <meta charset>
+func readHTML(name string, data []byte) (SavedPage, error) {
+	if len(data) > MaxSavedPageBytes {
+		return SavedPage{}, ErrSavedPageTooLarge
+	}
+	html := decodeHTML(data, "text/html")
+
+	page := SavedPage{Name: name, HTML: html}
+	if m := savedFromRe.FindStringSubmatch(html); m != nil {
+		page.URL = resolveURL("", m[1])
+	}
+	return page, nil
+}
+
+// decodeHTML transcodes HTML to UTF-8, returning it untouched if the charset is unknown
+func decodeHTML(data []byte, contentType string) string {
+	utf8Reader, err := charset.NewReader(bytes.NewReader(data), contentType)
+	if err != nil {
+		log.Printf("Unable to detect charset of saved page (%v), using raw bytes", err)
+		return string(data)
+	}
+	decoded, err := io.ReadAll(utf8Reader)
+	if err != nil {
+		return string(data)
+	}
+	return string(decoded)
+}
+
+// readMHTML returns the main HTML document of an MHTML archive, the format
+// browsers use for "Webpage, single file"
+func readMHTML(name string, data []byte) (SavedPage, error) {
+	if len(data) > MaxSavedPageBytes {
+		return SavedPage{}, ErrSavedPageTooLarge
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return SavedPage{}, fmt.Errorf("%w: %v", ErrUnsupportedPageFile, err)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return SavedPage{}, fmt.Errorf("%w: not a multipart MHTML archive", ErrUnsupportedPageFile)
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return SavedPage{}, ErrNoSavedPages
+		}
+		if err != nil {
+			return SavedPage{}, fmt.Errorf("%w: %v", ErrUnsupportedPageFile, err)
+		}
+
+		contentType := part.Header.Get("Content-Type")
+		if partType, _, _ := mime.ParseMediaType(contentType); partType != "text/html" {
+			continue
+		}
+
+		// Quoted-printable parts are decoded by the multipart reader itself
+		var body io.Reader = part
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			body = base64.NewDecoder(base64.StdEncoding, part)
+		}
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return SavedPage{}, fmt.Errorf("%w: %v", ErrUnsupportedPageFile, err)
+		}
+
+		pageURL := part.Header.Get("Content-Location")
+		if pageURL == "" {
+			pageURL = msg.Header.Get("Snapshot-Content-Location")
+		}
+		return SavedPage{
+			Name: name,
+			HTML: decodeHTML(raw, contentType),
+			URL:  resolveURL("", pageURL),
+		}, nil
+	}
+}
+
+// readZippedPages reads every HTML and MHTML page in a zip, skipping other
+// files such as the images folder browsers save next to a page
+func (b *PageBudget) readZippedPages(data []byte) ([]SavedPage, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedPageFile, err)
+	}
+
+	var pages []SavedPage
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		if kind := savedPageKind(file.Name, nil); kind != "html" && kind != "mhtml" {
+			continue
+		}
+		if len(pages) == MaxSavedPages {
+			return nil, ErrTooManySavedPages
+		}
+		if b.pages == 0 {
+			return nil, ErrImportTooLarge
+		}
+
+		// The limits apply to the uncompressed size so a small archive
+		// can't expand without bound
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedPageFile, err)
+		}
+		raw, err := io.ReadAll(io.LimitReader(rc, min(MaxSavedPageBytes, b.bytes)+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnsupportedPageFile, err)
+		}
+		if int64(len(raw)) > b.bytes {
+			return nil, ErrImportTooLarge
+		}
+
+		found, err := b.Read(file.Name, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		pages = append(pages, found...)
+	}
+
+	if len(pages) == 0 {
+		return nil, ErrNoSavedPages
+	}
+	return pages, nil
+}
+
+// ImportPages extracts a recipe from each saved page with the same tiers as
+// fetched pages and adds them to the mix. Pages that fail are reported in
+// the result rather than stopping the import.
+func (s *RecipeService) ImportPages(pages []SavedPage, mixId string, sharerID string, sharerName string, progressCallback func(string, string, string)) ImportResult {
+	result := ImportResult{Added: []*models.Recipe{}, Skipped: []ImportIssue{}, Failed: []ImportIssue{}}
+	for i, page := range pages {
+		if progressCallback != nil {
+			progressCallback("extracting", "in_progress", fmt.Sprintf("Extracting recipe %d of %d from %s", i+1, len(pages), page.Name))
+		}
+
+		recipe, duplicate, err := s.importPage(page, mixId, sharerID, sharerName, progressCallback)
+		switch {
+		case err != nil:
+			log.Printf("Failed to import saved page %s: %v", page.Name, err)
+			result.Failed = append(result.Failed, ImportIssue{Name: page.Name, Reason: err.Error()})
+		case duplicate:
+			result.Skipped = append(result.Skipped, ImportIssue{Name: page.Name, Reason: fmt.Sprintf("already in mix as %q", recipe.Name)})
+		default:
+			result.Added = append(result.Added, recipe)
+		}
+	}
+
+	if progressCallback != nil {
+		progressCallback("complete", "completed", fmt.Sprintf("Imported %d of %d pages", len(result.Added), len(pages)))
+	}
+	return result
+}
+
+// importPage extracts and stores the recipe in one saved page
+func (s *RecipeService) importPage(page SavedPage, mixId string, sharerID string, sharerName string, progressCallback func(string, string, string)) (*models.Recipe, bool, error) {
+	content, contentType := extractRecipeContent(page.HTML)
+
+	// Prefer the URL the page declares for itself, as for fetched pages
+	recipeURL := page.URL
+	if declared := extractDeclaredURL(page.HTML, page.URL); declared != "" {
+		recipeURL = declared
+	}
+	key := ""
+	if recipeURL != "" {
+		key = canonicalKey(recipeURL)
+		if recipe := s.getStoredRecipe(mixId, key); recipe != nil {
+			return recipe, true, nil
+		}
+	}
+
+	// The same content may already have been extracted from a live page
+	if cached, exists := s.pageCache.GetByContent(content); exists && cached.Recipe != nil {
+		recipe := copyRecipeForMix(cached.Recipe, recipeURL, sharerID, sharerName)
+		stored, duplicate := s.storeImported(mixId, key, recipe)
+		return stored, duplicate, nil
+	}
+
+	// Structured data needs no AI; anything else goes to the model like a fetched page
+	var recipe *models.Recipe
+	if contentType == "jsonld" {
+		parsed, err := s.parseJSONLDRecipe(content, recipeURL, sharerID, sharerName)
+		if err != nil {
+			log.Printf("JSON-LD in saved page %s could not be parsed, falling back to AI extraction: %v", page.Name, err)
+		} else {
+			recipe = parsed
+		}
+	}
+	if recipe == nil {
+		extracted, err := s.extractRecipe(content, recipeURL, sharerID, sharerName)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to extract recipe: %w", err)
+		}
+		recipe = extracted
+	}
+	if len(recipe.Ingredients) == 0 {
+		return nil, false, ErrNoIngredients
+	}
+
+	// The file may claim any URL, so what it holds is kept out of the shared
+	// cache where it would answer for that URL in every mix
+	s.attachImage(recipe, recipeURL, extractImageURLs(page.HTML, recipeURL), progressCallback)
+
+	stored, duplicate := s.storeImported(mixId, key, recipe)
+	return stored, duplicate, nil
+}
+
+// storeImported adds an imported recipe to a mix under its canonical URL, or
+// its ID if it has none
+func (s *RecipeService) storeImported(mixId string, key string, recipe *models.Recipe) (*models.Recipe, bool) {
+	if key == "" {
+		return s.AddRecipe(mixId, recipe)
+	}
+	return s.addToMix(mixId, key, recipe)
+}
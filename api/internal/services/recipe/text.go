@@ -0,0 +1,177 @@
+package recipe
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"kitchenmix/api/internal/models"
+)
+
+var (
+	// ErrInvalidRecipeText is returned for blank or overlong pasted text
+	ErrInvalidRecipeText = fmt.Errorf("recipe text must be 1 to %d bytes", MaxRecipeTextLength)
+	// ErrNoIngredients is returned when no ingredients could be read from
+	// pasted text or a saved page
+	ErrNoIngredients = errors.New("no ingredients found")
+)
+
+// MaxRecipeTextLength is the most pasted text accepted for one recipe, in bytes
+const MaxRecipeTextLength = 20000
+
+// untitledRecipeName names pasted recipes whose text doesn't start with a title
+const untitledRecipeName = "Untitled recipe"
+
+type textSection int
+
+const (
+	sectionNone textSection = iota
+	sectionIngredients
+	sectionOther
+)
+
+// otherSections are headings that end an ingredient list
+var otherSections = []string{
+	"instructions", "method", "directions", "steps", "preparation", "notes", "nutrition",
+}
+
+// GetRecipeFromText builds a recipe from free-form pasted text and adds it to
+// the mix without a URL. Ingredients are read with the same parser as JSON-LD
+// ingredient lists; with useAI the text is also sent to the AI extractor, and
+// the parsed ingredients are kept if that fails. A non-empty name overrides
+// the one found in the text.
+func (s *RecipeService) GetRecipeFromText(text string, name string, useAI bool, mixId string, sharerID string, sharerName string, progressCallback func(string, string, string)) (*models.Recipe, error) {
+	if strings.TrimSpace(text) == "" || len(text) > MaxRecipeTextLength {
+		return nil, ErrInvalidRecipeText
+	}
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > MaxRecipeNameLength {
+		return nil, ErrInvalidRecipeName
+	}
+
+	if progressCallback != nil {
+		progressCallback("extracting", "in_progress", "Reading ingredients from text...")
+	}
+	parsedName, ingredients := s.parseRecipeText(text)
+
+	if useAI {
+		if progressCallback != nil {
+			progressCallback("extracting", "in_progress", "Extracting ingredients with AI...")
+		}
+		extracted, err := s.extractRecipe(text, "", sharerID, sharerName)
+		switch {
+		case err != nil:
+			log.Printf("AI extraction of pasted text failed, keeping parsed ingredients: %v", err)
+		case len(extracted.Ingredients) == 0:
+			log.Printf("AI extraction of pasted text found no ingredients, keeping parsed ingredients")
+		default:
+			ingredients = extracted.Ingredients
+			if extracted.Name != "" {
+				parsedName = extracted.Name
+			}
+		}
+	}
+
+	if len(ingredients) == 0 {
+		if progressCallback != nil {
+			progressCallback("error", "failed", "No ingredients found in the text")
+		}
+		return nil, ErrNoIngredients
+	}
+	if progressCallback != nil {
+		progressCallback("extracting", "completed", fmt.Sprintf("Found %d ingredients", len(ingredients)))
+	}
+
+	if name == "" {
+		name = parsedName
+	}
+	if name == "" || utf8.RuneCountInString(name) > MaxRecipeNameLength {
+		name = untitledRecipeName
+	}
+
+	stored, duplicate := s.AddRecipe(mixId, &models.Recipe{
+		Name:        name,
+		Ingredients: ingredients,
+		SharerID:    sharerID,
+		SharerName:  sharerName,
+	})
+	if progressCallback != nil {
+		if duplicate {
+			progressCallback("complete", "completed", fmt.Sprintf("Recipe already in mix as %q", stored.Name))
+		} else {
+			progressCallback("complete", "completed", "Recipe processed successfully")
+		}
+	}
+	return stored, nil
+}
+
+// parseRecipeText reads a recipe name and ingredients from pasted text. Under
+// an "Ingredients" heading every line up to the next known heading is an
+// ingredient; without one, bulleted lines and lines starting with a quantity
+// are. The first other line before the ingredients is taken as the name.
+func (s *RecipeService) parseRecipeText(text string) (name string, ingredients []models.Ingredient) {
+	lines := strings.Split(text, "\n")
+	hasIngredientsHeading := slices.ContainsFunc(lines, func(line string) bool {
+		return textHeading(cleanTextLine(line)) == sectionIngredients
+	})
+
+	section := sectionNone
+	for _, line := range lines {
+		line = cleanTextLine(line)
+		if line == "" {
+			continue
+		}
+		if heading := textHeading(line); heading != sectionNone {
+			section = heading
+			continue
+		}
+		// Sub-headings such as "For the sauce:" don't end a section
+		if strings.HasSuffix(line, ":") {
+			continue
+		}
+
+		item, bulleted := trimBullet(line)
+		if item == "" {
+			continue
+		}
+		switch {
+		case section == sectionIngredients:
+			ingredients = append(ingredients, ParseIngredient(item))
+		case section == sectionNone && !hasIngredientsHeading && (bulleted || hasNumberPrefix(item)):
+			ingredients = append(ingredients, ParseIngredient(item))
+		case section == sectionNone && name == "" && len(ingredients) == 0:
+			name = item
+		}
+	}
+	return name, ingredients
+}
+
+// cleanTextLine trims whitespace and Markdown heading marks from a line
+func cleanTextLine(line string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+}
+
+// textHeading reports which section a line starts, if it is a heading
+func textHeading(line string) textSection {
+	heading := strings.ToLower(strings.TrimSuffix(line, ":"))
+	switch {
+	case strings.HasPrefix(heading, "ingredients") && len(heading) <= len("ingredients")+30:
+		return sectionIngredients
+	case slices.Contains(otherSections, heading):
+		return sectionOther
+	}
+	return sectionNone
+}
+
+// trimBullet strips a list bullet from the start of a line
+func trimBullet(line string) (item string, bulleted bool) {
+	for _, bullet := range []string{"-", "*", "•", "·", "–"} {
+		if strings.HasPrefix(line, bullet) {
+			return strings.TrimSpace(strings.TrimPrefix(line, bullet)), true
+		}
+	}
+	return line, false
+}
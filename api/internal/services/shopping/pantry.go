@@ -0,0 +1,91 @@
+package shopping
+
+import (
+	"strings"
+
+	"kitchenmix/api/internal/models"
+)
+
+// Subtract takes what is in the pantry off a shopping list. Items the pantry
+// covers entirely are returned apart, as stocked; items it covers some of
+// stay on the list marked PartlyStocked. Pantry items match list items by
+// name, as ingredients do in Build, or by grocery item, and their quantities
+// are converted between units of the same kind. Amounts that aren't numbers,
+// such as a pinch, are covered by any stock at all.
+func Subtract(items []Item, pantry []models.PantryItem) (toBuy []Item, stocked []Item) {
+	toBuy, stocked = []Item{}, []Item{}
+	for _, item := range items {
+		stock, ok := findStock(item, pantry)
+		if !ok {
+			toBuy = append(toBuy, item)
+			continue
+		}
+
+		item = item.subtract(stock)
+		if len(item.Amounts) == 0 {
+			stocked = append(stocked, item)
+		} else {
+			toBuy = append(toBuy, item)
+		}
+	}
+	return toBuy, stocked
+}
+
+// findStock returns the pantry item a list item is drawn from
+func findStock(item Item, pantry []models.PantryItem) (models.PantryItem, bool) {
+	name := NormalizeName(item.Name)
+	for _, stock := range pantry {
+		if NormalizeName(stock.Name) == name {
+			return stock, true
+		}
+		if item.groceryItem != nil && item.groceryItem.ID != "" && stock.GroceryItem != nil && stock.GroceryItem.ID == item.groceryItem.ID {
+			return stock, true
+		}
+	}
+	return models.PantryItem{}, false
+}
+
+// subtract draws an item's amounts from stock, in order, until it runs out
+func (i Item) subtract(stock models.PantryItem) Item {
+	if stock.Quantity == nil || strings.TrimSpace(*stock.Quantity) == "" {
+		i.Stocked, i.Amounts = i.Amounts, []Amount{}
+		return i
+	}
+	have, ok := ParseQuantity(*stock.Quantity)
+	if !ok || have <= 0 {
+		return i
+	}
+	unit := ""
+	if stock.Unit != nil {
+		unit = NormalizeUnit(*stock.Unit)
+	}
+
+	left := []Amount{}
+	for _, amount := range i.Amounts {
+		if have <= 0 {
+			left = append(left, amount)
+			continue
+		}
+		need, ok := ParseQuantity(amount.Quantity)
+		if !ok {
+			i.Stocked = append(i.Stocked, amount)
+			continue
+		}
+		available, ok := ConvertUnit(have, unit, amount.Unit)
+		if !ok || available <= 0 {
+			left = append(left, amount)
+			continue
+		}
+
+		used := min(need, available)
+		have -= have * used / available
+		i.Stocked = append(i.Stocked, Amount{Quantity: FormatQuantity(used), Unit: amount.Unit})
+		if remaining := FormatQuantity(need - used); remaining != "0" {
+			left = append(left, Amount{Quantity: remaining, Unit: amount.Unit})
+		}
+	}
+
+	i.Amounts = left
+	i.PartlyStocked = len(i.Stocked) > 0 && len(left) > 0
+	return i
+}
@@ -0,0 +1,159 @@
+package shopping
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// vulgarFractions are the Unicode fractions recipes are written with
+var vulgarFractions = map[rune]float64{
+	'¼': 0.25, '½': 0.5, '¾': 0.75,
+	'⅓': 1.0 / 3, '⅔': 2.0 / 3,
+	'⅛': 0.125, '⅜': 0.375, '⅝': 0.625, '⅞': 0.875,
+}
+
+// ParseQuantity reads a quantity as written in a recipe: "2", "1.5", "1/2",
+// "1 1/2", "1½" or a range such as "2-3", of which the larger is taken as when
+// recipes are extracted
+func ParseQuantity(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	if low, high, found := strings.Cut(s, "-"); found && low != "" {
+		if _, ok := ParseQuantity(low); !ok {
+			return 0, false
+		}
+		return ParseQuantity(high)
+	}
+
+	total := 0.0
+	for _, part := range strings.Fields(s) {
+		value, ok := parseQuantityPart(part)
+		if !ok {
+			return 0, false
+		}
+		total += value
+	}
+	return total, true
+}
+
+// parseQuantityPart reads one whole number, decimal or fraction, which may end
+// in a Unicode fraction
+func parseQuantityPart(part string) (float64, bool) {
+	total := 0.0
+	for fraction, value := range vulgarFractions {
+		if trimmed, found := strings.CutSuffix(part, string(fraction)); found {
+			total, part = value, trimmed
+			break
+		}
+	}
+	if part == "" {
+		return total, total > 0
+	}
+
+	if numerator, denominator, found := strings.Cut(part, "/"); found {
+		n, err1 := strconv.ParseFloat(numerator, 64)
+		d, err2 := strconv.ParseFloat(denominator, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0, false
+		}
+		return total + n/d, true
+	}
+
+	value, err := strconv.ParseFloat(part, 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, false
+	}
+	return total + value, true
+}
+
+// commonFractions are written as fractions rather than decimals
+var commonFractions = []struct {
+	value float64
+	text  string
+}{
+	{0.125, "1/8"}, {0.25, "1/4"}, {1.0 / 3, "1/3"}, {0.375, "3/8"}, {0.5, "1/2"},
+	{0.625, "5/8"}, {2.0 / 3, "2/3"}, {0.75, "3/4"}, {0.875, "7/8"},
+}
+
+// FormatQuantity writes a quantity the way a cook would: whole numbers as
+// they are, common fractions such as "1 1/2", and anything else as a decimal
+// with at most two places
+func FormatQuantity(value float64) string {
+	whole, frac := math.Modf(value)
+	if frac < 0.01 {
+		return strconv.FormatFloat(whole, 'f', -1, 64)
+	}
+	if frac > 0.99 {
+		return strconv.FormatFloat(whole+1, 'f', -1, 64)
+	}
+
+	for _, common := range commonFractions {
+		if math.Abs(frac-common.value) < 0.01 {
+			if whole == 0 {
+				return common.text
+			}
+			return strconv.FormatFloat(whole, 'f', -1, 64) + " " + common.text
+		}
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+// unitAliases maps the ways recipes write units onto one spelling each
+var unitAliases = map[string]string{
+	"c": "cup", "cup": "cup", "cups": "cup",
+	"tbsp": "tbsp", "tbs": "tbsp", "tbl": "tbsp", "tablespoon": "tbsp", "tablespoons": "tbsp",
+	"tsp": "tsp", "teaspoon": "tsp", "teaspoons": "tsp",
+	"g": "g", "gr": "g", "gram": "g", "grams": "g", "gramme": "g", "grammes": "g",
+	"kg": "kg", "kilo": "kg", "kilos": "kg", "kilogram": "kg", "kilograms": "kg",
+	"mg": "mg", "milligram": "mg", "milligrams": "mg",
+	"ml": "ml", "millilitre": "ml", "millilitres": "ml", "milliliter": "ml", "milliliters": "ml",
+	"cl": "cl", "dl": "dl",
+	"l": "l", "litre": "l", "litres": "l", "liter": "l", "liters": "l",
+	"oz": "oz", "ounce": "oz", "ounces": "oz",
+	"fl oz": "fl oz", "floz": "fl oz",
+	"lb": "lb", "lbs": "lb", "pound": "lb", "pounds": "lb",
+	"pint": "pint", "pints": "pint", "pt": "pint",
+	"quart": "quart", "quarts": "quart", "qt": "quart",
+	"piece": "piece", "pieces": "piece", "pc": "piece", "pcs": "piece",
+	"clove": "clove", "cloves": "clove",
+	"can": "can", "cans": "can", "tin": "can", "tins": "can",
+	"pinch": "pinch", "pinches": "pinch",
+	"bunch": "bunch", "bunches": "bunch",
+	"slice": "slice", "slices": "slice",
+}
+
+// NormalizeUnit folds the spellings of a unit into one, e.g. "Tablespoons"
+// and "tbsp." into "tbsp". Unknown units are only lowercased.
+func NormalizeUnit(unit string) string {
+	folded := strings.TrimSuffix(strings.Join(strings.Fields(strings.ToLower(unit)), " "), ".")
+	if alias, ok := unitAliases[folded]; ok {
+		return alias
+	}
+	return folded
+}
+
+// ScaleQuantity multiplies a quantity as written in a recipe by factor, e.g.
+// "1 1/2" by 2 is "3" and the range "2-3" by 2 is "4-6". Quantities that
+// can't be read as numbers, such as "a pinch", are returned as they are.
+func ScaleQuantity(quantity string, factor float64) string {
+	quantity = strings.TrimSpace(quantity)
+	if factor == 1 {
+		return quantity
+	}
+	if low, high, found := strings.Cut(quantity, "-"); found && low != "" {
+		l, lok := ParseQuantity(low)
+		h, hok := ParseQuantity(high)
+		if !lok || !hok {
+			return quantity
+		}
+		return FormatQuantity(l*factor) + "-" + FormatQuantity(h*factor)
+	}
+	value, ok := ParseQuantity(quantity)
+	if !ok {
+		return quantity
+	}
+	return FormatQuantity(value * factor)
+}
@@ -0,0 +1,188 @@
+// Package shopping aggregates the ingredients of recipes into a shopping list,
+// summing quantities of the same ingredient in the same unit.
+package shopping
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"kitchenmix/api/internal/models"
+)
+
+// Amount is a quantity of an item in one unit. Quantities that couldn't be
+// read as numbers are kept as written and never summed.
+type Amount struct {
+	Quantity string `json:"quantity,omitempty"`
+	Unit     string `json:"unit,omitempty"`
+}
+
+// Item is one line of a shopping list: an ingredient, how much of it all the
+// recipes need together, and which recipes need it. Once the pantry is
+// subtracted, Amounts is what is left to buy and Stocked what the pantry
+// covers.
+type Item struct {
+	Name          string   `json:"name"`
+	Category      string   `json:"category,omitempty"`
+	Amounts       []Amount `json:"amounts"`
+	Recipes       []string `json:"recipes"`
+	Stocked       []Amount `json:"stocked,omitempty"`
+	PartlyStocked bool     `json:"partlyStocked,omitempty"`
+
+	// groceryItem is the catalogue entry of the first ingredient that had one
+	groceryItem *models.GroceryItem
+}
+
+// String renders an item as a line of a list, e.g. "flour: 2 cup, 100 g"
+func (i Item) String() string {
+	if len(i.Amounts) == 0 {
+		return i.Name
+	}
+	amounts := make([]string, len(i.Amounts))
+	for n, amount := range i.Amounts {
+		amounts[n] = strings.TrimSpace(amount.Quantity + " " + amount.Unit)
+	}
+	return i.Name + ": " + strings.Join(amounts, ", ")
+}
+
+// GroceryListItems is what to put on a grocery list to buy an item: one
+// entry for each amount, or just the name if it has no amounts
+func (i Item) GroceryListItems() []models.GroceryListItem {
+	if len(i.Amounts) == 0 {
+		return []models.GroceryListItem{{Name: i.Name, GroceryItem: i.groceryItem}}
+	}
+	items := make([]models.GroceryListItem, len(i.Amounts))
+	for n, amount := range i.Amounts {
+		items[n] = models.GroceryListItem{
+			Name:        i.Name,
+			GroceryItem: i.groceryItem,
+			Quantity:    optional(amount.Quantity),
+			Unit:        optional(amount.Unit),
+		}
+	}
+	return items
+}
+
+// optional is nil for an empty string
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// builder collects the amounts of one item while a list is built
+type builder struct {
+	item    Item
+	sums    map[string]float64
+	units   []string
+	written []Amount
+}
+
+// Build aggregates the ingredients of recipes into a shopping list, sorted by
+// category and then name. Ingredients are the same item when their names
+// match ignoring case and spacing; their quantities are summed per unit.
+func Build(recipes []*models.Recipe) []Item {
+	builders := make(map[string]*builder)
+	var order []string
+
+	for _, recipe := range recipes {
+		for _, ingredient := range recipe.Ingredients {
+			key := NormalizeName(ingredient.Name)
+			if key == "" {
+				continue
+			}
+
+			b, exists := builders[key]
+			if !exists {
+				b = &builder{
+					item: Item{Name: strings.TrimSpace(ingredient.Name)},
+					sums: make(map[string]float64),
+				}
+				builders[key] = b
+				order = append(order, key)
+			}
+			if ingredient.GroceryItem != nil {
+				if b.item.Category == "" {
+					b.item.Category = ingredient.GroceryItem.Category
+				}
+				if b.item.groceryItem == nil {
+					grocery := *ingredient.GroceryItem
+					b.item.groceryItem = &grocery
+				}
+			}
+			if !slices.Contains(b.item.Recipes, recipe.Name) {
+				b.item.Recipes = append(b.item.Recipes, recipe.Name)
+			}
+			b.add(ingredient)
+		}
+	}
+
+	items := make([]Item, 0, len(order))
+	for _, key := range order {
+		items = append(items, builders[key].build())
+	}
+	slices.SortStableFunc(items, func(a, b Item) int {
+		if c := cmp.Compare(a.Category, b.Category); c != 0 {
+			return c
+		}
+		return cmp.Compare(NormalizeName(a.Name), NormalizeName(b.Name))
+	})
+	return items
+}
+
+// add records one ingredient's quantity
+func (b *builder) add(ingredient models.Ingredient) {
+	unit := ""
+	if ingredient.Unit != nil {
+		unit = NormalizeUnit(*ingredient.Unit)
+	}
+	if ingredient.Quantity == nil || strings.TrimSpace(*ingredient.Quantity) == "" {
+		if unit != "" {
+			b.written = append(b.written, Amount{Unit: unit})
+		}
+		return
+	}
+
+	quantity, ok := ParseQuantity(*ingredient.Quantity)
+	if !ok {
+		b.written = append(b.written, Amount{Quantity: strings.TrimSpace(*ingredient.Quantity), Unit: unit})
+		return
+	}
+	if _, seen := b.sums[unit]; !seen {
+		b.units = append(b.units, unit)
+	}
+	b.sums[unit] += quantity
+}
+
+func (b *builder) build() Item {
+	item := b.item
+	item.Amounts = []Amount{}
+	for _, unit := range b.units {
+		item.Amounts = append(item.Amounts, Amount{Quantity: FormatQuantity(b.sums[unit]), Unit: unit})
+	}
+	for _, amount := range b.written {
+		if !slices.Contains(item.Amounts, amount) {
+			item.Amounts = append(item.Amounts, amount)
+		}
+	}
+	return item
+}
+
+// NormalizeName folds an ingredient name for comparison
+func NormalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// FormatIngredient writes an ingredient the way recipes list it, e.g.
+// "2 cups flour", which the ingredient parser reads back the same
+func FormatIngredient(ingredient models.Ingredient) string {
+	var parts []string
+	if ingredient.Quantity != nil && strings.TrimSpace(*ingredient.Quantity) != "" {
+		parts = append(parts, strings.TrimSpace(*ingredient.Quantity))
+	}
+	if ingredient.Unit != nil && strings.TrimSpace(*ingredient.Unit) != "" {
+		parts = append(parts, strings.TrimSpace(*ingredient.Unit))
+	}
+	return strings.Join(append(parts, strings.TrimSpace(ingredient.Name)), " ")
+}
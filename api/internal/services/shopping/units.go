@@ -0,0 +1,42 @@
+package shopping
+
+// unitSizes are the units that can be converted into each other, each sized
+// in the smallest unit of its kind: milligrams for weights and millilitres
+// for volumes. Cups, pints and quarts are US measures.
+var unitSizes = map[string]struct {
+	kind string
+	size float64
+}{
+	"mg": {"weight", 1},
+	"g":  {"weight", 1000},
+	"kg": {"weight", 1_000_000},
+	"oz": {"weight", 28_349.523125},
+	"lb": {"weight", 453_592.37},
+
+	"ml":    {"volume", 1},
+	"cl":    {"volume", 10},
+	"dl":    {"volume", 100},
+	"l":     {"volume", 1000},
+	"tsp":   {"volume", 4.92892159375},
+	"tbsp":  {"volume", 14.78676478125},
+	"fl oz": {"volume", 29.5735295625},
+	"cup":   {"volume", 236.5882365},
+	"pint":  {"volume", 473.176473},
+	"quart": {"volume", 946.352946},
+}
+
+// ConvertUnit converts a quantity from one unit to another, e.g. 1 kg to
+// 1000 g. Any unit converts to itself; otherwise both must be weights or both
+// volumes.
+func ConvertUnit(value float64, from string, to string) (float64, bool) {
+	from, to = NormalizeUnit(from), NormalizeUnit(to)
+	if from == to {
+		return value, true
+	}
+	f, fok := unitSizes[from]
+	t, tok := unitSizes[to]
+	if !fok || !tok || f.kind != t.kind {
+		return 0, false
+	}
+	return value * f.size / t.size, true
+}
@@ -2,17 +2,47 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/chat"
+	"kitchenmix/api/internal/services/grocerylist"
+	"kitchenmix/api/internal/services/identity"
+	"kitchenmix/api/internal/services/mix"
+	"kitchenmix/api/internal/services/pantry"
+	"kitchenmix/api/internal/services/plan"
 	"kitchenmix/api/internal/services/recipe"
 
 	"github.com/gorilla/websocket"
 )
 
-// Initialize recipe service
-var recipeService = recipe.NewRecipeService()
+// Recipes holds the recipes of every mix; the REST API reads it too
+var Recipes = recipe.NewRecipeService()
+
+// Mixes records which mixes exist; connections are only accepted for known mixes
+var Mixes = mix.NewMixService(mix.ConfigFromEnv())
+
+// Chat stores the chat history of every mix; the REST API reads it too
+var Chat = chat.NewChatService(chat.ConfigFromEnv())
+
+// Plans stores the meal plan of every mix; the REST API reads it too
+var Plans = plan.NewPlanService(plan.ConfigFromEnv())
+
+// GroceryLists stores the shared grocery list of every mix; the REST API reads it too
+var GroceryLists = grocerylist.NewGroceryListService(grocerylist.ConfigFromEnv())
+
+// Pantries stores what each mix has in stock; the REST API reads it too
+var Pantries = pantry.NewPantryService(pantry.ConfigFromEnv())
+
+// Identities signs the identity tokens minted by the REST API and presented on USER_IDENTIFY
+var Identities = identity.NewIssuer(identity.ConfigFromEnv())
 
 const (
 	writeWait      = 10 * time.Second
@@ -21,15 +51,46 @@ const (
 	maxMessageSize = 512 * 1024
 )
 
+// Application close codes, in the 4000-4999 range reserved for private use
+const (
+	// CloseIncompatibleProtocol is sent when the client's protocol version is unsupported
+	CloseIncompatibleProtocol = 4000
+	// CloseSlowConsumer is sent when a connection falls too far behind; the client
+	// should reconnect and resume from its last seen sequence
+	CloseSlowConsumer = 4001
+	// CloseMixDeleted is sent to everyone in a mix when it is deleted
+	CloseMixDeleted = 4002
+	// CloseMembershipRevoked is sent to members removed from a mix, e.g. when
+	// the invite they joined through is revoked
+	CloseMembershipRevoked = 4003
+)
+
+// maxDroppedMessages is how many messages a connection may miss before it is disconnected
+var maxDroppedMessages = maxDroppedFromEnv()
+
+// maxDroppedFromEnv reads WS_MAX_DROPPED_MESSAGES
+func maxDroppedFromEnv() int64 {
+	if v, err := strconv.ParseInt(os.Getenv("WS_MAX_DROPPED_MESSAGES"), 10, 64); err == nil && v > 0 {
+		return v
+	}
+	return 8
+}
+
 type Connection struct {
 	ID       string
 	UUID     string
 	UserID   string
 	UserName string
+	// Role is the user's role in the mix; guarded by the pool lock since the
+	// REST API can change it, so read it with Pool.Role
+	Role     string
 	Status   string
 	Conn     *websocket.Conn
 	Send     chan WSMessage
 	LastPing time.Time
+
+	dropped   atomic.Int64
+	closeOnce sync.Once
 }
 
 func NewConnection(id, uuid string, conn *websocket.Conn) *Connection {
@@ -45,6 +106,27 @@ func NewConnection(id, uuid string, conn *websocket.Conn) *Connection {
 	}
 }
 
+// Dropped returns how many messages were dropped because the Send buffer was full
+func (c *Connection) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// closeSlow disconnects a connection that can't keep up
+func (c *Connection) closeSlow() {
+	log.Printf("Disconnecting slow connection %s after %d dropped messages (uuid: %s)", c.ID, c.Dropped(), c.UUID)
+	c.close(CloseSlowConsumer, "too many dropped messages")
+}
+
+// close disconnects with an application close code. The close frame is written as a
+// control message so it isn't stuck behind a full Send buffer; ReadPump then unregisters.
+func (c *Connection) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		message := websocket.FormatCloseMessage(code, reason)
+		c.Conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+		c.Conn.Close()
+	})
+}
+
 func (c *Connection) ReadPump() {
 	defer func() {
 		Pool.Unregister(c.ID)
@@ -102,17 +184,58 @@ func (c *Connection) WritePump() {
 	}
 }
 
-func (c *Connection) processRecipeRequest(payload RecipeUrlRequestPayload) {
-	// Define progress callback that sends messages to requesting connection only
-	progressCallback := func(phase, status, message string) {
+// sendError reports a failure to this connection only, correlated with the request that caused it
+func (c *Connection) sendError(requestID string, code string, message string) {
+	errorMsg, err := NewReply(MessageTypeError, requestID, ErrorPayload{
+		Code:      code,
+		Message:   message,
+		RequestID: requestID,
+	})
+	if err != nil {
+		log.Printf("Failed to create ERROR message: %v", err)
+		return
+	}
+
+	Pool.BroadcastToUUIDOnlySender(c.UUID, c.ID, errorMsg)
+}
+
+func (c *Connection) processRecipeRequest(requestID string, payload RecipeUrlRequestPayload) {
+	// Get recipe using the recipe service with progress updates
+	recipe, err := Recipes.GetRecipeByURL(payload.URL, c.UUID, c.UserID, c.UserName, c.progressReporter(requestID, payload))
+	if err != nil {
+		log.Printf("Failed to get recipe for URL %s from connection %s: %v", payload.URL, c.ID, err)
+		c.sendError(requestID, ErrorCodeRecipeUnavailable, fmt.Sprintf("Could not get a recipe from %s", payload.URL))
+		return
+	}
+
+	broadcastRecipeAdditions(c.UUID, requestID, recipe)
+}
+
+// processRecipeText adds a recipe from pasted text, reporting progress the
+// same way as shared URLs
+func (c *Connection) processRecipeText(requestID string, payload RecipeTextRequestPayload) {
+	request := RecipeUrlRequestPayload{SharerID: c.UserID, SharerName: c.UserName}
+	_, err := AddRecipeFromText(c.UUID, payload.Text, payload.Name, payload.UseAI, c.UserID, c.UserName, requestID, c.progressReporter(requestID, request))
+	switch {
+	case errors.Is(err, recipe.ErrNoIngredients):
+		c.sendError(requestID, ErrorCodeNoIngredients, "No ingredients found in the text")
+	case err != nil:
+		c.sendError(requestID, ErrorCodeInvalidPayload, err.Error())
+	}
+}
+
+// progressReporter returns a progress callback that sends RECIPE_PROGRESS for
+// a request to this connection only
+func (c *Connection) progressReporter(requestID string, request RecipeUrlRequestPayload) func(string, string, string) {
+	return func(phase, status, message string) {
 		progressPayload := RecipeProgressPayload{
-			Request: payload,
+			Request: request,
 			Phase:   phase,
 			Status:  status,
 			Message: message,
 		}
 
-		progressMsg, err := NewMessage(MessageTypeRecipeProgress, progressPayload)
+		progressMsg, err := NewReply(MessageTypeRecipeProgress, requestID, progressPayload)
 		if err != nil {
 			log.Printf("Failed to create progress message: %v", err)
 			return
@@ -121,41 +244,129 @@ func (c *Connection) processRecipeRequest(payload RecipeUrlRequestPayload) {
 		// Send to requesting connection only
 		Pool.BroadcastToUUIDOnlySender(c.UUID, c.ID, progressMsg)
 	}
+}
 
-	// Get recipe using the recipe service with progress updates
-	recipe, err := recipeService.GetRecipeByURL(payload.URL, c.UUID, c.UserID, c.UserName, progressCallback)
+// processChatMessage stores a chat message and relays it to the mix. The sender gets
+// the stored message back so it can replace its optimistic copy.
+func (c *Connection) processChatMessage(requestID string, payload ChatMessagePayload) {
+	// The identified user is the sender, whatever the client claims
+	payload.Payload.Sender.ID = c.UserID
+	payload.Payload.Sender.Name = c.UserName
+
+	stored, err := Chat.Add(c.UUID, payload.Payload)
 	if err != nil {
-		log.Printf("Failed to get recipe for URL %s from connection %s: %v", payload.URL, c.ID, err)
-		// Create error response
-		responsePayload := RecipeAdditionsPayload{
-			Status: "ERROR_SERVICE_UNAVAILABLE",
-			List:   []*models.Recipe{},
+		c.sendError(requestID, ErrorCodeInvalidPayload, err.Error())
+		return
+	}
+	payload.Payload = stored
+
+	chatMsg, err := NewMessage(MessageTypeChatMessage, payload)
+	if err != nil {
+		log.Printf("Failed to create CHAT_MESSAGE message: %v", err)
+		return
+	}
+	Pool.BroadcastToUUIDExceptSender(c.UUID, c.ID, chatMsg)
+
+	chatMsg.RequestID = requestID
+	Pool.BroadcastToUUIDOnlySender(c.UUID, c.ID, chatMsg)
+}
+
+// chatHistory builds a page of the mix's chat history
+func (c *Connection) chatHistory(requestID string, before string, limit int) (WSMessage, error) {
+	messages, hasMore := Chat.History(c.UUID, before, limit)
+	return NewReply(MessageTypeChatHistory, requestID, ChatHistoryPayload{
+		Messages: messages,
+		HasMore:  hasMore,
+	})
+}
+
+// snapshot builds the messages that bring a client up to date from scratch
+func (c *Connection) snapshot(requestID string) []WSMessage {
+	var messages []WSMessage
+
+	existingRecipes := Recipes.GetMixRecipes(c.UUID)
+	if len(existingRecipes) > 0 {
+		recipePayload := RecipeAdditionsPayload{
+			Status: "success",
+			List:   existingRecipes,
 		}
+		recipeMsg, err := NewReply(MessageTypeRecipeAdditions, requestID, recipePayload)
+		if err != nil {
+			log.Printf("Failed to create recipe additions message: %v", err)
+		} else {
+			messages = append(messages, recipeMsg)
+			log.Printf("Sending %d existing recipes to user %s in mix %s", len(existingRecipes), c.UserName, c.UUID)
+		}
+	}
 
-		responseMsg, err := NewMessage(MessageTypeRecipeAdditions, responsePayload)
+	if entries := Plans.Entries(c.UUID, "", ""); len(entries) > 0 {
+		planMsg, err := NewReply(MessageTypePlanUpdates, requestID, PlanUpdatesPayload{List: entries})
 		if err != nil {
-			log.Printf("Failed to create RECIPE_ADDITIONS message: %v", err)
+			log.Printf("Failed to create PLAN_UPDATES message: %v", err)
 		} else {
-			Pool.BroadcastToUUID(c.UUID, responseMsg)
+			messages = append(messages, planMsg)
 		}
-		return
 	}
 
-	// Create successful response
-	responsePayload := RecipeAdditionsPayload{
-		Status: "success",
-		List:   []*models.Recipe{recipe},
+	if items := GroceryLists.Items(c.UUID); len(items) > 0 {
+		groceryMsg, err := NewReply(MessageTypeGroceryItemUpdates, requestID, GroceryItemUpdatesPayload{List: items})
+		if err != nil {
+			log.Printf("Failed to create GROCERY_ITEM_UPDATES message: %v", err)
+		} else {
+			messages = append(messages, groceryMsg)
+		}
 	}
 
-	responseMsg, err := NewMessage(MessageTypeRecipeAdditions, responsePayload)
+	if items := Pantries.Items(c.UUID); len(items) > 0 {
+		pantryMsg, err := NewReply(MessageTypePantryItemUpdates, requestID, PantryItemUpdatesPayload{List: items})
+		if err != nil {
+			log.Printf("Failed to create PANTRY_ITEM_UPDATES message: %v", err)
+		} else {
+			messages = append(messages, pantryMsg)
+		}
+	}
+
+	historyMsg, err := c.chatHistory(requestID, "", 0)
 	if err != nil {
-		log.Printf("Failed to create RECIPE_ADDITIONS message: %v", err)
-		return
+		log.Printf("Failed to create CHAT_HISTORY message: %v", err)
+	} else {
+		messages = append(messages, historyMsg)
 	}
 
-	// Broadcast to all connections in the same session (including sender)
-	Pool.BroadcastToUUID(c.UUID, responseMsg)
-	log.Printf("Broadcasted RECIPE_ADDITIONS to %s", c.UUID)
+	return messages
+}
+
+// rejectRecipeEdit reports why a RECIPE_UPDATE or RECIPE_REMOVE failed. When
+// the client's copy is stale it also gets the current state, so it can
+// reapply its edit on top of it.
+func (c *Connection) rejectRecipeEdit(requestID string, recipeID string, current *models.Recipe, err error) {
+	switch {
+	case errors.Is(err, recipe.ErrVersionConflict):
+		log.Printf("Rejected stale edit of recipe %s from %s (uuid: %s)", recipeID, c.UserName, c.UUID)
+		c.sendError(requestID, ErrorCodeVersionConflict, fmt.Sprintf("Recipe has changed; it is now at version %d", current.Version))
+		c.resyncRecipe(requestID, recipeID, current)
+	case errors.Is(err, recipe.ErrRecipeNotFound):
+		c.sendError(requestID, ErrorCodeRecipeNotFound, "Recipe is not in this mix")
+		c.resyncRecipe(requestID, recipeID, nil)
+	default:
+		c.sendError(requestID, ErrorCodeInvalidPayload, err.Error())
+	}
+}
+
+// authorize checks that the connection is identified and its user has at
+// least the required role, replying with an ERROR otherwise
+func (c *Connection) authorize(msg WSMessage, required string) bool {
+	if c.Status != "Active" {
+		log.Printf("Rejected %s from unidentified connection %s", msg.Type, c.ID)
+		c.sendError(msg.RequestID, ErrorCodeNotIdentified, fmt.Sprintf("Send USER_IDENTIFY before %s", msg.Type))
+		return false
+	}
+	if role := Pool.Role(c); !mix.RoleAllows(role, required) {
+		log.Printf("Rejected %s from %s with role %s (uuid: %s)", msg.Type, c.UserName, role, c.UUID)
+		c.sendError(msg.RequestID, ErrorCodeForbidden, fmt.Sprintf("%s requires the %s role", msg.Type, required))
+		return false
+	}
+	return true
 }
 
 func (c *Connection) handleMessage(msg WSMessage) {
@@ -167,28 +378,64 @@ func (c *Connection) handleMessage(msg WSMessage) {
 		var payload UserIdentifyPayload
 		if err := json.Unmarshal(msg.Data, &payload); err != nil {
 			log.Printf("Failed to parse USER_IDENTIFY payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid USER_IDENTIFY payload")
 			return
 		}
-		c.UserID = payload.UserID
-		c.UserName = payload.UserName
-		c.Status = "Active"
-		log.Printf("User identified: %s (ID: %s) on connection %s (uuid: %s)", c.UserName, c.UserID, c.ID, c.UUID)
 
-		// Send current recipes for this mix to the identifying client
-		existingRecipes := recipeService.GetMixRecipes(c.UUID)
-		if len(existingRecipes) > 0 {
-			recipePayload := RecipeAdditionsPayload{
-				Status: "success",
-				List:   existingRecipes,
-			}
-			recipeMsg, err := NewMessage(MessageTypeRecipeAdditions, recipePayload)
-			if err != nil {
-				log.Printf("Failed to create recipe additions message: %v", err)
-			} else {
-				// Send only to the identifying connection
-				Pool.BroadcastToUUIDOnlySender(c.UUID, c.ID, recipeMsg)
-				log.Printf("Sent %d existing recipes to user %s in mix %s", len(existingRecipes), c.UserName, c.UUID)
-			}
+		ident, err := Identities.Verify(payload.Token)
+		if err != nil {
+			log.Printf("Rejected USER_IDENTIFY from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidIdentity, "Identity token is invalid or expired")
+			return
+		}
+		if payload.UserID != "" && payload.UserID != ident.UserID {
+			log.Printf("Rejected spoofed USER_IDENTIFY from connection %s: %s is not %s", c.ID, payload.UserID, ident.UserID)
+			c.sendError(msg.RequestID, ErrorCodeInvalidIdentity, "User ID does not match the identity token")
+			return
+		}
+
+		// Newcomers need an invite; members can identify without one
+		var member models.MixMember
+		if payload.Invite != "" {
+			member, err = Mixes.Redeem(c.UUID, payload.Invite, ident.UserID, ident.UserName)
+		} else {
+			member, err = Mixes.Join(c.UUID, ident.UserID, ident.UserName)
+		}
+		switch {
+		case errors.Is(err, mix.ErrNotMember):
+			c.sendError(msg.RequestID, ErrorCodeNotMember, "Join this mix with an invite first")
+			return
+		case errors.Is(err, mix.ErrInvalidInvite), errors.Is(err, mix.ErrInviteExpired), errors.Is(err, mix.ErrInviteUsedUp):
+			c.sendError(msg.RequestID, ErrorCodeInvalidInvite, err.Error())
+			return
+		case err != nil:
+			log.Printf("Failed to join %s to mix %s: %v", ident.UserID, c.UUID, err)
+			c.sendError(msg.RequestID, ErrorCodeNotMember, "Could not join this mix")
+			return
+		}
+
+		firstConnection, err := Pool.Identify(c, ident.UserID, ident.UserName, member.Role)
+		if err != nil {
+			c.sendError(msg.RequestID, ErrorCodeNameTaken, fmt.Sprintf("Someone in this mix is already called %s", ident.UserName))
+			return
+		}
+		log.Printf("User identified: %s (ID: %s, role: %s) on connection %s (uuid: %s)", c.UserName, c.UserID, member.Role, c.ID, c.UUID)
+
+		// Catch the client up on events it missed, or send the current recipes and recent chat
+		Pool.Resume(c, payload.LastSeq, func() []WSMessage {
+			return c.snapshot(msg.RequestID)
+		})
+
+		presenceMsg, err := NewReply(MessageTypePresenceState, msg.RequestID, Pool.Presence(c.UUID))
+		if err != nil {
+			log.Printf("Failed to create PRESENCE_STATE message: %v", err)
+		} else {
+			Pool.BroadcastToUUIDOnlySender(c.UUID, c.ID, presenceMsg)
+		}
+
+		// A second tab of someone already in the mix isn't a new arrival
+		if !firstConnection {
+			return
 		}
 
 		joinPayload := UserJoinedPayload{
@@ -204,27 +451,53 @@ func (c *Connection) handleMessage(msg WSMessage) {
 			log.Printf("Broadcast USER_JOINED for user %s to session %s", c.UserName, c.UUID)
 		}
 	case MessageTypeChatMessage:
-		if c.Status != "Active" {
-			log.Printf("Rejected CHAT_MESSAGE from unidentified connection %s", c.ID)
+		// Viewers can't change recipes but still take part in the chat
+		if !c.authorize(msg, RoleViewer) {
+			return
+		}
+
+		var payload ChatMessagePayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse CHAT_MESSAGE payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid CHAT_MESSAGE payload")
 			return
 		}
 		log.Printf("Received CHAT_MESSAGE from connection %s (uuid: %s)", c.ID, c.UUID)
-		Pool.BroadcastToUUIDExceptSender(c.UUID, c.ID, msg)
+		c.processChatMessage(msg.RequestID, payload)
+	case MessageTypeChatHistoryRequest:
+		if !c.authorize(msg, RoleViewer) {
+			return
+		}
+
+		var payload ChatHistoryRequestPayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse CHAT_HISTORY_REQUEST payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid CHAT_HISTORY_REQUEST payload")
+			return
+		}
+
+		historyMsg, err := c.chatHistory(msg.RequestID, payload.Before, payload.Limit)
+		if err != nil {
+			log.Printf("Failed to create CHAT_HISTORY message: %v", err)
+			return
+		}
+		Pool.BroadcastToUUIDOnlySender(c.UUID, c.ID, historyMsg)
 	case MessageTypeRecipeUrlRequest:
-		if c.Status != "Active" {
-			log.Printf("Rejected RECIPE_URL_REQUEST from unidentified connection %s", c.ID)
+		if !c.authorize(msg, RoleEditor) {
 			return
 		}
 
 		var payload RecipeUrlRequestPayload
 		if err := json.Unmarshal(msg.Data, &payload); err != nil {
 			log.Printf("Failed to parse RECIPE_URL_REQUEST payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid RECIPE_URL_REQUEST payload")
 			return
 		}
 
 		// Validate that the sharer info matches the connection
 		if payload.SharerID != c.UserID {
 			log.Printf("Sender ID mismatch in RECIPE_URL_REQUEST from connection %s", c.ID)
+			c.sendError(msg.RequestID, ErrorCodeSharerMismatch, "Sharer does not match the identified user")
 			return
 		}
 
@@ -232,8 +505,209 @@ func (c *Connection) handleMessage(msg WSMessage) {
 
 		// Process recipe in a separate goroutine to avoid blocking ReadPump
 		// This ensures the connection can continue processing pongs and other messages
-		go c.processRecipeRequest(payload)
+		go c.processRecipeRequest(msg.RequestID, payload)
+	case MessageTypeRecipeTextRequest:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload RecipeTextRequestPayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse RECIPE_TEXT_REQUEST payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid RECIPE_TEXT_REQUEST payload")
+			return
+		}
+
+		log.Printf("Received RECIPE_TEXT_REQUEST from %s (session: %s): %d bytes", c.UserName, c.UUID, len(payload.Text))
+
+		// AI extraction can take as long as fetching a page
+		go c.processRecipeText(msg.RequestID, payload)
+	case MessageTypeRecipeRemove:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload RecipeRemovePayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse RECIPE_REMOVE payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid RECIPE_REMOVE payload")
+			return
+		}
+
+		current, err := RemoveRecipe(c.UUID, payload.RecipeID, payload.Version, msg.RequestID)
+		if err != nil {
+			c.rejectRecipeEdit(msg.RequestID, payload.RecipeID, current, err)
+			return
+		}
+		log.Printf("%s removed recipe %s from mix %s", c.UserName, payload.RecipeID, c.UUID)
+	case MessageTypeRecipeUpdate:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload RecipeUpdatePayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse RECIPE_UPDATE payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid RECIPE_UPDATE payload")
+			return
+		}
+
+		update := recipe.RecipeUpdate{Ingredients: payload.Ingredients}
+		if payload.Name != "" {
+			update.Name = &payload.Name
+		}
+		current, err := UpdateRecipe(c.UUID, payload.RecipeID, payload.Version, update, msg.RequestID)
+		if err != nil {
+			c.rejectRecipeEdit(msg.RequestID, payload.RecipeID, current, err)
+			return
+		}
+		log.Printf("%s updated recipe %s to version %d in mix %s", c.UserName, payload.RecipeID, current.Version, c.UUID)
+	case MessageTypePlanEntryAdd:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload PlanEntryAddPayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse PLAN_ENTRY_ADD payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid PLAN_ENTRY_ADD payload")
+			return
+		}
+
+		added, err := AddPlanEntry(c.UUID, models.PlanEntry{
+			RecipeID:    payload.RecipeID,
+			Date:        payload.Date,
+			Meal:        payload.Meal,
+			Note:        payload.Note,
+			AddedByID:   c.UserID,
+			AddedByName: c.UserName,
+		}, msg.RequestID)
+		if err != nil {
+			c.rejectPlanEdit(msg.RequestID, err)
+			return
+		}
+		log.Printf("%s planned recipe %s for %s on %s in mix %s", c.UserName, added.RecipeID, added.Meal, added.Date, c.UUID)
+	case MessageTypePlanEntryMove:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload PlanEntryMovePayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse PLAN_ENTRY_MOVE payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid PLAN_ENTRY_MOVE payload")
+			return
+		}
+
+		if _, err := MovePlanEntry(c.UUID, payload.EntryID, payload.Date, payload.Meal, msg.RequestID); err != nil {
+			c.rejectPlanEdit(msg.RequestID, err)
+			return
+		}
+	case MessageTypePlanEntryRemove:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload PlanEntryRemovePayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse PLAN_ENTRY_REMOVE payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid PLAN_ENTRY_REMOVE payload")
+			return
+		}
+
+		if err := RemovePlanEntry(c.UUID, payload.EntryID, msg.RequestID); err != nil {
+			c.rejectPlanEdit(msg.RequestID, err)
+			return
+		}
+	case MessageTypeGroceryItemAdd:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload GroceryItemAddPayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse GROCERY_ITEM_ADD payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid GROCERY_ITEM_ADD payload")
+			return
+		}
+
+		added, err := AddGroceryItem(c.UUID, models.GroceryListItem{
+			Name:        payload.Name,
+			GroceryItem: payload.GroceryItem,
+			Quantity:    &payload.Quantity,
+			Unit:        &payload.Unit,
+			AssigneeID:  payload.AssigneeID,
+			AddedByID:   c.UserID,
+			AddedByName: c.UserName,
+		}, msg.RequestID)
+		if err != nil {
+			c.rejectGroceryEdit(msg.RequestID, "", models.GroceryListItem{}, err)
+			return
+		}
+		log.Printf("%s added %s to the grocery list of mix %s", c.UserName, added.Name, c.UUID)
+	case MessageTypeGroceryItemUpdate:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload GroceryItemUpdatePayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse GROCERY_ITEM_UPDATE payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid GROCERY_ITEM_UPDATE payload")
+			return
+		}
+
+		current, err := UpdateGroceryItem(c.UUID, payload.ItemID, payload.Version, grocerylist.ItemUpdate{
+			Name:       payload.Name,
+			Quantity:   payload.Quantity,
+			Unit:       payload.Unit,
+			AssigneeID: payload.AssigneeID,
+		}, msg.RequestID)
+		if err != nil {
+			c.rejectGroceryEdit(msg.RequestID, payload.ItemID, current, err)
+			return
+		}
+	case MessageTypeGroceryItemCheck:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload GroceryItemCheckPayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse GROCERY_ITEM_CHECK payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid GROCERY_ITEM_CHECK payload")
+			return
+		}
+
+		if _, err := CheckGroceryItem(c.UUID, payload.ItemID, payload.Checked, c.UserID, c.UserName, msg.RequestID); err != nil {
+			c.rejectGroceryEdit(msg.RequestID, payload.ItemID, models.GroceryListItem{}, err)
+			return
+		}
+	case MessageTypeGroceryItemRemove:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		var payload GroceryItemRemovePayload
+		if err := json.Unmarshal(msg.Data, &payload); err != nil {
+			log.Printf("Failed to parse GROCERY_ITEM_REMOVE payload from connection %s: %v", c.ID, err)
+			c.sendError(msg.RequestID, ErrorCodeInvalidPayload, "Invalid GROCERY_ITEM_REMOVE payload")
+			return
+		}
+
+		if err := RemoveGroceryItem(c.UUID, payload.ItemID, msg.RequestID); err != nil {
+			c.rejectGroceryEdit(msg.RequestID, payload.ItemID, models.GroceryListItem{}, err)
+			return
+		}
+	case MessageTypeGroceryListClearChecked:
+		if !c.authorize(msg, RoleEditor) {
+			return
+		}
+
+		removed := ClearCheckedGroceryItems(c.UUID, msg.RequestID)
+		log.Printf("%s cleared %d checked items from the grocery list of mix %s", c.UserName, len(removed), c.UUID)
 	default:
 		log.Printf("Unknown message type from connection %s: %s", c.ID, msg.Type)
+		c.sendError(msg.RequestID, ErrorCodeUnknownMessageType, fmt.Sprintf("Unknown message type %s", msg.Type))
 	}
 }
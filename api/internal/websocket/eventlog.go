@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// eventLogSize is how many recent events each mix keeps for resuming clients
+	eventLogSize = 512
+	// eventLogMaxAge is how long an event is kept for resuming clients; those
+	// away longer get a snapshot instead
+	eventLogMaxAge = 15 * time.Minute
+	// maxReplayEvents keeps a replay well inside a connection's Send buffer;
+	// clients further behind get a snapshot instead
+	maxReplayEvents = 128
+)
+
+// loggedEvent is a sequenced broadcast along with when it was sent
+type loggedEvent struct {
+	message WSMessage
+	at      time.Time
+}
+
+// EventLog assigns sequence numbers to the events of one mix and keeps the
+// most recent ones so reconnecting clients can catch up
+type EventLog struct {
+	maxAge time.Duration
+
+	mu     sync.Mutex
+	seq    uint64
+	events []loggedEvent
+}
+
+// NewEventLog creates an event log keeping events for at most maxAge
+func NewEventLog(maxAge time.Duration) *EventLog {
+	return &EventLog{maxAge: maxAge}
+}
+
+// Append stamps message with the next sequence number and records it
+func (l *EventLog) Append(message WSMessage) WSMessage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	message.Seq = l.seq
+
+	now := time.Now()
+	l.events = append(l.events, loggedEvent{message: message, at: now})
+	if len(l.events) > eventLogSize {
+		l.events = l.events[len(l.events)-eventLogSize:]
+	}
+	l.prune(now)
+	return message
+}
+
+// Seq returns the sequence number of the latest event
+func (l *EventLog) Seq() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq
+}
+
+// Since returns the events after lastSeq. Every connection gets them all,
+// including its user's own, since a new connection has seen none of them. ok
+// is false when lastSeq is no longer covered by the log, or is from before a
+// restart.
+func (l *EventLog) Since(lastSeq uint64) (events []WSMessage, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(time.Now())
+	if lastSeq > l.seq {
+		return nil, false
+	}
+	oldest := l.seq - uint64(len(l.events)) + 1
+	if lastSeq+1 < oldest {
+		return nil, false
+	}
+
+	for _, event := range l.events {
+		if event.message.Seq > lastSeq {
+			events = append(events, event.message)
+		}
+	}
+	return events, true
+}
+
+// Prune drops events older than the log's maximum age. The sequence number is
+// kept, so clients that missed them get a snapshot.
+func (l *EventLog) Prune() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(time.Now())
+}
+
+// prune drops expired events. Callers hold l.mu.
+func (l *EventLog) prune(now time.Time) {
+	expired := 0
+	for expired < len(l.events) && now.Sub(l.events[expired].at) > l.maxAge {
+		expired++
+	}
+	if expired == len(l.events) {
+		l.events = nil
+	} else if expired > 0 {
+		l.events = l.events[expired:]
+	}
+}
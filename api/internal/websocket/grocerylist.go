@@ -0,0 +1,219 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/grocerylist"
+	"kitchenmix/api/internal/services/mix"
+	"kitchenmix/api/internal/services/shopping"
+)
+
+// ErrInvalidAssignee is returned when a grocery list item is assigned to
+// someone who isn't a member of the mix
+var ErrInvalidAssignee = errors.New("assignee is not a member of this mix")
+
+// groceryEdits is held from changing a grocery list until its broadcast is
+// sequenced, so concurrent edits reach every connection in the order they
+// were made
+var groceryEdits sync.Mutex
+
+// AddGroceryItem adds an item to a mix's grocery list and tells everyone
+// there. It is shared by GROCERY_ITEM_ADD and the REST API; any assignee
+// must be a member of the mix.
+func AddGroceryItem(mixID string, item models.GroceryListItem, requestID string) (models.GroceryListItem, error) {
+	if item.AssigneeID != "" {
+		assignee, err := assigneeName(mixID, item.AssigneeID)
+		if err != nil {
+			return models.GroceryListItem{}, err
+		}
+		item.AssigneeName = assignee
+	}
+
+	groceryEdits.Lock()
+	defer groceryEdits.Unlock()
+
+	added, err := GroceryLists.Add(mixID, item)
+	if err != nil {
+		return models.GroceryListItem{}, err
+	}
+	broadcastGroceryItemUpdates(mixID, requestID, added)
+	return added, nil
+}
+
+// SkippedGroceryItem is a shopping list item left off the grocery list, and why
+type SkippedGroceryItem struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// AddShoppingItems puts the items of a shopping list on a mix's grocery
+// list, added by the given user, and tells everyone there in one
+// GROCERY_ITEM_UPDATES. Items already on the list and not yet ticked off are
+// skipped, checked under the same lock as the additions so filling the list
+// twice at once doesn't buy twice; so are items the list can't hold, such as
+// ones with overlong names. The rest are added all or none.
+func AddShoppingItems(mixID string, items []shopping.Item, userID string, userName string, requestID string) ([]models.GroceryListItem, []SkippedGroceryItem, error) {
+	groceryEdits.Lock()
+	defer groceryEdits.Unlock()
+
+	listed := make(map[string]bool)
+	for _, item := range GroceryLists.Items(mixID) {
+		if !item.Checked {
+			listed[shopping.NormalizeName(item.Name)] = true
+		}
+	}
+
+	var toAdd []models.GroceryListItem
+	skipped := []SkippedGroceryItem{}
+	for _, item := range items {
+		if listed[shopping.NormalizeName(item.Name)] {
+			skipped = append(skipped, SkippedGroceryItem{Name: item.Name, Reason: "already on the list"})
+			continue
+		}
+		listItems := item.GroceryListItems()
+		if err := validateGroceryItems(listItems); err != nil {
+			skipped = append(skipped, SkippedGroceryItem{Name: item.Name, Reason: err.Error()})
+			continue
+		}
+		for _, listItem := range listItems {
+			listItem.AddedByID, listItem.AddedByName = userID, userName
+			toAdd = append(toAdd, listItem)
+		}
+	}
+	if len(toAdd) == 0 {
+		return []models.GroceryListItem{}, skipped, nil
+	}
+
+	added, err := GroceryLists.AddAll(mixID, toAdd)
+	if err != nil {
+		return nil, nil, err
+	}
+	broadcastGroceryItemUpdates(mixID, requestID, added...)
+	return added, skipped, nil
+}
+
+// UpdateGroceryItem edits an item on a mix's grocery list and tells everyone
+// there. It is shared by GROCERY_ITEM_UPDATE and the REST API. On
+// grocerylist.ErrVersionConflict the current item is returned.
+func UpdateGroceryItem(mixID string, itemID string, version int, update grocerylist.ItemUpdate, requestID string) (models.GroceryListItem, error) {
+	if update.AssigneeID != nil && *update.AssigneeID != "" {
+		assignee, err := assigneeName(mixID, *update.AssigneeID)
+		if err != nil {
+			return models.GroceryListItem{}, err
+		}
+		update.AssigneeName = assignee
+	}
+
+	groceryEdits.Lock()
+	defer groceryEdits.Unlock()
+
+	updated, err := GroceryLists.Update(mixID, itemID, version, update)
+	if err != nil {
+		return updated, err
+	}
+	broadcastGroceryItemUpdates(mixID, requestID, updated)
+	return updated, nil
+}
+
+// CheckGroceryItem ticks an item off a mix's grocery list, or unticks it,
+// and tells everyone there. It is shared by GROCERY_ITEM_CHECK and the REST
+// API.
+func CheckGroceryItem(mixID string, itemID string, checked bool, userID string, userName string, requestID string) (models.GroceryListItem, error) {
+	groceryEdits.Lock()
+	defer groceryEdits.Unlock()
+
+	checkedItem, err := GroceryLists.Check(mixID, itemID, checked, userID, userName)
+	if err != nil {
+		return models.GroceryListItem{}, err
+	}
+	broadcastGroceryItemUpdates(mixID, requestID, checkedItem)
+	return checkedItem, nil
+}
+
+// RemoveGroceryItem takes an item off a mix's grocery list and tells
+// everyone there. It is shared by GROCERY_ITEM_REMOVE and the REST API.
+func RemoveGroceryItem(mixID string, itemID string, requestID string) error {
+	groceryEdits.Lock()
+	defer groceryEdits.Unlock()
+
+	if err := GroceryLists.Remove(mixID, itemID); err != nil {
+		return err
+	}
+	broadcastGroceryItemRemovals(mixID, requestID, itemID)
+	return nil
+}
+
+// ClearCheckedGroceryItems takes every checked item off a mix's grocery list
+// and tells everyone there which. It is shared by GROCERY_LIST_CLEAR_CHECKED
+// and the REST API.
+func ClearCheckedGroceryItems(mixID string, requestID string) []string {
+	groceryEdits.Lock()
+	defer groceryEdits.Unlock()
+
+	removed := GroceryLists.RemoveChecked(mixID)
+	if len(removed) > 0 {
+		broadcastGroceryItemRemovals(mixID, requestID, removed...)
+	}
+	return removed
+}
+
+// validateGroceryItems checks items could all be added to a grocery list
+func validateGroceryItems(items []models.GroceryListItem) error {
+	for _, item := range items {
+		if err := grocerylist.Validate(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assigneeName looks up the name of a member of a mix
+func assigneeName(mixID string, userID string) (string, error) {
+	member, err := Mixes.Member(mixID, userID)
+	if errors.Is(err, mix.ErrNotMember) {
+		return "", ErrInvalidAssignee
+	}
+	if err != nil {
+		return "", err
+	}
+	return member.UserName, nil
+}
+
+// rejectGroceryEdit reports why a grocery list message failed, sending a
+// connection whose edit was stale the item as it is now
+func (c *Connection) rejectGroceryEdit(requestID string, itemID string, current models.GroceryListItem, err error) {
+	switch {
+	case errors.Is(err, grocerylist.ErrVersionConflict):
+		log.Printf("Rejected stale edit of grocery list item %s from %s (uuid: %s)", itemID, c.UserName, c.UUID)
+		c.sendError(requestID, ErrorCodeVersionConflict, fmt.Sprintf("Item has changed; it is now at version %d", current.Version))
+		if msg, err := NewReply(MessageTypeGroceryItemUpdates, requestID, GroceryItemUpdatesPayload{List: []models.GroceryListItem{current}}); err == nil {
+			Pool.BroadcastToUUIDOnlySender(c.UUID, c.ID, msg)
+		}
+	case errors.Is(err, grocerylist.ErrItemNotFound):
+		c.sendError(requestID, ErrorCodeGroceryItemNotFound, "Item is not on this mix's grocery list")
+	default:
+		c.sendError(requestID, ErrorCodeInvalidPayload, err.Error())
+	}
+}
+
+func broadcastGroceryItemUpdates(mixID string, requestID string, items ...models.GroceryListItem) {
+	updatesMsg, err := NewReply(MessageTypeGroceryItemUpdates, requestID, GroceryItemUpdatesPayload{List: items})
+	if err != nil {
+		log.Printf("Failed to create GROCERY_ITEM_UPDATES message: %v", err)
+		return
+	}
+	Pool.BroadcastToUUID(mixID, updatesMsg)
+}
+
+func broadcastGroceryItemRemovals(mixID string, requestID string, itemIDs ...string) {
+	removalsMsg, err := NewReply(MessageTypeGroceryItemRemovals, requestID, GroceryItemRemovalsPayload{ItemIDs: itemIDs})
+	if err != nil {
+		log.Printf("Failed to create GROCERY_ITEM_REMOVALS message: %v", err)
+		return
+	}
+	Pool.BroadcastToUUID(mixID, removalsMsg)
+}
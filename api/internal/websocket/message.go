@@ -1,27 +1,24 @@
 package websocket
 
 import (
+	_ "embed"
 	"encoding/json"
 	"time"
-
-	"kitchenmix/api/internal/models"
 )
 
-const (
-	MessageTypeConnectionAck    = "CONNECTION_ACK"
-	MessageTypePing             = "PING"
-	MessageTypeUserIdentify     = "USER_IDENTIFY"
-	MessageTypeUserJoined       = "USER_JOINED"
-	MessageTypeUserLeft         = "USER_LEFT"
-	MessageTypeChatMessage      = "CHAT_MESSAGE"
-	MessageTypeRecipeUrlRequest = "RECIPE_URL_REQUEST"
-	MessageTypeRecipeAdditions  = "RECIPE_ADDITIONS"
-	MessageTypeRecipeProgress   = "RECIPE_PROGRESS"
-)
+// Message type constants and payload structs are generated from the schema
+//go:generate go run ../../cmd/protogen -schema protocol.schema.json -go-out protocol.gen.go -ts-out ../../../web/src/types/protocol.ts
+
+// ProtocolSchema is the machine-readable definition of every message type
+//
+//go:embed protocol.schema.json
+var ProtocolSchema []byte
 
 type WSMessage struct {
 	Type      string          `json:"type"`
 	Timestamp time.Time       `json:"timestamp"`
+	RequestID string          `json:"requestId,omitempty"`
+	Seq       uint64          `json:"seq,omitempty"`
 	Data      json.RawMessage `json:"data"`
 }
 
@@ -38,37 +35,12 @@ func NewMessage(messageType string, data interface{}) (WSMessage, error) {
 	}, nil
 }
 
-type UserIdentifyPayload struct {
-	UserID   string `json:"userId"`
-	UserName string `json:"userName"`
-}
-
-type UserJoinedPayload struct {
-	UserID    string `json:"userId"`
-	UserName  string `json:"userName"`
-	SessionID string `json:"sessionId"`
-}
-
-type UserLeftPayload struct {
-	UserID    string `json:"userId"`
-	UserName  string `json:"userName"`
-	SessionID string `json:"sessionId"`
-}
-
-type RecipeUrlRequestPayload struct {
-	SharerID   string `json:"sharerId"`
-	SharerName string `json:"sharerName"`
-	URL        string `json:"url"`
-}
-
-type RecipeAdditionsPayload struct {
-	Status string           `json:"status"`
-	List   []*models.Recipe `json:"list"`
-}
-
-type RecipeProgressPayload struct {
-	Request RecipeUrlRequestPayload `json:"request"`
-	Phase   string                  `json:"phase"`
-	Status  string                  `json:"status"`
-	Message string                  `json:"message"`
+// NewReply creates a message correlated with the client request that caused it
+func NewReply(messageType string, requestID string, data interface{}) (WSMessage, error) {
+	msg, err := NewMessage(messageType, data)
+	if err != nil {
+		return WSMessage{}, err
+	}
+	msg.RequestID = requestID
+	return msg, nil
 }
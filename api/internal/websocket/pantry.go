@@ -0,0 +1,95 @@
+package websocket
+
+import (
+	"log"
+	"sync"
+
+	"kitchenmix/api/internal/models"
+)
+
+// pantryEdits is held from changing a pantry until its broadcast is
+// sequenced, so concurrent edits reach every connection in the order they
+// were made
+var pantryEdits sync.Mutex
+
+// AddPantryItem stocks a mix's pantry with an item and tells everyone there.
+// It is shared by the REST API and anything else that stocks pantries.
+func AddPantryItem(mixID string, item models.PantryItem, requestID string) (models.PantryItem, error) {
+	item.GroceryItem = recipeGroceryItem(mixID, item.GroceryItem)
+
+	pantryEdits.Lock()
+	defer pantryEdits.Unlock()
+
+	added, err := Pantries.Add(mixID, item)
+	if err != nil {
+		return models.PantryItem{}, err
+	}
+	broadcastPantryItemUpdates(mixID, requestID, added)
+	return added, nil
+}
+
+// ReplacePantryItem updates an item in a mix's pantry and tells everyone
+// there.
+func ReplacePantryItem(mixID string, itemID string, item models.PantryItem, requestID string) (models.PantryItem, error) {
+	item.GroceryItem = recipeGroceryItem(mixID, item.GroceryItem)
+
+	pantryEdits.Lock()
+	defer pantryEdits.Unlock()
+
+	replaced, err := Pantries.Replace(mixID, itemID, item)
+	if err != nil {
+		return models.PantryItem{}, err
+	}
+	broadcastPantryItemUpdates(mixID, requestID, replaced)
+	return replaced, nil
+}
+
+// RemovePantryItem takes an item out of a mix's pantry and tells everyone
+// there.
+func RemovePantryItem(mixID string, itemID string, requestID string) error {
+	pantryEdits.Lock()
+	defer pantryEdits.Unlock()
+
+	if err := Pantries.Remove(mixID, itemID); err != nil {
+		return err
+	}
+	broadcastPantryItemRemovals(mixID, requestID, itemID)
+	return nil
+}
+
+// recipeGroceryItem returns the catalogue entry with the ID of sent as the
+// mix's recipes have it. Shopping lists match pantry items to ingredients by
+// that ID, so entries no recipe in the mix uses are dropped rather than
+// trusted as sent.
+func recipeGroceryItem(mixID string, sent *models.GroceryItem) *models.GroceryItem {
+	if sent == nil || sent.ID == "" {
+		return nil
+	}
+	for _, r := range Recipes.GetMixRecipes(mixID) {
+		for _, ingredient := range r.Ingredients {
+			if ingredient.GroceryItem != nil && ingredient.GroceryItem.ID == sent.ID {
+				found := *ingredient.GroceryItem
+				return &found
+			}
+		}
+	}
+	return nil
+}
+
+func broadcastPantryItemUpdates(mixID string, requestID string, items ...models.PantryItem) {
+	updatesMsg, err := NewReply(MessageTypePantryItemUpdates, requestID, PantryItemUpdatesPayload{List: items})
+	if err != nil {
+		log.Printf("Failed to create PANTRY_ITEM_UPDATES message: %v", err)
+		return
+	}
+	Pool.BroadcastToUUID(mixID, updatesMsg)
+}
+
+func broadcastPantryItemRemovals(mixID string, requestID string, itemIDs ...string) {
+	removalsMsg, err := NewReply(MessageTypePantryItemRemovals, requestID, PantryItemRemovalsPayload{ItemIDs: itemIDs})
+	if err != nil {
+		log.Printf("Failed to create PANTRY_ITEM_REMOVALS message: %v", err)
+		return
+	}
+	Pool.BroadcastToUUID(mixID, removalsMsg)
+}
@@ -0,0 +1,94 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/plan"
+	"kitchenmix/api/internal/services/recipe"
+)
+
+// planEdits is held from changing a plan until its broadcast is sequenced,
+// so concurrent edits reach every connection in the order they were made.
+// Code holding recipeEdits too takes it first.
+var planEdits sync.Mutex
+
+// AddPlanEntry schedules a recipe in a mix and tells everyone there. It is
+// shared by PLAN_ENTRY_ADD and the REST API; the recipe must be in the mix.
+func AddPlanEntry(mixID string, entry models.PlanEntry, requestID string) (models.PlanEntry, error) {
+	// The recipe mustn't be removed between checking for it and planning it
+	recipeEdits.Lock()
+	defer recipeEdits.Unlock()
+	planEdits.Lock()
+	defer planEdits.Unlock()
+
+	if _, err := Recipes.GetMixRecipe(mixID, entry.RecipeID); err != nil {
+		return models.PlanEntry{}, err
+	}
+
+	added, err := Plans.Add(mixID, entry)
+	if err != nil {
+		return models.PlanEntry{}, err
+	}
+	broadcastPlanUpdates(mixID, requestID, added)
+	return added, nil
+}
+
+// MovePlanEntry reschedules an entry and tells everyone in the mix. It is
+// shared by PLAN_ENTRY_MOVE and the REST API.
+func MovePlanEntry(mixID string, entryID string, date string, meal string, requestID string) (models.PlanEntry, error) {
+	planEdits.Lock()
+	defer planEdits.Unlock()
+
+	moved, err := Plans.Move(mixID, entryID, date, meal)
+	if err != nil {
+		return models.PlanEntry{}, err
+	}
+	broadcastPlanUpdates(mixID, requestID, moved)
+	return moved, nil
+}
+
+// RemovePlanEntry takes an entry off the plan and tells everyone in the mix.
+// It is shared by PLAN_ENTRY_REMOVE and the REST API.
+func RemovePlanEntry(mixID string, entryID string, requestID string) error {
+	planEdits.Lock()
+	defer planEdits.Unlock()
+
+	if err := Plans.Remove(mixID, entryID); err != nil {
+		return err
+	}
+	broadcastPlanRemovals(mixID, requestID, entryID)
+	return nil
+}
+
+// rejectPlanEdit reports why a plan message failed
+func (c *Connection) rejectPlanEdit(requestID string, err error) {
+	switch {
+	case errors.Is(err, plan.ErrEntryNotFound):
+		c.sendError(requestID, ErrorCodePlanEntryNotFound, "Plan entry is not in this mix")
+	case errors.Is(err, recipe.ErrRecipeNotFound):
+		c.sendError(requestID, ErrorCodeRecipeNotFound, "Recipe is not in this mix")
+	default:
+		c.sendError(requestID, ErrorCodeInvalidPayload, err.Error())
+	}
+}
+
+func broadcastPlanUpdates(mixID string, requestID string, entries ...models.PlanEntry) {
+	updatesMsg, err := NewReply(MessageTypePlanUpdates, requestID, PlanUpdatesPayload{List: entries})
+	if err != nil {
+		log.Printf("Failed to create PLAN_UPDATES message: %v", err)
+		return
+	}
+	Pool.BroadcastToUUID(mixID, updatesMsg)
+}
+
+func broadcastPlanRemovals(mixID string, requestID string, entryIDs ...string) {
+	removalsMsg, err := NewReply(MessageTypePlanRemovals, requestID, PlanRemovalsPayload{EntryIDs: entryIDs})
+	if err != nil {
+		log.Printf("Failed to create PLAN_REMOVALS message: %v", err)
+		return
+	}
+	Pool.BroadcastToUUID(mixID, removalsMsg)
+}
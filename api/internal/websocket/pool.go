@@ -1,19 +1,35 @@
 package websocket
 
 import (
+	"errors"
 	"log"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ErrNameTaken is returned when another user in the mix has the same name
+var ErrNameTaken = errors.New("name is already taken in this mix")
+
 type ConnectionPool struct {
-	mu          sync.RWMutex
+	mu sync.RWMutex
+	// Counters for messages dropped and connections closed as slow consumers
+	dropped         atomic.Int64
+	slowDisconnects atomic.Int64
+
 	connections map[string]*Connection
 	index       map[string][]*Connection
+	logs        map[string]*EventLog
+	// lastPrune is when expired events were last dropped from every mix's log
+	lastPrune time.Time
 }
 
 var Pool = &ConnectionPool{
 	connections: make(map[string]*Connection),
 	index:       make(map[string][]*Connection),
+	logs:        make(map[string]*EventLog),
 }
 
 func (p *ConnectionPool) Register(conn *Connection) {
@@ -39,7 +55,8 @@ func (p *ConnectionPool) Unregister(connID string) {
 		return
 	}
 
-	if conn.Status == "Active" {
+	// Other tabs of the same user keep them present in the mix
+	if conn.Status == "Active" && !p.hasOtherActive(conn) {
 		leavePayload := UserLeftPayload{
 			UserID:    conn.UserID,
 			UserName:  conn.UserName,
@@ -76,9 +93,13 @@ func (p *ConnectionPool) Unregister(connID string) {
 	p.mu.Unlock()
 }
 
+// BroadcastToUUID sequences message as a mix event and sends it to every connection in the mix
 func (p *ConnectionPool) BroadcastToUUID(uuid string, message WSMessage) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	// Sequenced broadcasts hold the write lock so events reach every connection in seq order
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	message = p.eventLog(uuid).Append(message)
 
 	connections, exists := p.index[uuid]
 	if !exists {
@@ -87,19 +108,19 @@ func (p *ConnectionPool) BroadcastToUUID(uuid string, message WSMessage) {
 	}
 
 	for _, conn := range connections {
-		select {
-		case conn.Send <- message:
-		default:
-			log.Printf("Failed to send message to connection %s (channel full)", conn.ID)
-		}
+		p.deliver(conn, message)
 	}
 
 	log.Printf("Broadcast message type %s to %d connections (uuid: %s)", message.Type, len(connections), uuid)
 }
 
+// BroadcastToUUIDExceptSender sequences message as a mix event and sends it to the
+// identified connections in the mix other than excludeConnID
 func (p *ConnectionPool) BroadcastToUUIDExceptSender(uuid string, excludeConnID string, message WSMessage) {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	message = p.eventLog(uuid).Append(message)
 
 	connections, exists := p.index[uuid]
 	if !exists {
@@ -115,11 +136,8 @@ func (p *ConnectionPool) BroadcastToUUIDExceptSender(uuid string, excludeConnID
 		if conn.Status != "Active" {
 			continue
 		}
-		select {
-		case conn.Send <- message:
+		if p.deliver(conn, message) {
 			sent++
-		default:
-			log.Printf("Failed to send message to connection %s (channel full)", conn.ID)
 		}
 	}
 
@@ -141,21 +159,266 @@ func (p *ConnectionPool) BroadcastToUUIDOnlySender(uuid string, senderConnID str
 		if conn.ID != senderConnID {
 			continue
 		}
-		// Send the message (non-blocking, same pattern as other broadcast helpers)
-		select {
-		case conn.Send <- message:
+		if p.deliver(conn, message) {
 			log.Printf("Sent %s to sender %s (uuid: %s)", message.Type, senderConnID, uuid)
-		default:
-			log.Printf("Failed to send message to connection %s (channel full)", conn.ID)
 		}
 		// We can break after we've delivered to the single match
 		break
 	}
 }
 
+// SendToUser sends message to the identified connections of one user in a
+// mix, e.g. every tab they have open, without sequencing it as a mix event
+func (p *ConnectionPool) SendToUser(uuid string, userID string, message WSMessage) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	sent := 0
+	for _, conn := range p.index[uuid] {
+		if conn.Status != "Active" || conn.UserID != userID {
+			continue
+		}
+		if p.deliver(conn, message) {
+			sent++
+		}
+	}
+
+	log.Printf("Sent %s to %d connections of user %s (uuid: %s)", message.Type, sent, userID, uuid)
+}
+
+// Resume brings an identifying connection up to date. If lastSeq is still covered by
+// the mix's event log the missed events are replayed, otherwise the connection gets
+// the messages built by snapshot. Either way a SYNC message is sent first.
+func (p *ConnectionPool) Resume(conn *Connection, lastSeq uint64, snapshot func() []WSMessage) {
+	// Holding the write lock keeps broadcasts from interleaving with the catch-up
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	eventLog := p.eventLog(conn.UUID)
+	seq := eventLog.Seq()
+
+	mode := SyncModeSnapshot
+	var messages []WSMessage
+	if lastSeq > 0 {
+		if missed, ok := eventLog.Since(lastSeq); ok && len(missed) <= maxReplayEvents {
+			mode = SyncModeReplay
+			messages = missed
+		}
+	}
+	if mode == SyncModeSnapshot {
+		messages = snapshot()
+	}
+
+	syncMsg, err := NewMessage(MessageTypeSync, SyncPayload{Mode: mode, Seq: seq})
+	if err != nil {
+		log.Printf("Failed to create SYNC message: %v", err)
+		return
+	}
+
+	for _, message := range append([]WSMessage{syncMsg}, messages...) {
+		if !p.deliver(conn, message) {
+			return
+		}
+	}
+
+	log.Printf("Resumed connection %s from seq %d with %s of %d messages (uuid: %s, seq: %d)", conn.ID, lastSeq, mode, len(messages), conn.UUID, seq)
+}
+
+// deliver queues message without blocking. Dropped messages are counted, and a
+// connection that drops too many is disconnected so its client resyncs.
+// Callers hold p.mu.
+func (p *ConnectionPool) deliver(conn *Connection, message WSMessage) bool {
+	select {
+	case conn.Send <- message:
+		return true
+	default:
+	}
+
+	p.dropped.Add(1)
+	dropped := conn.dropped.Add(1)
+	log.Printf("Dropped %s for connection %s (channel full, %d dropped)", message.Type, conn.ID, dropped)
+
+	if dropped == maxDroppedMessages {
+		p.slowDisconnects.Add(1)
+		go conn.closeSlow()
+	}
+	return false
+}
+
+// PoolStats is a point-in-time view of the pool for monitoring
+type PoolStats struct {
+	Connections         int              `json:"connections"`
+	Mixes               int              `json:"mixes"`
+	DroppedMessages     int64            `json:"droppedMessages"`
+	SlowDisconnects     int64            `json:"slowDisconnects"`
+	DroppedByConnection map[string]int64 `json:"droppedByConnection"`
+}
+
+// Stats reports connection counts and slow-consumer counters
+func (p *ConnectionPool) Stats() PoolStats {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := PoolStats{
+		Connections:         len(p.connections),
+		Mixes:               len(p.index),
+		DroppedMessages:     p.dropped.Load(),
+		SlowDisconnects:     p.slowDisconnects.Load(),
+		DroppedByConnection: make(map[string]int64),
+	}
+	for id, conn := range p.connections {
+		if dropped := conn.Dropped(); dropped > 0 {
+			stats.DroppedByConnection[id] = dropped
+		}
+	}
+	return stats
+}
+
+// eventLog returns the event log for a mix, creating it on first use. Now and
+// then the logs of every mix are pruned, so idle mixes don't keep their events.
+// Callers hold p.mu for writing.
+func (p *ConnectionPool) eventLog(uuid string) *EventLog {
+	if now := time.Now(); now.Sub(p.lastPrune) > eventLogMaxAge {
+		for _, eventLog := range p.logs {
+			eventLog.Prune()
+		}
+		p.lastPrune = now
+	}
+
+	eventLog, exists := p.logs[uuid]
+	if !exists {
+		eventLog = NewEventLog(eventLogMaxAge)
+		p.logs[uuid] = eventLog
+	}
+	return eventLog
+}
+
+// GetUUIDConnections returns a snapshot of the connections in a mix
 func (p *ConnectionPool) GetUUIDConnections(uuid string) []*Connection {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	return p.index[uuid]
+	return slices.Clone(p.index[uuid])
+}
+
+// CloseMix disconnects every connection in a mix and forgets its event log
+func (p *ConnectionPool) CloseMix(uuid string, code int, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, conn := range p.index[uuid] {
+		go conn.close(code, reason)
+	}
+	delete(p.logs, uuid)
+
+	log.Printf("Closed %d connections to mix %s: %s", len(p.index[uuid]), uuid, reason)
+}
+
+// CloseUsers disconnects the given users' connections to a mix, e.g. after
+// they lose their membership
+func (p *ConnectionPool) CloseUsers(uuid string, userIDs []string, code int, reason string) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, conn := range p.index[uuid] {
+		if slices.Contains(userIDs, conn.UserID) {
+			go conn.close(code, reason)
+		}
+	}
+
+	log.Printf("Closed connections of %d users in mix %s: %s", len(userIDs), uuid, reason)
+}
+
+// Identify marks conn as belonging to a user with the given role in the mix and
+// reports whether it is that user's first active connection there. It fails
+// with ErrNameTaken when another user in the mix already goes by userName.
+func (p *ConnectionPool) Identify(conn *Connection, userID string, userName string, role string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, other := range p.index[conn.UUID] {
+		if other.Status == "Active" && other.UserID != userID && strings.EqualFold(other.UserName, userName) {
+			return false, ErrNameTaken
+		}
+	}
+
+	alreadyPresent := conn.Status == "Active" && conn.UserID == userID
+
+	conn.UserID = userID
+	conn.UserName = userName
+	conn.Role = role
+	conn.Status = "Active"
+
+	return !alreadyPresent && !p.hasOtherActive(conn), nil
+}
+
+// Role returns the role conn's user has in the mix; empty before USER_IDENTIFY
+func (p *ConnectionPool) Role(conn *Connection) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return conn.Role
+}
+
+// SetRole changes the role of a user's connections in a mix and sends everyone
+// there the updated presence
+func (p *ConnectionPool) SetRole(uuid string, userID string, role string) {
+	p.mu.Lock()
+	for _, conn := range p.index[uuid] {
+		if conn.UserID == userID {
+			conn.Role = role
+		}
+	}
+	p.mu.Unlock()
+
+	presenceMsg, err := NewMessage(MessageTypePresenceState, p.Presence(uuid))
+	if err != nil {
+		log.Printf("Failed to create PRESENCE_STATE message: %v", err)
+		return
+	}
+	p.BroadcastToUUID(uuid, presenceMsg)
+}
+
+// hasOtherActive reports whether conn's user has another active connection in the mix. Callers hold p.mu.
+func (p *ConnectionPool) hasOtherActive(conn *Connection) bool {
+	for _, other := range p.index[conn.UUID] {
+		if other.ID != conn.ID && other.Status == "Active" && other.UserID == conn.UserID {
+			return true
+		}
+	}
+	return false
+}
+
+// Presence lists the users identified in a mix, one entry per user however many
+// connections they have, in the order they joined
+func (p *ConnectionPool) Presence(uuid string) PresenceStatePayload {
+	// Read under the lock since Identify updates connections in place
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	users := []PresenceUser{}
+	positions := make(map[string]int)
+
+	for _, conn := range p.index[uuid] {
+		if conn.Status != "Active" {
+			continue
+		}
+		if i, ok := positions[conn.UserID]; ok {
+			users[i].Connections++
+			continue
+		}
+		positions[conn.UserID] = len(users)
+		users = append(users, PresenceUser{
+			UserID:      conn.UserID,
+			UserName:    conn.UserName,
+			Role:        conn.Role,
+			Connections: 1,
+		})
+	}
+
+	return PresenceStatePayload{Users: users}
 }
@@ -0,0 +1,308 @@
+// Code generated by protogen from protocol.schema.json. DO NOT EDIT.
+
+package websocket
+
+import (
+	"kitchenmix/api/internal/models"
+)
+
+const (
+	ProtocolVersion    = 4
+	MinProtocolVersion = 4
+)
+
+const (
+	MessageTypeConnectionAck           = "CONNECTION_ACK"
+	MessageTypePing                    = "PING"
+	MessageTypeUserIdentify            = "USER_IDENTIFY"
+	MessageTypeUserJoined              = "USER_JOINED"
+	MessageTypeUserLeft                = "USER_LEFT"
+	MessageTypePresenceState           = "PRESENCE_STATE"
+	MessageTypeChatMessage             = "CHAT_MESSAGE"
+	MessageTypeChatHistoryRequest      = "CHAT_HISTORY_REQUEST"
+	MessageTypeChatHistory             = "CHAT_HISTORY"
+	MessageTypeRecipeUrlRequest        = "RECIPE_URL_REQUEST"
+	MessageTypeRecipeTextRequest       = "RECIPE_TEXT_REQUEST"
+	MessageTypeRecipeAdditions         = "RECIPE_ADDITIONS"
+	MessageTypeRecipeProgress          = "RECIPE_PROGRESS"
+	MessageTypeRecipeImportProgress    = "RECIPE_IMPORT_PROGRESS"
+	MessageTypeRecipeRemove            = "RECIPE_REMOVE"
+	MessageTypeRecipeRemovals          = "RECIPE_REMOVALS"
+	MessageTypeRecipeUpdate            = "RECIPE_UPDATE"
+	MessageTypeRecipeUpdates           = "RECIPE_UPDATES"
+	MessageTypePlanEntryAdd            = "PLAN_ENTRY_ADD"
+	MessageTypePlanEntryMove           = "PLAN_ENTRY_MOVE"
+	MessageTypePlanEntryRemove         = "PLAN_ENTRY_REMOVE"
+	MessageTypePlanUpdates             = "PLAN_UPDATES"
+	MessageTypePlanRemovals            = "PLAN_REMOVALS"
+	MessageTypeGroceryItemAdd          = "GROCERY_ITEM_ADD"
+	MessageTypeGroceryItemUpdate       = "GROCERY_ITEM_UPDATE"
+	MessageTypeGroceryItemCheck        = "GROCERY_ITEM_CHECK"
+	MessageTypeGroceryItemRemove       = "GROCERY_ITEM_REMOVE"
+	MessageTypeGroceryListClearChecked = "GROCERY_LIST_CLEAR_CHECKED"
+	MessageTypeGroceryItemUpdates      = "GROCERY_ITEM_UPDATES"
+	MessageTypeGroceryItemRemovals     = "GROCERY_ITEM_REMOVALS"
+	MessageTypePantryItemUpdates       = "PANTRY_ITEM_UPDATES"
+	MessageTypePantryItemRemovals      = "PANTRY_ITEM_REMOVALS"
+	MessageTypeError                   = "ERROR"
+	MessageTypeSync                    = "SYNC"
+)
+
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+const (
+	MealBreakfast = "breakfast"
+	MealLunch     = "lunch"
+	MealDinner    = "dinner"
+	MealSnack     = "snack"
+)
+
+const (
+	ErrorCodeInvalidPayload       = "INVALID_PAYLOAD"
+	ErrorCodeNotIdentified        = "NOT_IDENTIFIED"
+	ErrorCodeSharerMismatch       = "SHARER_MISMATCH"
+	ErrorCodeUnknownMessageType   = "UNKNOWN_MESSAGE_TYPE"
+	ErrorCodeRecipeUnavailable    = "RECIPE_UNAVAILABLE"
+	ErrorCodeIncompatibleProtocol = "INCOMPATIBLE_PROTOCOL"
+	ErrorCodeInvalidIdentity      = "INVALID_IDENTITY"
+	ErrorCodeNameTaken            = "NAME_TAKEN"
+	ErrorCodeForbidden            = "FORBIDDEN"
+	ErrorCodeNotMember            = "NOT_MEMBER"
+	ErrorCodeInvalidInvite        = "INVALID_INVITE"
+	ErrorCodeRecipeNotFound       = "RECIPE_NOT_FOUND"
+	ErrorCodeVersionConflict      = "VERSION_CONFLICT"
+	ErrorCodeNoIngredients        = "NO_INGREDIENTS"
+	ErrorCodePlanEntryNotFound    = "PLAN_ENTRY_NOT_FOUND"
+	ErrorCodeGroceryItemNotFound  = "GROCERY_ITEM_NOT_FOUND"
+)
+
+const (
+	SyncModeReplay   = "replay"
+	SyncModeSnapshot = "snapshot"
+)
+
+type ConnectionAckPayload struct {
+	ID                 string `json:"id"`
+	Message            string `json:"message"`
+	ProtocolVersion    int    `json:"protocolVersion"`
+	MinProtocolVersion int    `json:"minProtocolVersion"`
+}
+
+type PingPayload struct{}
+
+// UserIdentifyPayload identifies the connection as the user named by token; userId, when sent, must match it
+type UserIdentifyPayload struct {
+	Token    string `json:"token"`
+	UserID   string `json:"userId,omitempty"`
+	UserName string `json:"userName,omitempty"`
+	LastSeq  uint64 `json:"lastSeq,omitempty"`
+	Invite   string `json:"invite,omitempty"`
+}
+
+type UserJoinedPayload struct {
+	UserID    string `json:"userId"`
+	UserName  string `json:"userName"`
+	SessionID string `json:"sessionId"`
+}
+
+type UserLeftPayload struct {
+	UserID    string `json:"userId"`
+	UserName  string `json:"userName"`
+	SessionID string `json:"sessionId"`
+}
+
+// PresenceStatePayload lists everyone identified in the mix and is sent after USER_IDENTIFY. USER_JOINED and USER_LEFT then follow a user's first and last connection
+type PresenceStatePayload struct {
+	Users []PresenceUser `json:"users"`
+}
+
+type PresenceUser struct {
+	UserID      string `json:"userId"`
+	UserName    string `json:"userName"`
+	Role        string `json:"role"`
+	Connections int    `json:"connections"`
+}
+
+// ChatMessagePayload is stored and relayed to the other connections in the mix; the sender gets the stored message as a reply
+type ChatMessagePayload struct {
+	Type      string             `json:"type"`
+	Payload   models.ChatMessage `json:"payload"`
+	Timestamp string             `json:"timestamp"`
+}
+
+type ChatHistoryRequestPayload struct {
+	Before string `json:"before,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// ChatHistoryPayload is a page of chat history, oldest message first
+type ChatHistoryPayload struct {
+	Messages []models.ChatMessage `json:"messages"`
+	HasMore  bool                 `json:"hasMore"`
+}
+
+type RecipeUrlRequestPayload struct {
+	SharerID   string `json:"sharerId"`
+	SharerName string `json:"sharerName"`
+	URL        string `json:"url"`
+}
+
+// RecipeTextRequestPayload adds a recipe from pasted text, shared by the identified user. Progress is reported with RECIPE_PROGRESS as for RECIPE_URL_REQUEST, with an empty url
+type RecipeTextRequestPayload struct {
+	Text  string `json:"text"`
+	Name  string `json:"name,omitempty"`
+	UseAI bool   `json:"useAi,omitempty"`
+}
+
+type RecipeAdditionsPayload struct {
+	Status string           `json:"status"`
+	List   []*models.Recipe `json:"list"`
+}
+
+type RecipeProgressPayload struct {
+	Request RecipeUrlRequestPayload `json:"request"`
+	Phase   string                  `json:"phase"`
+	Status  string                  `json:"status"`
+	Message string                  `json:"message"`
+}
+
+// RecipeImportProgressPayload reports on a file import started with POST /mixes/:id/recipes/import, to the importing user's connections only. The last one has phase complete and the result
+type RecipeImportProgressPayload struct {
+	ImportID string              `json:"importId"`
+	Phase    string              `json:"phase"`
+	Status   string              `json:"status"`
+	Message  string              `json:"message"`
+	Result   *RecipeImportResult `json:"result,omitempty"`
+}
+
+// RecipeImportResult summarises an import. Skipped entries are recipes already in the mix; failed entries couldn't be read or extracted
+type RecipeImportResult struct {
+	Added   []*models.Recipe `json:"added"`
+	Skipped []ImportIssue    `json:"skipped"`
+	Failed  []ImportIssue    `json:"failed"`
+}
+
+type ImportIssue struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+type RecipeRemovePayload struct {
+	RecipeID string `json:"recipeId"`
+	Version  int    `json:"version"`
+}
+
+// RecipeRemovalsPayload lists recipes removed from the mix
+type RecipeRemovalsPayload struct {
+	RecipeIDs []string `json:"recipeIds"`
+}
+
+// RecipeUpdatePayload corrects a stored recipe; omitted fields are left unchanged and ingredients replaces the whole list. On VERSION_CONFLICT the current recipe is sent back in RECIPE_UPDATES
+type RecipeUpdatePayload struct {
+	RecipeID    string              `json:"recipeId"`
+	Version     int                 `json:"version"`
+	Name        string              `json:"name,omitempty"`
+	Ingredients []models.Ingredient `json:"ingredients,omitempty"`
+}
+
+// RecipeUpdatesPayload carries the new state of edited recipes
+type RecipeUpdatesPayload struct {
+	List []*models.Recipe `json:"list"`
+}
+
+// PlanEntryAddPayload schedules a recipe in the mix for a meal on a date; everyone gets the new entry in PLAN_UPDATES
+type PlanEntryAddPayload struct {
+	RecipeID string `json:"recipeId"`
+	Date     string `json:"date"`
+	Meal     string `json:"meal"`
+	Note     string `json:"note,omitempty"`
+}
+
+// PlanEntryMovePayload reschedules a plan entry for another date and meal
+type PlanEntryMovePayload struct {
+	EntryID string `json:"entryId"`
+	Date    string `json:"date"`
+	Meal    string `json:"meal"`
+}
+
+type PlanEntryRemovePayload struct {
+	EntryID string `json:"entryId"`
+}
+
+// PlanUpdatesPayload carries new and rescheduled plan entries; clients replace entries with the same id
+type PlanUpdatesPayload struct {
+	List []models.PlanEntry `json:"list"`
+}
+
+// PlanRemovalsPayload lists entries taken off the plan, including those for recipes removed from the mix
+type PlanRemovalsPayload struct {
+	EntryIDs []string `json:"entryIds"`
+}
+
+// GroceryItemAddPayload adds an item to the mix's grocery list; everyone gets it in GROCERY_ITEM_UPDATES
+type GroceryItemAddPayload struct {
+	Name        string              `json:"name"`
+	Quantity    string              `json:"quantity,omitempty"`
+	Unit        string              `json:"unit,omitempty"`
+	GroceryItem *models.GroceryItem `json:"groceryItem,omitempty"`
+	AssigneeID  string              `json:"assigneeId,omitempty"`
+}
+
+// GroceryItemUpdatePayload edits a grocery list item; omitted fields are left unchanged and empty ones are cleared. On VERSION_CONFLICT the current item is sent back in GROCERY_ITEM_UPDATES
+type GroceryItemUpdatePayload struct {
+	ItemID     string  `json:"itemId"`
+	Version    int     `json:"version"`
+	Name       *string `json:"name,omitempty"`
+	Quantity   *string `json:"quantity,omitempty"`
+	Unit       *string `json:"unit,omitempty"`
+	AssigneeID *string `json:"assigneeId,omitempty"`
+}
+
+// GroceryItemCheckPayload ticks an item off the grocery list, or unticks it; the last tick wins, so no version is needed
+type GroceryItemCheckPayload struct {
+	ItemID  string `json:"itemId"`
+	Checked bool   `json:"checked"`
+}
+
+type GroceryItemRemovePayload struct {
+	ItemID string `json:"itemId"`
+}
+
+// GroceryListClearCheckedPayload takes every checked item off the grocery list
+type GroceryListClearCheckedPayload struct{}
+
+// GroceryItemUpdatesPayload carries new and changed grocery list items; clients replace items with the same id
+type GroceryItemUpdatesPayload struct {
+	List []models.GroceryListItem `json:"list"`
+}
+
+// GroceryItemRemovalsPayload lists items taken off the grocery list
+type GroceryItemRemovalsPayload struct {
+	ItemIDs []string `json:"itemIds"`
+}
+
+// PantryItemUpdatesPayload carries new and changed pantry items; clients replace items with the same id
+type PantryItemUpdatesPayload struct {
+	List []models.PantryItem `json:"list"`
+}
+
+// PantryItemRemovalsPayload lists items taken out of the pantry
+type PantryItemRemovalsPayload struct {
+	ItemIDs []string `json:"itemIds"`
+}
+
+type ErrorPayload struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId,omitempty"`
+}
+
+// SyncPayload is sent after USER_IDENTIFY. In replay mode the missed events follow; in snapshot mode the current recipes, meal plan, grocery list and chat history follow
+type SyncPayload struct {
+	Mode string `json:"mode"`
+	Seq  uint64 `json:"seq"`
+}
@@ -0,0 +1,194 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"sync"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/importer"
+	"kitchenmix/api/internal/services/recipe"
+)
+
+// recipeEdits is held from changing a recipe until its broadcast is sequenced,
+// so concurrent edits reach every connection in the order they were made
+var recipeEdits sync.Mutex
+
+// RemoveRecipe removes a recipe from a mix and its meal plan, and tells
+// everyone there. It is shared by RECIPE_REMOVE and the REST API;
+// requestID correlates the broadcasts with the request that caused them, if
+// any. On recipe.ErrVersionConflict the current recipe is returned.
+func RemoveRecipe(mixID string, recipeID string, version int, requestID string) (*models.Recipe, error) {
+	recipeEdits.Lock()
+	defer recipeEdits.Unlock()
+
+	removed, err := Recipes.RemoveRecipe(mixID, recipeID, version)
+	if err != nil {
+		return removed, err
+	}
+
+	removalsMsg, err := recipeRemovals(requestID, recipeID)
+	if err != nil {
+		log.Printf("Failed to create RECIPE_REMOVALS message: %v", err)
+	} else {
+		Pool.BroadcastToUUID(mixID, removalsMsg)
+	}
+
+	planEdits.Lock()
+	defer planEdits.Unlock()
+	if unplanned := Plans.RemoveRecipe(mixID, recipeID); len(unplanned) > 0 {
+		broadcastPlanRemovals(mixID, requestID, unplanned...)
+	}
+	return removed, nil
+}
+
+// UpdateRecipe corrects a recipe in a mix and sends everyone there its new
+// state. It is shared by RECIPE_UPDATE and the REST API. On
+// recipe.ErrVersionConflict the current recipe is returned.
+func UpdateRecipe(mixID string, recipeID string, version int, update recipe.RecipeUpdate, requestID string) (*models.Recipe, error) {
+	recipeEdits.Lock()
+	defer recipeEdits.Unlock()
+
+	updated, err := Recipes.UpdateRecipe(mixID, recipeID, version, update)
+	if err != nil {
+		return updated, err
+	}
+
+	updatesMsg, err := recipeUpdates(requestID, updated)
+	if err != nil {
+		log.Printf("Failed to create RECIPE_UPDATES message: %v", err)
+		return updated, nil
+	}
+	Pool.BroadcastToUUID(mixID, updatesMsg)
+	return updated, nil
+}
+
+// AddRecipeFromText adds a recipe from pasted text to a mix and tells everyone
+// there. It is shared by RECIPE_TEXT_REQUEST and the REST API; progress may be
+// nil.
+func AddRecipeFromText(mixID string, text string, name string, useAI bool, sharerID string, sharerName string, requestID string, progress func(string, string, string)) (*models.Recipe, error) {
+	added, err := Recipes.GetRecipeFromText(text, name, useAI, mixID, sharerID, sharerName, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	broadcastRecipeAdditions(mixID, requestID, added)
+	return added, nil
+}
+
+// ImportRecipes adds the recipes read from other apps' exports and from saved
+// pages to a mix, and sends everyone there the ones that were added at once.
+// It reports RECIPE_IMPORT_PROGRESS under importID to the importing user's
+// connections, ending with the result; unreadable lists the files that
+// couldn't be read at all. Extracting pages can take minutes, so the REST API
+// runs this in the background.
+func ImportRecipes(mixID string, importID string, entries []importer.Entry, pages []recipe.SavedPage, unreadable []recipe.ImportIssue, sharerID string, sharerName string) recipe.ImportResult {
+	progress := importProgressReporter(mixID, importID, sharerID)
+
+	if len(entries) > 0 {
+		progress("importing", "in_progress", fmt.Sprintf("Importing %d recipes from exports", len(entries)), nil)
+	}
+	result := importer.Import(Recipes, entries, mixID, sharerID, sharerName)
+	result.Merge(Recipes.ImportPages(pages, mixID, sharerID, sharerName, func(phase, status, message string) {
+		// Pages report their own completion; the import completes below
+		if phase != "complete" {
+			progress(phase, status, message, nil)
+		}
+	}))
+	result.Failed = append(unreadable, result.Failed...)
+	if len(result.Added) > 0 {
+		broadcastRecipeAdditions(mixID, "", result.Added...)
+	}
+
+	summary := RecipeImportResult{
+		Added:   result.Added,
+		Skipped: importIssues(result.Skipped),
+		Failed:  importIssues(result.Failed),
+	}
+	progress("complete", "completed", fmt.Sprintf("Imported %d of %d recipes", len(result.Added), len(result.Added)+len(result.Skipped)+len(result.Failed)), &summary)
+	return result
+}
+
+// importProgressReporter returns a callback that sends RECIPE_IMPORT_PROGRESS
+// for an import to the importing user only
+func importProgressReporter(mixID string, importID string, userID string) func(string, string, string, *RecipeImportResult) {
+	return func(phase, status, message string, result *RecipeImportResult) {
+		progressMsg, err := NewMessage(MessageTypeRecipeImportProgress, RecipeImportProgressPayload{
+			ImportID: importID,
+			Phase:    phase,
+			Status:   status,
+			Message:  message,
+			Result:   result,
+		})
+		if err != nil {
+			log.Printf("Failed to create RECIPE_IMPORT_PROGRESS message: %v", err)
+			return
+		}
+		Pool.SendToUser(mixID, userID, progressMsg)
+	}
+}
+
+func importIssues(issues []recipe.ImportIssue) []ImportIssue {
+	converted := make([]ImportIssue, len(issues))
+	for i, issue := range issues {
+		converted[i] = ImportIssue{Name: issue.Name, Reason: issue.Reason}
+	}
+	return converted
+}
+
+// broadcastRecipeAdditions sends new recipes to all connections in a mix,
+// including the one that shared them. Recipes are added to the store before
+// this is called, as they are fetched or imported, so an edit or removal may
+// have been broadcast since; holding recipeEdits, each is sent as it is
+// stored now and those removed meanwhile are left out, so the addition never
+// undoes a later broadcast.
+func broadcastRecipeAdditions(mixID string, requestID string, recipes ...*models.Recipe) {
+	recipeEdits.Lock()
+	defer recipeEdits.Unlock()
+
+	current := make([]*models.Recipe, 0, len(recipes))
+	for _, added := range recipes {
+		if stored, err := Recipes.GetMixRecipe(mixID, added.ID); err == nil {
+			current = append(current, stored)
+		}
+	}
+
+	responseMsg, err := NewReply(MessageTypeRecipeAdditions, requestID, RecipeAdditionsPayload{
+		Status: "success",
+		List:   current,
+	})
+	if err != nil {
+		log.Printf("Failed to create RECIPE_ADDITIONS message: %v", err)
+		return
+	}
+
+	Pool.BroadcastToUUID(mixID, responseMsg)
+	log.Printf("Broadcasted RECIPE_ADDITIONS to %s", mixID)
+}
+
+// resyncRecipe sends a connection whose edit was rejected the recipe as it
+// is now, or its removal if it no longer exists
+func (c *Connection) resyncRecipe(requestID string, recipeID string, current *models.Recipe) {
+	var (
+		msg WSMessage
+		err error
+	)
+	if current != nil {
+		msg, err = recipeUpdates(requestID, current)
+	} else {
+		msg, err = recipeRemovals(requestID, recipeID)
+	}
+	if err != nil {
+		log.Printf("Failed to create recipe resync message: %v", err)
+		return
+	}
+	Pool.BroadcastToUUIDOnlySender(c.UUID, c.ID, msg)
+}
+
+func recipeUpdates(requestID string, recipes ...*models.Recipe) (WSMessage, error) {
+	return NewReply(MessageTypeRecipeUpdates, requestID, RecipeUpdatesPayload{List: recipes})
+}
+
+func recipeRemovals(requestID string, recipeIDs ...string) (WSMessage, error) {
+	return NewReply(MessageTypeRecipeRemovals, requestID, RecipeRemovalsPayload{RecipeIDs: recipeIDs})
+}
@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"testing"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/recipe"
+)
+
+func TestCanonicalizeURL(t *testing.T) {
+	want := "https://example.com/recipes/soup"
+
+	cases := []string{
+		"https://example.com/recipes/soup",
+		"http://example.com/recipes/soup",
+		"https://WWW.Example.com/recipes/soup/",
+		"https://example.com/recipes/soup#recipe",
+		"https://example.com/recipes/soup?utm_source=newsletter&utm_medium=email",
+		"https://example.com/recipes/soup?fbclid=abc123",
+		"https://m.example.com/recipes/soup",
+		"https://example.com/recipes/soup/amp/",
+		"https://example.com/recipes/soup/print",
+		"https://example.com:443/recipes/soup",
+	}
+
+	for _, input := range cases {
+		got, err := recipe.CanonicalizeURL(input)
+		if err != nil {
+			t.Errorf("CanonicalizeURL(%q) failed: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("CanonicalizeURL(%q) = %q, expected %q", input, got, want)
+		}
+	}
+}
+
+func TestCanonicalizeURL_KeepsMeaningfulQuery(t *testing.T) {
+	got, err := recipe.CanonicalizeURL("https://example.com/recipe?utm_campaign=x&id=42&b=2")
+	if err != nil {
+		t.Fatalf("CanonicalizeURL failed: %v", err)
+	}
+
+	if got != "https://example.com/recipe?b=2&id=42" {
+		t.Errorf("Expected sorted meaningful query, got %q", got)
+	}
+}
+
+func TestCanonicalizeURL_RejectsNonHTTP(t *testing.T) {
+	if _, err := recipe.CanonicalizeURL("ftp://example.com/recipe"); err == nil {
+		t.Error("Expected error for ftp URL")
+	}
+}
+
+func TestAddRecipe_FindsDuplicates(t *testing.T) {
+	cake := []string{"200g flour", "200g sugar", "200g butter", "4 eggs"}
+
+	cases := []struct {
+		name        string
+		existing    string
+		ingredients []string
+		duplicate   bool
+	}{
+		{"same dish", "Victoria sponge", cake, true},
+		{"similar name and ingredients", "Classic victoria sponge", []string{"200g flour", "200g sugar", "200g butter", "4 eggs", "jam"}, true},
+		{"same ingredients, half the name", "Easy victoria sponge traybake", cake, true},
+		{"same ingredients, different dish", "Madeira cake", cake, false},
+		{"same name, different ingredients", "Victoria sponge", []string{"1 cauliflower", "100g cheddar"}, false},
+		{"few ingredients, different name", "Fairy cakes", []string{"200g flour", "200g sugar"}, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := recipe.NewRecipeService()
+			first, _ := service.AddRecipe("mix", parsedRecipe("Victoria sponge", cake...))
+
+			stored, duplicate := service.AddRecipe("mix", parsedRecipe(tc.existing, tc.ingredients...))
+			if duplicate != tc.duplicate {
+				t.Fatalf("Expected duplicate=%v for %q, got %v", tc.duplicate, tc.existing, duplicate)
+			}
+			if duplicate && stored.ID != first.ID {
+				t.Errorf("Expected the duplicate to return the stored recipe, got %+v", stored)
+			}
+			if !duplicate && stored.ID == first.ID {
+				t.Errorf("Expected a new recipe, got the stored one")
+			}
+		})
+	}
+}
+
+func parsedRecipe(name string, ingredients ...string) *models.Recipe {
+	r := &models.Recipe{Name: name}
+	for _, line := range ingredients {
+		r.Ingredients = append(r.Ingredients, recipe.ParseIngredient(line))
+	}
+	return r
+}
+
+func TestImportPages_DeclaredURL(t *testing.T) {
+	const saved = "https://example.com/recipes/soup?utm_source=share"
+
+	cases := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"none", ``, saved},
+		{"canonical link", `<link rel="canonical" href="https://example.com/soup">`, "https://example.com/soup"},
+		{"relative canonical link", `<link href="/amp/soup" rel="canonical">`, "https://example.com/amp/soup"},
+		{"og:url", `<meta property="og:url" content="https://example.com/og-soup">`, "https://example.com/og-soup"},
+		{"canonical link wins over og:url", `<meta property="og:url" content="https://example.com/og-soup"><link rel="canonical" href="https://example.com/soup">`, "https://example.com/soup"},
+		{"escaped entities", `<link rel="canonical" href="https://example.com/soup?a=1&amp;b=2">`, "https://example.com/soup?a=1&b=2"},
+		{"non-HTTP scheme", `<link rel="canonical" href="javascript:alert(1)">`, saved},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			page := recipe.SavedPage{
+				Name: "Soup.html",
+				URL:  saved,
+				HTML: `<html><head>` + tc.head + `<script type="application/ld+json">{"@type": "Recipe", "name": "Soup", "recipeIngredient": ["1 onion", "2 carrots"]}</script></head><body></body></html>`,
+			}
+
+			result := recipe.NewRecipeService().ImportPages([]recipe.SavedPage{page}, "mix", "sharer", "Sharer", nil)
+			if len(result.Added) != 1 {
+				t.Fatalf("Expected the page to be added, got %+v", result)
+			}
+			if got := result.Added[0].URL; got != tc.want {
+				t.Errorf("Expected URL %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
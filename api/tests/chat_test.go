@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/chat"
+)
+
+func TestChatService_PaginatesAndPersists(t *testing.T) {
+	config := chat.Config{Dir: t.TempDir(), MaxMessages: 100}
+	service := chat.NewChatService(config)
+	mixID := uuid.New().String()
+
+	for i := 1; i <= 5; i++ {
+		if _, err := service.Add(mixID, models.ChatMessage{Text: fmt.Sprintf("message %d", i)}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if _, err := service.Add(mixID, models.ChatMessage{Text: "   "}); err != chat.ErrEmptyMessage {
+		t.Errorf("Expected ErrEmptyMessage, got %v", err)
+	}
+
+	// A new service reads the same history back from disk
+	reloaded := chat.NewChatService(config)
+
+	latest, hasMore := reloaded.History(mixID, "", 2)
+	if len(latest) != 2 || latest[1].Text != "message 5" || !hasMore {
+		t.Fatalf("Expected the two latest messages with more to load, got %+v (hasMore=%v)", latest, hasMore)
+	}
+	if latest[0].ID == "" || latest[0].SentAt.IsZero() {
+		t.Errorf("Expected server-assigned ID and timestamp, got %+v", latest[0])
+	}
+
+	older, hasMore := reloaded.History(mixID, latest[0].ID, 10)
+	if len(older) != 3 || older[0].Text != "message 1" || hasMore {
+		t.Errorf("Expected the three oldest messages and no more, got %+v (hasMore=%v)", older, hasMore)
+	}
+}
+
+func TestWebSocketChat_HistoryForLateJoiner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "SYNC")
+
+	sendMessage(t, alice, "CHAT_MESSAGE", map[string]any{
+		"type":    "MESSAGE",
+		"payload": map[string]any{"id": "temp-1", "sender": map[string]any{"id": "mallory", "name": "Mallory"}, "text": "hello"},
+	})
+	reply := readMessageOfType(t, alice, "CHAT_MESSAGE")
+	stored := reply["data"].(map[string]any)["payload"].(map[string]any)
+	if stored["id"] == "temp-1" || stored["sender"].(map[string]any)["id"] != "alice" {
+		t.Errorf("Expected server ID and identified sender, got %v", stored)
+	}
+
+	bob := dialMix(t, server.URL, id)
+	defer bob.Close()
+	sendMessage(t, bob, "USER_IDENTIFY", identifyPayload(t, id, "bob", "Bob"))
+
+	history := readMessageOfType(t, bob, "CHAT_HISTORY")
+	messages := history["data"].(map[string]any)["messages"].([]any)
+	if len(messages) != 1 || messages[0].(map[string]any)["id"] != stored["id"] {
+		t.Errorf("Expected the stored message in history, got %v", messages)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v1/mixes/"+id+"/messages?limit=10", nil)
+	req.Header.Set("Authorization", "Bearer "+identityToken(t, "bob", "Bob"))
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var page struct {
+		Messages []models.ChatMessage `json:"messages"`
+		HasMore  bool                 `json:"hasMore"`
+	}
+	if err := json.Unmarshal(resp.Body.Bytes(), &page); err != nil || resp.Code != http.StatusOK {
+		t.Fatalf("Expected chat history page, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if len(page.Messages) != 1 || page.Messages[0].Text != "hello" || page.HasMore {
+		t.Errorf("Expected one message and no more, got %+v", page)
+	}
+}
@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/cookbook"
+	"kitchenmix/api/internal/services/shopping"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+func TestScaleQuantity_KeepsUnreadableQuantities(t *testing.T) {
+	for _, c := range []struct {
+		quantity string
+		factor   float64
+		want     string
+	}{
+		{"1 1/2", 2, "3"},
+		{"2-3", 2, "4-6"},
+		{"½", 0.5, "1/4"},
+		{"3", 1.0 / 3, "1"},
+		{"a pinch", 4, "a pinch"},
+	} {
+		if got := shopping.ScaleQuantity(c.quantity, c.factor); got != c.want {
+			t.Errorf("Expected %q scaled by %v to be %q, got %q", c.quantity, c.factor, c.want, got)
+		}
+	}
+}
+
+func TestGetMixCookbook_RendersPrintableHTML(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	id := createMix(t)
+	pancakes := addRecipe(t, id, "Pancakes", "https://example.com/pancakes")
+	salt, imageSource := "a pinch", "javascript:alert(2)"
+	ws.Recipes.AddRecipe(id, &models.Recipe{
+		Name:        "<Crêpes>",
+		URL:         "javascript:alert(1)",
+		ImageSource: &imageSource,
+		Ingredients: []models.Ingredient{ingredient("flour", "1/2", "cup"), {Name: "salt", Quantity: &salt}},
+	})
+	token := identityToken(t, testOwnerID, "Owner")
+
+	resp, _ := doJSON(router, http.MethodGet, "/api/v1/mixes/"+id+"/cookbook?scale=2", token, "")
+	if resp.Code != http.StatusOK || !strings.HasPrefix(resp.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected an HTML cookbook, got %d: %s", resp.Code, resp.Body.String())
+	}
+	body := resp.Body.String()
+	for _, want := range []string{
+		`<a href="#recipe-` + pancakes.ID + `">Pancakes</a>`,
+		"<li>4 cups flour</li>",
+		"<li>1 cup flour</li>",
+		"<li>a pinch salt</li>",
+		"&lt;Crêpes&gt;",
+		`id="shopping-list"`,
+		"flour: 5 cup",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected the cookbook to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "javascript:alert") {
+		t.Error("Expected unsafe source and image URLs to be neutralised")
+	}
+
+	resp, _ = doJSON(router, http.MethodGet, "/api/v1/mixes/"+id+"/cookbook?shopping=false&recipes="+pancakes.ID, token, "")
+	if body := resp.Body.String(); resp.Code != http.StatusOK || strings.Contains(body, "Crêpes") || strings.Contains(body, `id="shopping-list"`) {
+		t.Errorf("Expected only pancakes without a shopping list, got %d: %s", resp.Code, body)
+	}
+
+	resp, result := doJSON(router, http.MethodGet, "/api/v1/mixes/"+id+"/cookbook?scale=0", token, "")
+	if resp.Code != http.StatusBadRequest || result["error"] != "invalid_scale" {
+		t.Errorf("Expected 400 invalid_scale, got %d: %v", resp.Code, result)
+	}
+}
+
+func TestCookbookRender_TrustsOnlyInlinedImages(t *testing.T) {
+	sources := map[string]string{
+		"inlined": "data:image/png;base64,iVBORw0KGgo=",
+		"linked":  "https://example.com/soup.jpg",
+		"svg":     "data:image/svg+xml,<svg onload=alert(1)>",
+	}
+	var recipes []*models.Recipe
+	for name := range sources {
+		recipes = append(recipes, &models.Recipe{ID: name, Name: name, Ingredients: []models.Ingredient{ingredient("salt", "", "")}})
+	}
+
+	var body strings.Builder
+	err := cookbook.Render(&body, cookbook.Options{
+		Title: "Images",
+		Image: func(recipe *models.Recipe) string { return sources[recipe.Name] },
+	}, recipes)
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	for _, want := range []string{`src="data:image/png;base64,iVBORw0KGgo="`, `src="https://example.com/soup.jpg"`} {
+		if !strings.Contains(body.String(), want) {
+			t.Errorf("Expected the cookbook to contain %s", want)
+		}
+	}
+	if strings.Contains(body.String(), "onload") {
+		t.Error("Expected data: URLs that aren't base64 images to be neutralised")
+	}
+}
@@ -0,0 +1,163 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/exporter"
+	"kitchenmix/api/internal/services/importer"
+	"kitchenmix/api/internal/services/shopping"
+)
+
+func ingredient(name string, quantity string, unit string) models.Ingredient {
+	in := models.Ingredient{Name: name}
+	if quantity != "" {
+		in.Quantity = &quantity
+	}
+	if unit != "" {
+		in.Unit = &unit
+	}
+	return in
+}
+
+func TestShoppingList_SumsIngredientsAcrossRecipes(t *testing.T) {
+	pancakes := &models.Recipe{Name: "Pancakes", Ingredients: []models.Ingredient{
+		ingredient("Flour", "1 1/2", "cups"),
+		ingredient("eggs", "2", ""),
+		ingredient("salt", "a pinch", ""),
+	}}
+	bread := &models.Recipe{Name: "Bread", Ingredients: []models.Ingredient{
+		ingredient("flour", "½", "cup"),
+		ingredient("flour", "100", "g"),
+		ingredient("Eggs", "1-2", ""),
+	}}
+
+	items := shopping.Build([]*models.Recipe{pancakes, bread})
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = item.String()
+	}
+	want := []string{"eggs: 4", "Flour: 2 cup, 100 g", "salt: a pinch"}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q, got %q", want, lines)
+	}
+	if len(items[1].Recipes) != 2 {
+		t.Errorf("Expected flour to be needed by both recipes, got %v", items[1].Recipes)
+	}
+
+	for in, want := range map[float64]string{2: "2", 0.5: "1/2", 1.0 / 3: "1/3", 2.25: "2 1/4", 1.1: "1.1"} {
+		if got := shopping.FormatQuantity(in); got != want {
+			t.Errorf("Expected %v to format as %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestExport_RoundTripsThroughImporter(t *testing.T) {
+	image := "https://example.com/pancakes.jpg"
+	recipes := []*models.Recipe{
+		{ID: "1", Name: "Pancakes", URL: "https://example.com/pancakes", ImageSource: &image, Ingredients: []models.Ingredient{
+			ingredient("flour", "2", "cups"), ingredient("eggs", "2", ""),
+		}},
+		{ID: "2", Name: "Pancakes", Ingredients: []models.Ingredient{ingredient("fine sea salt", "", "")}},
+	}
+
+	for format, filename := range map[string]string{"jsonld": "pancakes.jsonld", "paprika": "pancakes.paprikarecipes"} {
+		var buf bytes.Buffer
+		if err := exporter.Export(&buf, format, "Pancakes", recipes); err != nil {
+			t.Fatalf("Failed to export %s: %v", format, err)
+		}
+		entries, err := importer.Read(filename, buf.Bytes())
+		if err != nil || len(entries) != 2 || entries[0].Recipe == nil || entries[1].Recipe == nil {
+			t.Fatalf("Expected both recipes back from %s, got %+v (%v)", format, entries, err)
+		}
+		first := entries[0].Recipe
+		if first.URL != recipes[0].URL || len(first.Ingredients) != 2 || *first.Ingredients[0].Quantity != "2" || *first.Ingredients[0].Unit != "cups" {
+			t.Errorf("Expected %s to keep the source and ingredients, got %+v", format, first)
+		}
+	}
+
+	var buf bytes.Buffer
+	exporter.Export(&buf, "csv", "Pancakes", recipes)
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 || lines[1] != "Pancakes,https://example.com/pancakes,flour,2,cups," {
+		t.Errorf("Expected a header and three ingredient rows, got %q", lines)
+	}
+
+	if err := exporter.Export(&buf, "pdf", "Pancakes", recipes); err != exporter.ErrUnknownFormat {
+		t.Errorf("Expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestExport_EscapesScrapedText(t *testing.T) {
+	recipes := []*models.Recipe{
+		{ID: "1", Name: "Pancakes\n# Injected heading", URL: "https://example.com/pancakes", Ingredients: []models.Ingredient{
+			ingredient("=HYPERLINK(\"https://evil.example\")", "", ""), ingredient("[flour](javascript:alert(1))", "2", "cups"),
+		}},
+	}
+
+	var buf bytes.Buffer
+	exporter.Export(&buf, "markdown", "Brunch", recipes)
+	markdown := buf.String()
+	if !strings.Contains(markdown, "## Pancakes \\# Injected heading\n") || strings.Contains(markdown, "\n# Injected") {
+		t.Errorf("Expected the name to stay on its heading line, got %q", markdown)
+	}
+	if !strings.Contains(markdown, "\\[flour\\](javascript:alert(1))") {
+		t.Errorf("Expected ingredients not to become links, got %q", markdown)
+	}
+
+	buf.Reset()
+	exporter.Export(&buf, "csv", "Brunch", recipes)
+	rows, _ := csv.NewReader(&buf).ReadAll()
+	if len(rows) != 3 || rows[1][2] != `'=HYPERLINK("https://evil.example")` {
+		t.Errorf("Expected formulas to be neutralised, got %q", rows)
+	}
+}
+
+func TestExportMix_DownloadsSelectedRecipes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	id := createMix(t)
+	pancakes := addRecipe(t, id, "Pancakes", "https://example.com/pancakes")
+	addRecipe(t, id, "Waffles", "https://example.com/waffles")
+	token := identityToken(t, testOwnerID, "Owner")
+
+	resp, _ := doJSON(router, http.MethodGet, "/api/v1/mixes/"+id+"/export?format=markdown", token, "")
+	if resp.Code != http.StatusOK || !strings.HasPrefix(resp.Header().Get("Content-Type"), "text/markdown") {
+		t.Fatalf("Expected a Markdown export, got %d: %s", resp.Code, resp.Body.String())
+	}
+	if !strings.Contains(resp.Header().Get("Content-Disposition"), ".md") {
+		t.Errorf("Expected a Markdown attachment, got %q", resp.Header().Get("Content-Disposition"))
+	}
+	body := resp.Body.String()
+	if !strings.Contains(body, "## Pancakes") || !strings.Contains(body, "- 2 cups flour") || strings.Index(body, "## Pancakes") > strings.Index(body, "## Waffles") {
+		t.Errorf("Expected both recipes oldest first, got %s", body)
+	}
+
+	resp, _ = doJSON(router, http.MethodGet, "/api/v1/mixes/"+id+"/export?format=shopping-list&recipes="+pancakes.ID, token, "")
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), "[ ] flour: 2 cup\n") {
+		t.Errorf("Expected a shopping list for pancakes only, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	resp, result := doJSON(router, http.MethodGet, "/api/v1/mixes/"+id+"/export?format=pdf", token, "")
+	if resp.Code != http.StatusBadRequest || result["error"] != "invalid_format" {
+		t.Errorf("Expected 400 invalid_format, got %d: %v", resp.Code, result)
+	}
+
+	resp, result = doJSON(router, http.MethodGet, "/api/v1/mixes/"+id+"/export?format=csv&recipes=missing", token, "")
+	if resp.Code != http.StatusNotFound || result["error"] != "recipe_not_found" {
+		t.Errorf("Expected 404 recipe_not_found, got %d: %v", resp.Code, result)
+	}
+
+	resp, _ = doJSON(router, http.MethodGet, "/api/v1/mixes/"+id+"/export?format=csv", identityToken(t, "stranger", "Stranger"), "")
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for non-members, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"kitchenmix/api/internal/services/recipe"
+)
+
+func testFetcherConfig() recipe.FetcherConfig {
+	cfg := recipe.DefaultFetcherConfig()
+	cfg.BaseBackoff = time.Millisecond
+	cfg.MaxBackoff = 10 * time.Millisecond
+	return cfg
+}
+
+func TestFetcher_GzipAndCharset(t *testing.T) {
+	// "Crème brûlée" encoded as ISO-8859-1
+	latin1 := []byte("<html><body>Cr\xe8me br\xfbl\xe9e</body></html>")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("User-Agent"), "KitchenMix") {
+			t.Errorf("Expected KitchenMix user agent, got '%s'", r.Header.Get("User-Agent"))
+		}
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		gz.Write(latin1)
+		gz.Close()
+
+		w.Header().Set("Content-Type", "text/html; charset=ISO-8859-1")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	body, err := recipe.NewFetcher(testFetcherConfig()).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if !strings.Contains(body, "Crème brûlée") {
+		t.Errorf("Expected UTF-8 transcoded body, got '%s'", body)
+	}
+}
+
+func TestFetcher_MaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bytes.Repeat([]byte("a"), 2048))
+	}))
+	defer server.Close()
+
+	cfg := testFetcherConfig()
+	cfg.MaxBodyBytes = 1024
+
+	_, err := recipe.NewFetcher(cfg).Fetch(context.Background(), server.URL)
+	if !errors.Is(err, recipe.ErrBodyTooLarge) {
+		t.Errorf("Expected ErrBodyTooLarge, got %v", err)
+	}
+}
+
+func TestFetcher_RetriesOnServiceUnavailable(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	body, err := recipe.NewFetcher(testFetcherConfig()).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	if body != "ok" || attempts != 3 {
+		t.Errorf("Expected 'ok' after 3 attempts, got '%s' after %d", body, attempts)
+	}
+}
+
+func TestFetcher_ConditionalRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("original"))
+	}))
+	defer server.Close()
+
+	fetcher := recipe.NewFetcher(testFetcherConfig())
+	if _, err := fetcher.Fetch(context.Background(), server.URL); err != nil {
+		t.Fatalf("First fetch failed: %v", err)
+	}
+
+	body, err := fetcher.Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Second fetch failed: %v", err)
+	}
+
+	if body != "original" {
+		t.Errorf("Expected cached body on 304, got '%s'", body)
+	}
+}
+
+func TestFetcher_ConditionalRequestCacheIsBounded(t *testing.T) {
+	conditional := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("original"))
+	}))
+	defer server.Close()
+
+	cfg := testFetcherConfig()
+	cfg.MaxCachedBytes = 4
+	fetcher := recipe.NewFetcher(cfg)
+	for range 2 {
+		if body, err := fetcher.Fetch(context.Background(), server.URL); err != nil || body != "original" {
+			t.Fatalf("Expected the page, got %q: %v", body, err)
+		}
+	}
+	if conditional != 0 {
+		t.Errorf("Expected a body over the cache budget not to be revalidated, got %d conditional requests", conditional)
+	}
+}
@@ -0,0 +1,307 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/grocerylist"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+func TestGroceryList_SyncsChecksAndEdits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	bob := dialMix(t, server.URL, id)
+	defer bob.Close()
+	sendMessage(t, bob, "USER_IDENTIFY", identifyPayload(t, id, "bob", "Bob"))
+	readMessageOfType(t, bob, "PRESENCE_STATE")
+
+	sendMessage(t, alice, "GROCERY_ITEM_ADD", map[string]any{"name": " Milk ", "quantity": "2", "unit": "l", "assigneeId": "bob"})
+	added := readMessageOfType(t, bob, "GROCERY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	if added["name"] != "Milk" || added["quantity"] != "2" || added["assigneeName"] != "Bob" || added["addedByName"] != "Alice" || added["version"] != float64(1) {
+		t.Fatalf("Expected 2 l of milk for Bob to buy, got %v", added)
+	}
+	itemID := added["id"].(string)
+	readMessageOfType(t, alice, "GROCERY_ITEM_UPDATES")
+
+	sendMessage(t, bob, "GROCERY_ITEM_CHECK", map[string]any{"itemId": itemID, "checked": true})
+	checked := readMessageOfType(t, alice, "GROCERY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	if checked["checked"] != true || checked["checkedByName"] != "Bob" || checked["version"] != float64(2) {
+		t.Errorf("Expected Bob to have ticked the milk off, got %v", checked)
+	}
+	readMessageOfType(t, bob, "GROCERY_ITEM_UPDATES")
+
+	// Alice's edit is based on the version before Bob ticked the milk off
+	sendMessage(t, alice, "GROCERY_ITEM_UPDATE", map[string]any{"itemId": itemID, "version": 1, "quantity": "3"})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "VERSION_CONFLICT" {
+		t.Errorf("Expected VERSION_CONFLICT, got %v", code)
+	}
+	if current := readMessageOfType(t, alice, "GROCERY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any); current["version"] != float64(2) {
+		t.Errorf("Expected the current item to be resent, got %v", current)
+	}
+
+	sendMessage(t, alice, "GROCERY_ITEM_UPDATE", map[string]any{"itemId": itemID, "version": 2, "quantity": "3", "unit": "", "assigneeId": ""})
+	updated := readMessageOfType(t, bob, "GROCERY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	if updated["quantity"] != "3" || updated["unit"] != nil || updated["assigneeId"] != nil || updated["name"] != "Milk" {
+		t.Errorf("Expected 3 milk for nobody in particular, got %v", updated)
+	}
+
+	sendMessage(t, alice, "GROCERY_ITEM_UPDATE", map[string]any{"itemId": itemID, "version": 3, "assigneeId": "stranger"})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "INVALID_PAYLOAD" {
+		t.Errorf("Expected INVALID_PAYLOAD for an assignee outside the mix, got %v", code)
+	}
+
+	sendMessage(t, alice, "GROCERY_ITEM_ADD", map[string]any{"name": "eggs"})
+	eggsID := readMessageOfType(t, bob, "GROCERY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)["id"].(string)
+
+	sendMessage(t, bob, "GROCERY_LIST_CLEAR_CHECKED", map[string]any{})
+	if ids := readMessageOfType(t, alice, "GROCERY_ITEM_REMOVALS")["data"].(map[string]any)["itemIds"].([]any); len(ids) != 1 || ids[0] != itemID {
+		t.Errorf("Expected only the checked milk to be cleared, got %v", ids)
+	}
+	readMessageOfType(t, bob, "GROCERY_ITEM_REMOVALS")
+
+	sendMessage(t, alice, "GROCERY_ITEM_REMOVE", map[string]any{"itemId": eggsID})
+	if ids := readMessageOfType(t, bob, "GROCERY_ITEM_REMOVALS")["data"].(map[string]any)["itemIds"].([]any); len(ids) != 1 || ids[0] != eggsID {
+		t.Errorf("Expected the eggs to be removed, got %v", ids)
+	}
+	sendMessage(t, alice, "GROCERY_ITEM_CHECK", map[string]any{"itemId": eggsID, "checked": true})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "GROCERY_ITEM_NOT_FOUND" {
+		t.Errorf("Expected GROCERY_ITEM_NOT_FOUND, got %v", code)
+	}
+}
+
+func TestMixGroceryList_REST(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	id := createMix(t)
+	owner := identityToken(t, testOwnerID, "Owner")
+	path := "/api/v1/mixes/" + id + "/grocery-list"
+
+	resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "flour", "quantity": "1", "unit": "kg", "assigneeId": "`+testOwnerID+`"}`)
+	if resp.Code != http.StatusCreated || result["assigneeName"] != "Owner" || result["checked"] != false {
+		t.Fatalf("Expected flour to be added, got %d: %v", resp.Code, result)
+	}
+	itemPath := path + "/" + result["id"].(string)
+
+	if resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "   "}`); resp.Code != http.StatusBadRequest || result["error"] != "invalid_grocery_item" {
+		t.Errorf("Expected 400 for a blank name, got %d: %v", resp.Code, result)
+	}
+	if resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "sugar", "assigneeId": "stranger"}`); resp.Code != http.StatusBadRequest || result["error"] != "invalid_assignee" {
+		t.Errorf("Expected 400 for an assignee outside the mix, got %d: %v", resp.Code, result)
+	}
+
+	if resp, result := doJSON(router, http.MethodPut, itemPath+"/checked", owner, `{"checked": true}`); resp.Code != http.StatusOK || result["checked"] != true {
+		t.Errorf("Expected flour to be checked, got %d: %v", resp.Code, result)
+	}
+	resp, result = doJSON(router, http.MethodPatch, itemPath, owner, `{"version": 1, "quantity": "2"}`)
+	if current, _ := result["item"].(map[string]any); resp.Code != http.StatusConflict || current["version"] != float64(2) {
+		t.Errorf("Expected 409 with the current item, got %d: %v", resp.Code, result)
+	}
+	if resp, result := doJSON(router, http.MethodPatch, itemPath, owner, `{"version": 2, "quantity": "2"}`); resp.Code != http.StatusOK || result["quantity"] != "2" || result["unit"] != "kg" {
+		t.Errorf("Expected 2 kg of flour, got %d: %v", resp.Code, result)
+	}
+
+	_, result = doJSON(router, http.MethodGet, path, owner, "")
+	if items := result["items"].([]any); len(items) != 1 {
+		t.Errorf("Expected one item, got %v", items)
+	}
+	if resp, result := doJSON(router, http.MethodPost, path+"/clear-checked", owner, ""); resp.Code != http.StatusOK || len(result["itemIds"].([]any)) != 1 {
+		t.Errorf("Expected the checked flour to be cleared, got %d: %v", resp.Code, result)
+	}
+	if resp, _ := doJSON(router, http.MethodDelete, itemPath, owner, ""); resp.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a cleared item, got %d", resp.Code)
+	}
+
+	stranger := identityToken(t, "stranger", "Stranger")
+	if resp, _ := doJSON(router, http.MethodGet, path, stranger, ""); resp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for non-members, got %d", resp.Code)
+	}
+}
+
+func TestGroceryList_BroadcastsInterleavedEditsInOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	milk, err := ws.AddGroceryItem(id, models.GroceryListItem{Name: "Milk", AddedByID: testOwnerID, AddedByName: "Owner"}, "")
+	if err != nil {
+		t.Fatalf("Failed to add milk: %v", err)
+	}
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	// Several shoppers keep retrying against the latest version, so their
+	// edits interleave
+	const editors, editsEach = 8, 20
+	var wg sync.WaitGroup
+	for editor := range editors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for edit := range editsEach {
+				quantity := fmt.Sprintf("%d.%d", editor, edit)
+				for {
+					current := ws.GroceryLists.Items(id)[0]
+					if _, err := ws.UpdateGroceryItem(id, milk.ID, current.Version, grocerylist.ItemUpdate{Quantity: &quantity}, ""); err == nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	last := float64(milk.Version)
+	for range editors * editsEach {
+		updated := readMessageOfType(t, alice, "GROCERY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+		if version := updated["version"].(float64); version != last+1 {
+			t.Fatalf("Expected version %v next, got %v", last+1, version)
+		}
+		last++
+	}
+}
+
+func TestGroceryList_FillsFromPlanShoppingList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	eggs := ingredient("eggs", "3", "")
+	eggs.GroceryItem = &models.GroceryItem{ID: "eggs", Name: "Eggs", Category: "Dairy"}
+	pancakes, duplicate := ws.Recipes.AddRecipe(id, &models.Recipe{Name: "Pancakes", Ingredients: []models.Ingredient{
+		ingredient("flour", "2", "cups"),
+		ingredient("Flour", "100", "g"),
+		eggs,
+		ingredient("salt", "a pinch", ""),
+		ingredient("milk", "1", "cup"),
+		ingredient(strings.Repeat("very ", 30)+"fine sugar", "1", "tbsp"),
+	}})
+	if duplicate {
+		t.Fatal("Expected pancakes to be added")
+	}
+	owner := identityToken(t, testOwnerID, "Owner")
+	path := "/api/v1/mixes/" + id + "/grocery-list"
+
+	body := `{"recipeId": "` + pancakes.ID + `", "date": "2025-03-01", "meal": "breakfast"}`
+	if resp, result := doJSON(router, http.MethodPost, "/api/v1/mixes/"+id+"/plan", owner, body); resp.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %v", resp.Code, result)
+	}
+	if resp, result := doJSON(router, http.MethodPost, "/api/v1/mixes/"+id+"/pantry", owner, `{"name": "salt"}`); resp.Code != http.StatusCreated {
+		t.Fatalf("Expected salt to be stocked, got %d: %v", resp.Code, result)
+	}
+	if resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "Milk"}`); resp.Code != http.StatusCreated {
+		t.Fatalf("Expected milk to be listed, got %d: %v", resp.Code, result)
+	}
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	// Salt is in the pantry and milk already listed; flour is bought in both
+	// amounts it is needed in
+	resp, result := doJSON(router, http.MethodPost, path+"/from-plan?from=2025-03-01", owner, "")
+	if resp.Code != http.StatusCreated || result["to"] != "2025-03-07" {
+		t.Fatalf("Expected the week's plan to be listed, got %d: %v", resp.Code, result)
+	}
+	// The sugar's name is too long for the list; the rest are still added
+	skipped := result["skipped"].([]any)
+	if len(skipped) != 2 || skipped[0].(map[string]any)["name"] != "milk" || skipped[0].(map[string]any)["reason"] != "already on the list" {
+		t.Fatalf("Expected milk to be skipped, got %v", skipped)
+	}
+	if sugar := skipped[1].(map[string]any); !strings.HasSuffix(sugar["name"].(string), "fine sugar") || sugar["reason"] != grocerylist.ErrInvalidName.Error() {
+		t.Errorf("Expected the sugar to be skipped for its name, got %v", sugar)
+	}
+	var lines []string
+	for _, item := range result["items"].([]any) {
+		item := item.(map[string]any)
+		lines = append(lines, fmt.Sprintf("%v %v %v by %v", item["name"], item["quantity"], item["unit"], item["addedByName"]))
+	}
+	if want := []string{"flour 2 cup by Owner", "flour 100 g by Owner", "eggs 3 <nil> by Owner"}; strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q to be listed, got %q", want, lines)
+	}
+	if grocery, _ := result["items"].([]any)[2].(map[string]any)["groceryItem"].(map[string]any); grocery["category"] != "Dairy" {
+		t.Errorf("Expected eggs to keep their grocery item, got %v", result["items"])
+	}
+
+	if updates := readMessageOfType(t, alice, "GROCERY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any); len(updates) != 3 {
+		t.Errorf("Expected the three items in one update, got %v", updates)
+	}
+
+	// Filling again adds nothing that is still to buy
+	if resp, result := doJSON(router, http.MethodPost, path+"/from-plan?from=2025-03-01", owner, ""); resp.Code != http.StatusCreated || len(result["items"].([]any)) != 0 || len(result["skipped"].([]any)) != 4 {
+		t.Errorf("Expected everything to be skipped, got %d: %v", resp.Code, result)
+	}
+
+	// Once they are bought, filling twice at once lists them again only once
+	for _, item := range result["items"].([]any) {
+		if _, err := ws.CheckGroceryItem(id, item.(map[string]any)["id"].(string), true, testOwnerID, "Owner", ""); err != nil {
+			t.Fatalf("Failed to tick off %v: %v", item, err)
+		}
+	}
+	ws.ClearCheckedGroceryItems(id, "")
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	added := 0
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, result := doJSON(router, http.MethodPost, path+"/from-plan?from=2025-03-01", owner, "")
+			if resp.Code != http.StatusCreated {
+				t.Errorf("Expected 201, got %d: %v", resp.Code, result)
+				return
+			}
+			mu.Lock()
+			added += len(result["items"].([]any))
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if items := ws.GroceryLists.Items(id); added != 3 || len(items) != 4 {
+		t.Errorf("Expected milk and the three items to be listed once, got %d added: %v", added, items)
+	}
+
+	if resp, _ := doJSON(router, http.MethodPost, path+"/from-plan?from=March", owner, ""); resp.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid date, got %d", resp.Code)
+	}
+	if resp, _ := doJSON(router, http.MethodPost, path+"/from-plan", identityToken(t, "stranger", "Stranger"), ""); resp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for non-members, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/identity"
+)
+
+func TestIdentityIssuer_VerifiesSignedTokens(t *testing.T) {
+	issuer := identity.NewIssuer(identity.Config{Secret: []byte("secret"), TTL: time.Hour})
+
+	minted, token, err := issuer.MintGuest("  Ada   Lovelace ")
+	if err != nil {
+		t.Fatalf("MintGuest failed: %v", err)
+	}
+	if minted.UserName != "Ada Lovelace" {
+		t.Errorf("Expected whitespace to be collapsed, got %q", minted.UserName)
+	}
+
+	verified, err := issuer.Verify(token)
+	if err != nil || verified.UserID != minted.UserID || verified.UserName != "Ada Lovelace" {
+		t.Errorf("Expected token to verify as %+v, got %+v (%v)", minted, verified, err)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+	_, forged, _ := identity.NewIssuer(identity.Config{Secret: []byte("other"), TTL: time.Hour}).Mint(minted.UserID, "Mallory")
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	for _, bad := range []string{forgedPayload + "." + signature, payload, payload + ".AAAA", forged} {
+		if _, err := issuer.Verify(bad); err != identity.ErrInvalidToken {
+			t.Errorf("Expected ErrInvalidToken for %q, got %v", bad, err)
+		}
+	}
+
+	expired := identity.NewIssuer(identity.Config{Secret: []byte("secret"), TTL: -time.Minute})
+	_, oldToken, _ := expired.MintGuest("Ada")
+	if _, err := issuer.Verify(oldToken); err != identity.ErrExpiredToken {
+		t.Errorf("Expected ErrExpiredToken, got %v", err)
+	}
+
+	for _, name := range []string{"", "   ", strings.Repeat("a", 33), "<script>", "pizza 🍕"} {
+		if _, err := identity.ValidateName(name); err != identity.ErrInvalidName {
+			t.Errorf("Expected ErrInvalidName for %q, got %v", name, err)
+		}
+	}
+}
+
+func TestMintGuestIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	mint := func(body string) (*httptest.ResponseRecorder, map[string]any) {
+		req, _ := http.NewRequest("POST", "/api/v1/identity/guest", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		var result map[string]any
+		json.Unmarshal(resp.Body.Bytes(), &result)
+		return resp, result
+	}
+
+	resp, guest := mint(`{"name": "Ada"}`)
+	if resp.Code != http.StatusCreated || guest["token"] == "" || guest["userName"] != "Ada" {
+		t.Fatalf("Expected a guest identity, got %d: %v", resp.Code, guest)
+	}
+
+	resp, renamed := mint(`{"name": "Ada L", "token": "` + guest["token"].(string) + `"}`)
+	if resp.Code != http.StatusCreated || renamed["userId"] != guest["userId"] || renamed["userName"] != "Ada L" {
+		t.Errorf("Expected rename to keep the user ID, got %d: %v", resp.Code, renamed)
+	}
+
+	if resp, _ := mint(`{"name": "<b>"}`); resp.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid name, got %d", resp.Code)
+	}
+	if resp, _ := mint(`{"name": "Ada", "token": "forged.token"}`); resp.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a forged token, got %d", resp.Code)
+	}
+}
+
+func TestWebSocketIdentify_RejectsSpoofingAndDuplicateNames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	mallory := dialMix(t, server.URL, id)
+	defer mallory.Close()
+
+	expectError := func(payload map[string]any, code string) {
+		t.Helper()
+		sendMessage(t, mallory, "USER_IDENTIFY", payload)
+		msg := readMessageOfType(t, mallory, "ERROR")
+		if got := msg["data"].(map[string]any)["code"]; got != code {
+			t.Errorf("Expected %s, got %v", code, got)
+		}
+	}
+
+	expectError(map[string]any{"userId": "alice", "userName": "Alice"}, "INVALID_IDENTITY")
+
+	spoofed := identifyPayload(t, id, "mallory", "Mallory")
+	spoofed["userId"] = "alice"
+	expectError(spoofed, "INVALID_IDENTITY")
+
+	expectError(identifyPayload(t, id, "mallory", "alice"), "NAME_TAKEN")
+}
@@ -0,0 +1,244 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/images"
+)
+
+// encodeTestPNG returns a noisy PNG so it isn't compressed below the minimum image size
+func encodeTestPNG(width, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{uint8(rand.IntN(256)), uint8(rand.IntN(256)), 0, 255})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestImageService_SkipsTrackingPixel(t *testing.T) {
+	photo := encodeTestPNG(400, 300)
+	pixel := encodeTestPNG(1, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Referer") != "https://example.com/recipe" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		switch r.URL.Path {
+		case "/pixel.png":
+			w.Write(pixel)
+		case "/photo.png":
+			w.Write(photo)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	service := images.NewImageService(images.Config{
+		Dir:            t.TempDir(),
+		MaxBytes:       10 * 1024 * 1024,
+		ThumbnailWidth: 100,
+		Timeout:        5 * time.Second,
+		// The test server listens on loopback
+		AllowPrivateAddresses: true,
+	})
+
+	stored, err := service.Store(context.Background(), "https://example.com/recipe", []string{
+		server.URL + "/missing.png",
+		server.URL + "/pixel.png",
+		server.URL + "/photo.png",
+	})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	if stored.SourceURL != server.URL+"/photo.png" || stored.Width != 400 {
+		t.Errorf("Expected the 400px photo to be stored, got %+v", stored)
+	}
+
+	file, contentType, err := service.Open(stored.ID, true)
+	if err != nil {
+		t.Fatalf("Open thumbnail failed: %v", err)
+	}
+	defer file.Close()
+
+	data, _ := io.ReadAll(file)
+	thumb, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || contentType != "image/jpeg" || thumb.Width != 100 || thumb.Height != 75 {
+		t.Errorf("Expected 100x75 JPEG thumbnail, got %+v (%s, %v)", thumb, contentType, err)
+	}
+}
+
+func TestImageService_RejectsPrivateAddresses(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(encodeTestPNG(400, 300))
+	}))
+	defer server.Close()
+
+	service := images.NewImageService(images.Config{
+		Dir:            t.TempDir(),
+		MaxBytes:       10 * 1024 * 1024,
+		ThumbnailWidth: 100,
+		Timeout:        5 * time.Second,
+	})
+
+	_, err := service.Store(context.Background(), "https://example.com/recipe", []string{
+		server.URL + "/photo.png",
+		"http://169.254.169.254/latest/meta-data/photo.png",
+	})
+	if err != images.ErrNoValidImage || requests != 0 {
+		t.Errorf("Expected loopback and link-local images to be refused unrequested, got %v after %d requests", err, requests)
+	}
+}
+
+func TestImageService_RejectsOversizedDimensions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(encodeTestPNG(400, 300))
+	}))
+	defer server.Close()
+
+	service := images.NewImageService(images.Config{
+		Dir:                   t.TempDir(),
+		MaxBytes:              10 * 1024 * 1024,
+		MaxPixels:             400*300 - 1,
+		ThumbnailWidth:        100,
+		Timeout:               5 * time.Second,
+		AllowPrivateAddresses: true,
+	})
+
+	if _, err := service.Store(context.Background(), "https://example.com/recipe", []string{server.URL + "/photo.png"}); err != images.ErrNoValidImage {
+		t.Errorf("Expected an image over the pixel limit to be rejected, got %v", err)
+	}
+}
+
+type countingLimiter struct{ acquired []string }
+
+func (l *countingLimiter) Acquire(ctx context.Context, targetURL string) (func(), error) {
+	l.acquired = append(l.acquired, targetURL)
+	return func() {}, nil
+}
+
+func TestImageService_AsksLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(encodeTestPNG(400, 300))
+	}))
+	defer server.Close()
+
+	limiter := &countingLimiter{}
+	service := images.NewImageService(images.Config{
+		Dir:                   t.TempDir(),
+		MaxBytes:              10 * 1024 * 1024,
+		ThumbnailWidth:        100,
+		Timeout:               5 * time.Second,
+		Limiter:               limiter,
+		AllowPrivateAddresses: true,
+	})
+
+	if _, err := service.Store(context.Background(), "https://example.com/recipe", []string{server.URL + "/photo.png"}); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if len(limiter.acquired) != 2 {
+		t.Errorf("Expected the HEAD and GET requests to be paced, got %v", limiter.acquired)
+	}
+}
+
+func TestGetImage_NotFound(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	for _, id := range []string{"..%2F..%2Fetc%2Fpasswd", "0123456789abcdef0123456789abcdef"} {
+		req, _ := http.NewRequest("GET", "/api/v1/images/"+id, nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		if resp.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for %s, got %d", id, resp.Code)
+		}
+	}
+}
+
+func TestImageService_StoresDecodedFormat(t *testing.T) {
+	photo := encodeTestPNG(400, 300)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/svg+xml; charset=utf-8")
+		w.Write(photo)
+	}))
+	defer server.Close()
+
+	service := images.NewImageService(images.Config{
+		Dir:                   t.TempDir(),
+		MaxBytes:              10 * 1024 * 1024,
+		ThumbnailWidth:        100,
+		Timeout:               5 * time.Second,
+		AllowPrivateAddresses: true,
+	})
+
+	stored, err := service.Store(context.Background(), "https://example.com/recipe", []string{server.URL + "/photo.svg"})
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	file, contentType, err := service.Open(stored.ID, false)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	file.Close()
+	if stored.ContentType != "image/png" || contentType != "image/png" {
+		t.Errorf("Expected the PNG to be served as image/png, got %q and %q", stored.ContentType, contentType)
+	}
+}
+
+func TestGetImage_ServesDecodedFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	// An image stored with the type its server claimed, as before the
+	// decoded format was recorded
+	photo := encodeTestPNG(400, 300)
+	id := "0123456789abcdef0123456789abcde0"
+	dir := os.Getenv("IMAGE_DIR")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("Failed to create image directory: %v", err)
+	}
+	os.WriteFile(filepath.Join(dir, id), photo, 0o644)
+	os.WriteFile(filepath.Join(dir, id+".type"), []byte("image/svg+xml"), 0o644)
+
+	req, _ := http.NewRequest("GET", "/api/v1/images/"+id, nil)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK || resp.Header().Get("Content-Type") != "image/png" || !bytes.Equal(resp.Body.Bytes(), photo) {
+		t.Errorf("Expected the PNG served as image/png, got %d %q", resp.Code, resp.Header().Get("Content-Type"))
+	}
+	if resp.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("Expected browsers not to sniff images, got %v", resp.Header())
+	}
+}
@@ -0,0 +1,250 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/importer"
+	"kitchenmix/api/internal/services/recipe"
+)
+
+// paprikaRecipe is a single .paprikarecipe file: gzipped JSON
+func paprikaRecipe(t *testing.T, recipe map[string]any) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	json.NewEncoder(gz).Encode(recipe)
+	gz.Close()
+	return buf.String()
+}
+
+const mealieRecipe = `{
+	"name": "Shakshuka",
+	"slug": "shakshuka",
+	"orgURL": "https://example.com/shakshuka",
+	"recipeIngredient": [
+		{"quantity": 4.0, "unit": null, "food": {"name": "eggs"}, "note": "", "title": null},
+		{"quantity": 0.5, "unit": {"name": "teaspoon"}, "food": {"name": "cumin"}, "note": ""},
+		{"quantity": 0, "unit": null, "food": null, "note": "2 cans chopped tomatoes", "title": "Sauce"},
+		"1 onion"
+	]
+}`
+
+const tandoorRecipe = `{
+	"name": "Dal",
+	"source_url": "",
+	"steps": [
+		{"instruction": "Rinse", "ingredients": [
+			{"food": {"name": "red lentils"}, "unit": {"name": "g"}, "amount": "250.000", "is_header": false, "no_amount": false},
+			{"food": null, "unit": null, "amount": "0", "note": "Tempering", "is_header": true}
+		]},
+		{"instruction": "Temper", "ingredients": [
+			{"food": {"name": "salt"}, "unit": null, "amount": "0", "is_header": false, "no_amount": true}
+		]}
+	]
+}`
+
+const jsonLDRecipes = `{"@context": "https://schema.org", "@graph": [
+	{"@type": "WebPage", "name": "Soups"},
+	{"@type": "Recipe", "name": "Minestrone", "url": "https://example.com/minestrone", "recipeIngredient": ["1 can beans", "100g pasta"]},
+	{"@type": ["Recipe"], "name": "Broth"}
+]}`
+
+func TestImporter_ReadsExportFormats(t *testing.T) {
+	paprika := zipPages(t, map[string]string{
+		"Pancakes.paprikarecipe": paprikaRecipe(t, map[string]any{
+			"uid":         "ABC-123",
+			"name":        "Pancakes",
+			"ingredients": "2 cups flour\n\n1 cup milk\n2 eggs",
+			"source_url":  "https://example.com/pancakes",
+		}),
+	})
+	entries, err := importer.Read("My Recipes.paprikarecipes", paprika)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected one Paprika recipe, got %+v (%v)", entries, err)
+	}
+	if pancakes := entries[0].Recipe; pancakes.Name != "Pancakes" || pancakes.URL != "https://example.com/pancakes" || len(pancakes.Ingredients) != 3 {
+		t.Errorf("Expected pancakes with three ingredients, got %+v", pancakes)
+	}
+
+	entries, err = importer.Read("shakshuka.json", []byte(mealieRecipe))
+	if err != nil || len(entries) != 1 || entries[0].Recipe == nil {
+		t.Fatalf("Expected one Mealie recipe, got %+v (%v)", entries, err)
+	}
+	ingredients := entries[0].Recipe.Ingredients
+	if len(ingredients) != 4 || ingredients[0].Name != "eggs" || *ingredients[0].Quantity != "4" || ingredients[0].Unit != nil {
+		t.Fatalf("Expected four Mealie ingredients starting with 4 eggs, got %+v", ingredients)
+	}
+	if *ingredients[1].Quantity != "0.5" || *ingredients[1].Unit != "teaspoon" || ingredients[2].Name != "chopped tomatoes" {
+		t.Errorf("Expected parsed units and notes, got %+v %+v", ingredients[1], ingredients[2])
+	}
+
+	// Tandoor nests one zip per recipe in its export
+	tandoor := zipPages(t, map[string]string{
+		"1.zip": string(zipPages(t, map[string]string{"recipe.json": tandoorRecipe, "image.jpg": "not json"})),
+	})
+	entries, err = importer.Read("export.zip", tandoor)
+	if err != nil || len(entries) != 1 || entries[0].Recipe == nil {
+		t.Fatalf("Expected one Tandoor recipe, got %+v (%v)", entries, err)
+	}
+	if dal := entries[0].Recipe.Ingredients; len(dal) != 2 || *dal[0].Quantity != "250" || dal[1].Name != "salt" || dal[1].Quantity != nil {
+		t.Errorf("Expected lentils and salt without headers, got %+v", dal)
+	}
+
+	entries, err = importer.Read("soups.jsonld", []byte(jsonLDRecipes))
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Expected two JSON-LD recipes, got %+v (%v)", entries, err)
+	}
+	if entries[0].Recipe.Name != "Minestrone" || entries[1].Err == nil {
+		t.Errorf("Expected minestrone and a broth without ingredients, got %+v", entries)
+	}
+
+	overlong := `{"@type": "Recipe", "name": "` + strings.Repeat("a", recipe.MaxRecipeNameLength+1) + `", "recipeIngredient": ["1 egg"]}`
+	entries, err = importer.Read("long.jsonld", []byte(overlong))
+	if err != nil || len(entries) != 1 || !errors.Is(entries[0].Err, recipe.ErrInvalidRecipeName) {
+		t.Errorf("Expected an overlong name to be rejected, got %+v (%v)", entries, err)
+	}
+
+	// Source URLs that aren't web pages are dropped, keeping the recipe
+	links := map[string]string{}
+	for name, link := range map[string]string{"Script": "javascript:alert(1)", "Relative": "/recipes/scones", "Tracked": "https://www.example.com/scones?utm_source=app"} {
+		links[name+".paprikarecipe"] = paprikaRecipe(t, map[string]any{"name": name, "ingredients": "1 egg", "source_url": link})
+	}
+	entries, err = importer.Read("links.paprikarecipes", zipPages(t, links))
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Expected three Paprika recipes, got %+v (%v)", entries, err)
+	}
+	for _, entry := range entries {
+		want := ""
+		if entry.Name == "Tracked" {
+			want = "https://example.com/scones"
+		}
+		if entry.Recipe == nil || entry.Recipe.URL != want {
+			t.Errorf("Expected %s to be imported with URL %q, got %+v", entry.Name, want, entry)
+		}
+	}
+
+	if _, err := importer.Read("Scones.html", []byte("<html></html>")); err != importer.ErrUnknownFormat {
+		t.Errorf("Expected ErrUnknownFormat for a page, got %v", err)
+	}
+}
+
+// uploadImport posts files to a mix's import endpoint as the user with token,
+// returning the response
+func uploadImport(t *testing.T, router *gin.Engine, mixID string, token string, files map[string][]byte) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, content := range files {
+		w, _ := form.CreateFormFile("file", name)
+		w.Write(content)
+	}
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/mixes/"+mixID+"/recipes/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var accepted map[string]any
+	json.Unmarshal(resp.Body.Bytes(), &accepted)
+	return resp, accepted
+}
+
+// readImportResult waits for the RECIPE_IMPORT_PROGRESS that completes an import
+func readImportResult(t *testing.T, conn *websocket.Conn, importID string) map[string]any {
+	t.Helper()
+
+	for {
+		progress := readMessageOfType(t, conn, "RECIPE_IMPORT_PROGRESS")["data"].(map[string]any)
+		if progress["importId"] != importID {
+			t.Fatalf("Expected progress for import %s, got %v", importID, progress)
+		}
+		if progress["phase"] == "complete" {
+			return progress["result"].(map[string]any)
+		}
+	}
+}
+
+func TestImportMixRecipes_SummarisesExports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	addRecipe(t, id, "Minestrone", "https://example.com/minestrone")
+
+	token := identityToken(t, testOwnerID, "Owner")
+	owner := dialMix(t, server.URL, id)
+	defer owner.Close()
+	sendMessage(t, owner, "USER_IDENTIFY", map[string]any{"token": token})
+	readMessageOfType(t, owner, "PRESENCE_STATE")
+
+	resp, accepted := uploadImport(t, router, id, token, map[string][]byte{
+		"shakshuka.json": []byte(mealieRecipe),
+		"soups.json":     []byte(jsonLDRecipes),
+		"settings.json":  []byte(`{"theme": "dark"}`),
+	})
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", resp.Code, resp.Body.String())
+	}
+	result := readImportResult(t, owner, accepted["importId"].(string))
+	added, skipped, failed := result["added"].([]any), result["skipped"].([]any), result["failed"].([]any)
+
+	// Shakshuka is added; minestrone is already in the mix and settings aren't a recipe; broth has no ingredients
+	if len(added) != 1 || added[0].(map[string]any)["name"] != "Shakshuka" || added[0].(map[string]any)["sharerId"] != testOwnerID {
+		t.Errorf("Expected shakshuka to be added by the owner, got %v", added)
+	}
+	if len(skipped) != 2 || len(failed) != 1 {
+		t.Errorf("Expected two skipped and one failed, got %v and %v", skipped, failed)
+	}
+}
+
+func TestImportMixRecipes_LimitsDecompressedExports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	// Each entry is within the per-entry limit and compresses to almost
+	// nothing, but together they decompress to more than an import may
+	padded := strings.Repeat(" ", importer.MaxEntryBytes-2) + "{}"
+	entries := map[string]string{}
+	for i := range importer.MaxImportBytes/importer.MaxEntryBytes + 1 {
+		entries[fmt.Sprintf("padded %d.json", i)] = padded
+	}
+	archive := zipPages(t, entries)
+
+	if _, err := importer.Read("export.zip", archive); !errors.Is(err, importer.ErrImportTooLarge) {
+		t.Errorf("Expected ErrImportTooLarge, got %v", err)
+	}
+
+	// The budget covers every file of a request
+	half := zipPages(t, map[string]string{"a.json": padded, "b.json": padded})
+	files := map[string][]byte{}
+	for i := range importer.MaxImportBytes/(2*importer.MaxEntryBytes) + 1 {
+		files[fmt.Sprintf("export %d.zip", i)] = half
+	}
+	resp, body := uploadImport(t, router, createMix(t), identityToken(t, testOwnerID, "Owner"), files)
+	if resp.Code != http.StatusRequestEntityTooLarge || body["error"] != "import_too_large" {
+		t.Errorf("Expected 413 import_too_large, got %d: %v", resp.Code, body)
+	}
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/routes"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+func TestInvites_JoinLimitsAndExpiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	owner := identityToken(t, testOwnerID, "Owner")
+	ivy := identityToken(t, "ivy", "Ivy")
+
+	stranger := dialMix(t, server.URL, id)
+	defer stranger.Close()
+	sendMessage(t, stranger, "USER_IDENTIFY", map[string]any{"token": ivy})
+	if code := readMessageOfType(t, stranger, "ERROR")["data"].(map[string]any)["code"]; code != "NOT_MEMBER" {
+		t.Errorf("Expected NOT_MEMBER without an invite, got %v", code)
+	}
+
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes/"+id+"/invites", ivy, `{"role": "editor"}`); resp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-member creating an invite, got %d", resp.Code)
+	}
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes/"+id+"/invites", owner, `{"role": "owner"}`); resp.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an owner invite, got %d", resp.Code)
+	}
+
+	resp, invite := doJSON(router, "POST", "/api/v1/mixes/"+id+"/invites", owner, `{"role": "editor", "expiresIn": 3600, "maxUses": 1}`)
+	if resp.Code != http.StatusCreated || invite["token"] == "" || invite["expiresAt"] == nil {
+		t.Fatalf("Expected an invite, got %d: %v", resp.Code, invite)
+	}
+	join := `{"token": "` + invite["token"].(string) + `"}`
+
+	if resp, member := doJSON(router, "POST", "/api/v1/mixes/"+id+"/join", ivy, join); resp.Code != http.StatusOK || member["role"] != "editor" {
+		t.Fatalf("Expected Ivy to join as an editor, got %d: %v", resp.Code, member)
+	}
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes/"+id+"/join", ivy, join); resp.Code != http.StatusOK {
+		t.Errorf("Expected rejoining to succeed without using the invite, got %d", resp.Code)
+	}
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes/"+id+"/join", identityToken(t, "jack", "Jack"), join); resp.Code != http.StatusGone {
+		t.Errorf("Expected 410 once the invite is used up, got %d", resp.Code)
+	}
+
+	expired, err := ws.Mixes.CreateInvite(id, testOwnerID, models.RoleViewer, time.Nanosecond, 0)
+	if err != nil {
+		t.Fatalf("Failed to create invite: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes/"+id+"/join", identityToken(t, "jack", "Jack"), `{"token": "`+expired.Token+`"}`); resp.Code != http.StatusGone {
+		t.Errorf("Expected 410 for an expired invite, got %d", resp.Code)
+	}
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes/"+id+"/join", ivy, `{"token": "nope"}`); resp.Code != http.StatusOK {
+		t.Errorf("Expected members to need no valid invite, got %d", resp.Code)
+	}
+
+	if _, fetched := doJSON(router, "GET", "/api/v1/mixes/"+id, ivy, ""); fetched["invites"] != nil {
+		t.Errorf("Expected invites to be hidden from non-owners, got %v", fetched["invites"])
+	}
+	_, listed := doJSON(router, "GET", "/api/v1/mixes/"+id+"/invites", owner, "")
+	if invites := listed["invites"].([]any); len(invites) != 2 || invites[0].(map[string]any)["uses"].(float64) != 1 {
+		t.Errorf("Expected two invites with one use of the first, got %v", invites)
+	}
+}
+
+func TestInvites_RevocationDisconnectsMembers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	owner := identityToken(t, testOwnerID, "Owner")
+
+	_, invite := doJSON(router, "POST", "/api/v1/mixes/"+id+"/invites", owner, `{"role": "editor"}`)
+
+	ivy := dialMix(t, server.URL, id)
+	defer ivy.Close()
+	sendMessage(t, ivy, "USER_IDENTIFY", map[string]any{
+		"token":  identityToken(t, "ivy", "Ivy"),
+		"invite": invite["token"],
+	})
+	readMessageOfType(t, ivy, "PRESENCE_STATE")
+
+	ownerConn := dialMix(t, server.URL, id)
+	defer ownerConn.Close()
+	sendMessage(t, ownerConn, "USER_IDENTIFY", map[string]any{"token": owner})
+	readMessageOfType(t, ownerConn, "PRESENCE_STATE")
+
+	if resp, _ := doJSON(router, "DELETE", "/api/v1/mixes/"+id+"/invites/"+invite["id"].(string), owner, ""); resp.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on revoke, got %d", resp.Code)
+	}
+
+	ivy.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		if _, _, err := ivy.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, ws.CloseMembershipRevoked) {
+				t.Errorf("Expected close code %d, got %v", ws.CloseMembershipRevoked, err)
+			}
+			break
+		}
+	}
+	readMessageOfType(t, ownerConn, "USER_LEFT")
+
+	if resp, _ := doJSON(router, "GET", "/api/v1/mixes/"+id+"/recipes", identityToken(t, "ivy", "Ivy"), ""); resp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 after revocation, got %d", resp.Code)
+	}
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes/"+id+"/join", identityToken(t, "ivy", "Ivy"), `{"token": "`+invite["token"].(string)+`"}`); resp.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a revoked invite, got %d", resp.Code)
+	}
+}
@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kitchenmix/api/internal/services/chat"
+	"kitchenmix/api/internal/services/grocerylist"
+	"kitchenmix/api/internal/services/mix"
+	"kitchenmix/api/internal/services/pantry"
+	"kitchenmix/api/internal/services/plan"
+	"kitchenmix/api/internal/services/recipe"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// TestMain keeps everything the services persist in a temporary directory,
+// rather than the tmp/ directories they default to
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "kitchenmix-tests-")
+	if err != nil {
+		log.Fatalf("Failed to create test data directory: %v", err)
+	}
+
+	for env, sub := range map[string]string{
+		"MIX_DIR":          "mixes",
+		"CHAT_DIR":         "chat",
+		"PLAN_DIR":         "plans",
+		"PANTRY_DIR":       "pantries",
+		"GROCERY_LIST_DIR": "grocery-lists",
+		"IMAGE_DIR":        "images",
+		"RECIPE_DIR":       "recipes",
+	} {
+		os.Setenv(env, filepath.Join(dir, sub))
+	}
+
+	// The shared services were created when their package loaded, before the
+	// environment was set
+	ws.Recipes = recipe.NewRecipeService()
+	ws.Mixes = mix.NewMixService(mix.ConfigFromEnv())
+	ws.Chat = chat.NewChatService(chat.ConfigFromEnv())
+	ws.Plans = plan.NewPlanService(plan.ConfigFromEnv())
+	ws.GroceryLists = grocerylist.NewGroceryListService(grocerylist.ConfigFromEnv())
+	ws.Pantries = pantry.NewPantryService(pantry.ConfigFromEnv())
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,123 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"kitchenmix/api/internal/routes"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// doJSON sends a request with an optional JSON body and decodes the JSON response
+func doJSON(router *gin.Engine, method string, path string, token string, body string) (*httptest.ResponseRecorder, map[string]any) {
+	var reader *bytes.Buffer
+	if body != "" {
+		reader = bytes.NewBufferString(body)
+	} else {
+		reader = &bytes.Buffer{}
+	}
+	req, _ := http.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	var result map[string]any
+	json.Unmarshal(resp.Body.Bytes(), &result)
+	return resp, result
+}
+
+func TestMixLifecycle(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	owner := identityToken(t, "lifecycle-owner", "Owner")
+
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes", "", `{"title": "Sunday dinner"}`); resp.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without an identity, got %d", resp.Code)
+	}
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes", owner, `{"title": "   "}`); resp.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a blank title, got %d", resp.Code)
+	}
+
+	resp, created := doJSON(router, "POST", "/api/v1/mixes", owner, `{"title": "Sunday dinner", "description": "Roasts"}`)
+	if resp.Code != http.StatusCreated || created["title"] != "Sunday dinner" {
+		t.Fatalf("Expected mix to be created, got %d: %v", resp.Code, created)
+	}
+	id := created["id"].(string)
+
+	resp, renamed := doJSON(router, "PATCH", "/api/v1/mixes/"+id, owner, `{"title": "Sunday lunch"}`)
+	if resp.Code != http.StatusOK || renamed["title"] != "Sunday lunch" || renamed["description"] != "Roasts" {
+		t.Errorf("Expected rename to keep the description, got %d: %v", resp.Code, renamed)
+	}
+
+	resp, fetched := doJSON(router, "GET", "/api/v1/mixes/"+id, owner, "")
+	if resp.Code != http.StatusOK || fetched["title"] != "Sunday lunch" || fetched["recipeCount"].(float64) != 0 {
+		t.Errorf("Expected renamed mix, got %d: %v", resp.Code, fetched)
+	}
+
+	resp, recipes := doJSON(router, "GET", "/api/v1/mixes/"+id+"/recipes", owner, "")
+	if resp.Code != http.StatusOK || len(recipes["recipes"].([]any)) != 0 {
+		t.Errorf("Expected an empty recipe list, got %d: %v", resp.Code, recipes)
+	}
+
+	if resp, _ := doJSON(router, "DELETE", "/api/v1/mixes/"+id, owner, ""); resp.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 on delete, got %d", resp.Code)
+	}
+	for _, path := range []string{"/api/v1/mixes/" + id, "/api/v1/mixes/" + id + "/recipes", "/api/v1/ws/" + id} {
+		if resp, _ := doJSON(router, "GET", path, owner, ""); resp.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s after delete, got %d", path, resp.Code)
+		}
+	}
+}
+
+func TestWebSocket_RefusesUnknownMix(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws/" + uuid.New().String() + protocolQuery
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown mix, got %v", err)
+	}
+}
+
+func TestDeleteMix_DisconnectsClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	client := dialMix(t, server.URL, id)
+	defer client.Close()
+
+	if resp, _ := doJSON(router, "DELETE", "/api/v1/mixes/"+id, identityToken(t, testOwnerID, "Owner"), ""); resp.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on delete, got %d", resp.Code)
+	}
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := client.ReadMessage(); !websocket.IsCloseError(err, ws.CloseMixDeleted) {
+		t.Errorf("Expected close code %d, got %v", ws.CloseMixDeleted, err)
+	}
+}
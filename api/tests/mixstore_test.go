@@ -0,0 +1,41 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"kitchenmix/api/internal/services/mixstore"
+)
+
+func TestMixStore_PersistsPerMix(t *testing.T) {
+	dir := t.TempDir()
+	mixA, mixB := uuid.New().String(), uuid.New().String()
+
+	store := mixstore.New[[]string](dir, "notes")
+	if _, ok := store.Get(mixA); ok {
+		t.Fatal("Expected nothing stored for a new mix")
+	}
+	store.Put(mixA, []string{"buy flour"})
+	store.Put(mixB, []string{"book table"})
+
+	reloaded := mixstore.New[[]string](dir, "notes")
+	if notes, ok := reloaded.Get(mixA); !ok || len(notes) != 1 || notes[0] != "buy flour" {
+		t.Errorf("Expected the notes of mix-a to be read back, got %v (ok=%v)", notes, ok)
+	}
+
+	reloaded.Delete(mixB)
+	if _, ok := mixstore.New[[]string](dir, "notes").Get(mixB); ok {
+		t.Error("Expected a deleted mix to be gone from disk")
+	}
+
+	memory := mixstore.New[[]string]("", "notes")
+	memory.Put(mixA, []string{"kept in memory"})
+	if notes, ok := memory.Get(mixA); !ok || notes[0] != "kept in memory" {
+		t.Errorf("Expected a store without a directory to keep values in memory, got %v", notes)
+	}
+
+	store.Put("../escape", []string{"not a file"})
+	if _, ok := mixstore.New[[]string](dir, "notes").Get("../escape"); ok {
+		t.Error("Expected a mix ID that isn't a UUID to be kept in memory only")
+	}
+}
@@ -0,0 +1,61 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/services/recipe"
+)
+
+func TestPageCache_SharedAcrossURLs(t *testing.T) {
+	cache := recipe.NewPageCache(recipe.PageCacheConfig{TTL: time.Hour, MaxBytes: 1024})
+
+	cache.Put("https://example.com/a", "<html>soup</html>", &models.Recipe{Name: "Soup"})
+
+	page, ok := cache.GetByURL("https://example.com/a")
+	if !ok || page.Recipe == nil || page.Recipe.Name != "Soup" {
+		t.Fatalf("Expected cached Soup recipe, got %+v", page)
+	}
+
+	page, ok = cache.GetByContent("<html>soup</html>")
+	if !ok || page.ContentHash != recipe.HashContent("<html>soup</html>") {
+		t.Errorf("Expected lookup by content to hit, got %+v", page)
+	}
+}
+
+func TestPageCache_EvictsBySize(t *testing.T) {
+	cache := recipe.NewPageCache(recipe.PageCacheConfig{TTL: time.Hour, MaxBytes: 10})
+
+	cache.Put("https://example.com/a", "0123456789", nil)
+	cache.Put("https://example.com/b", "abcdefghij", nil)
+
+	if _, ok := cache.GetByURL("https://example.com/a"); ok {
+		t.Error("Expected oldest page to be evicted")
+	}
+	if _, ok := cache.GetByURL("https://example.com/b"); !ok {
+		t.Error("Expected newest page to remain cached")
+	}
+}
+
+func TestPageCache_Expires(t *testing.T) {
+	cache := recipe.NewPageCache(recipe.PageCacheConfig{TTL: time.Millisecond, MaxBytes: 1024})
+
+	cache.Put("https://example.com/a", "content", nil)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := cache.GetByURL("https://example.com/a"); ok {
+		t.Error("Expected expired page to be dropped")
+	}
+}
+
+func TestPageCache_PersistsToDisk(t *testing.T) {
+	config := recipe.PageCacheConfig{TTL: time.Hour, MaxBytes: 1024, Dir: t.TempDir()}
+
+	recipe.NewPageCache(config).Put("https://example.com/a", "content", &models.Recipe{Name: "Stew"})
+
+	page, ok := recipe.NewPageCache(config).GetByURL("https://example.com/a")
+	if !ok || page.Recipe == nil || page.Recipe.Name != "Stew" {
+		t.Errorf("Expected persisted Stew recipe, got %+v", page)
+	}
+}
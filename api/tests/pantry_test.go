@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/shopping"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+func pantryItem(name string, quantity string, unit string) models.PantryItem {
+	in := ingredient(name, quantity, unit)
+	return models.PantryItem{Name: in.Name, Quantity: in.Quantity, Unit: in.Unit}
+}
+
+func TestShoppingList_SubtractsPantryStock(t *testing.T) {
+	milk := ingredient("whole milk", "1", "cup")
+	milk.GroceryItem = &models.GroceryItem{ID: "milk", Name: "Milk", Category: "Dairy"}
+	pancakes := &models.Recipe{Name: "Pancakes", Ingredients: []models.Ingredient{
+		ingredient("Flour", "2", "cups"),
+		ingredient("flour", "100", "g"),
+		ingredient("eggs", "4", ""),
+		ingredient("salt", "a pinch", ""),
+		ingredient("butter", "200", "g"),
+		milk,
+	}}
+
+	stockedMilk := pantryItem("Milk", "1", "litre")
+	stockedMilk.GroceryItem = &models.GroceryItem{ID: "milk", Name: "Milk", Category: "Dairy"}
+	toBuy, stocked := shopping.Subtract(shopping.Build([]*models.Recipe{pancakes}), []models.PantryItem{
+		pantryItem("Salt", "", ""),
+		pantryItem("flour", "0.25", "kg"),
+		pantryItem("Eggs", "6", ""),
+		pantryItem("butter", "0.1", "kg"),
+		stockedMilk,
+	})
+
+	lines := make([]string, len(toBuy))
+	for i, item := range toBuy {
+		lines[i] = item.String()
+		if !item.PartlyStocked {
+			t.Errorf("Expected %s to be partly stocked", item.Name)
+		}
+	}
+	if want := []string{"butter: 100 g", "Flour: 2 cup"}; strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected %q left to buy, got %q", want, lines)
+	}
+	if flour := toBuy[1].Stocked; len(flour) != 1 || flour[0] != (shopping.Amount{Quantity: "100", Unit: "g"}) {
+		t.Errorf("Expected the pantry to cover 100 g of flour, got %v", flour)
+	}
+
+	var names []string
+	for _, item := range stocked {
+		names = append(names, item.Name)
+	}
+	if want := []string{"eggs", "salt", "whole milk"}; strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %q to be stocked, got %q", want, names)
+	}
+
+	if grams, ok := shopping.ConvertUnit(1, "lbs", "g"); !ok || grams < 453 || grams > 454 {
+		t.Errorf("Expected a pound to be 453.6 g, got %v", grams)
+	}
+	if _, ok := shopping.ConvertUnit(1, "cup", "g"); ok {
+		t.Error("Expected volumes not to convert to weights")
+	}
+}
+
+func TestMixPantry_IsTakenOffPlanShoppingList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	id := createMix(t)
+	pancakes := addRecipe(t, id, "Pancakes", "https://example.com/pancakes")
+	owner := identityToken(t, testOwnerID, "Owner")
+	path := "/api/v1/mixes/" + id + "/pantry"
+
+	for _, date := range []string{"2025-03-01", "2025-03-02"} {
+		body := `{"recipeId": "` + pancakes.ID + `", "date": "` + date + `", "meal": "breakfast"}`
+		if resp, result := doJSON(router, http.MethodPost, "/api/v1/mixes/"+id+"/plan", owner, body); resp.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %v", resp.Code, result)
+		}
+	}
+
+	if resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "salt"}`); resp.Code != http.StatusCreated || result["quantity"] != nil {
+		t.Errorf("Expected salt to be stocked as a staple, got %d: %v", resp.Code, result)
+	}
+	if resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": " SALT "}`); resp.Code != http.StatusConflict || result["error"] != "pantry_item_exists" {
+		t.Errorf("Expected 409 for a second salt, got %d: %v", resp.Code, result)
+	}
+	if resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "sugar", "quantity": "lots"}`); resp.Code != http.StatusBadRequest || result["error"] != "invalid_pantry_item" {
+		t.Errorf("Expected 400 for an unreadable quantity, got %d: %v", resp.Code, result)
+	}
+	resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "Flour", "quantity": "1", "unit": "cup"}`)
+	if resp.Code != http.StatusCreated || result["updatedByName"] != "Owner" {
+		t.Fatalf("Expected flour to be stocked, got %d: %v", resp.Code, result)
+	}
+	flourID := result["id"].(string)
+
+	// Two planned breakfasts need 4 cups of flour, of which the pantry has 1
+	shoppingPath := "/api/v1/mixes/" + id + "/plan/shopping-list?from=2025-03-01"
+	_, result = doJSON(router, http.MethodGet, shoppingPath, owner, "")
+	items := result["items"].([]any)
+	if len(items) != 1 {
+		t.Fatalf("Expected flour left to buy, got %v", result)
+	}
+	flour := items[0].(map[string]any)
+	if amount := flour["amounts"].([]any)[0].(map[string]any); amount["quantity"] != "3" || flour["partlyStocked"] != true {
+		t.Errorf("Expected 3 more cups of flour, got %v", flour)
+	}
+
+	if resp, result := doJSON(router, http.MethodPut, path+"/"+flourID, owner, `{"name": "flour", "quantity": "1", "unit": "l"}`); resp.Code != http.StatusOK || result["id"] != flourID {
+		t.Fatalf("Expected flour to be restocked, got %d: %v", resp.Code, result)
+	}
+	_, result = doJSON(router, http.MethodGet, shoppingPath, owner, "")
+	if items, stocked := result["items"].([]any), result["stocked"].([]any); len(items) != 0 || len(stocked) != 1 {
+		t.Errorf("Expected a litre of flour to cover 4 cups, got %v", result)
+	}
+	_, result = doJSON(router, http.MethodGet, shoppingPath+"&pantry=false", owner, "")
+	if items := result["items"].([]any); len(items) != 1 {
+		t.Errorf("Expected the pantry to be ignored, got %v", result)
+	}
+
+	_, result = doJSON(router, http.MethodGet, path, owner, "")
+	if items := result["items"].([]any); len(items) != 2 || items[0].(map[string]any)["name"] != "flour" {
+		t.Errorf("Expected flour and salt by name, got %v", items)
+	}
+
+	if resp, _ := doJSON(router, http.MethodDelete, path+"/"+flourID, owner, ""); resp.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", resp.Code)
+	}
+	if resp, result := doJSON(router, http.MethodDelete, path+"/"+flourID, owner, ""); resp.Code != http.StatusNotFound || result["error"] != "pantry_item_not_found" {
+		t.Errorf("Expected 404 for a removed item, got %d: %v", resp.Code, result)
+	}
+
+	stranger := identityToken(t, "stranger", "Stranger")
+	if resp, _ := doJSON(router, http.MethodPost, path, stranger, `{"name": "pepper"}`); resp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for non-members, got %d", resp.Code)
+	}
+}
+
+func TestMixPantry_BroadcastsChangesWithRecipeGroceryItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	milk := ingredient("whole milk", "1", "cup")
+	milk.GroceryItem = &models.GroceryItem{ID: "milk", Name: "Milk", Category: "Dairy"}
+	if _, duplicate := ws.Recipes.AddRecipe(id, &models.Recipe{Name: "Pancakes", Ingredients: []models.Ingredient{milk}}); duplicate {
+		t.Fatal("Expected pancakes to be added")
+	}
+	owner := identityToken(t, testOwnerID, "Owner")
+	path := "/api/v1/mixes/" + id + "/pantry"
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	// The catalogue entry is the one the mix's recipes use, not the one sent
+	resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "Milk", "quantity": "1", "unit": "l", "groceryItem": {"id": "milk", "name": "Anything", "category": "Other"}}`)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("Expected milk to be stocked, got %d: %v", resp.Code, result)
+	}
+	added := readMessageOfType(t, alice, "PANTRY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	if grocery, _ := added["groceryItem"].(map[string]any); added["id"] != result["id"] || grocery["name"] != "Milk" || grocery["category"] != "Dairy" {
+		t.Errorf("Expected milk to be stocked as the recipes' Dairy item, got %v", added)
+	}
+	milkID := result["id"].(string)
+
+	// IDs no recipe in the mix uses are dropped rather than matched against
+	if resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "Cocoa", "groceryItem": {"id": "milk-powder", "name": "Cocoa", "category": "Baking"}}`); resp.Code != http.StatusCreated || result["groceryItem"] != nil {
+		t.Errorf("Expected an unknown grocery item to be dropped, got %d: %v", resp.Code, result)
+	}
+	readMessageOfType(t, alice, "PANTRY_ITEM_UPDATES")
+
+	if resp, result := doJSON(router, http.MethodPut, path+"/"+milkID, owner, `{"name": "Milk", "quantity": "2", "unit": "l", "groceryItem": {"id": "cocoa"}}`); resp.Code != http.StatusOK || result["groceryItem"] != nil {
+		t.Errorf("Expected milk to be restocked without a grocery item, got %d: %v", resp.Code, result)
+	}
+	if replaced := readMessageOfType(t, alice, "PANTRY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any); replaced["quantity"] != "2" {
+		t.Errorf("Expected 2 l of milk, got %v", replaced)
+	}
+
+	if resp, _ := doJSON(router, http.MethodDelete, path+"/"+milkID, owner, ""); resp.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", resp.Code)
+	}
+	removed := readMessageOfType(t, alice, "PANTRY_ITEM_REMOVALS")["data"].(map[string]any)["itemIds"].([]any)
+	if len(removed) != 1 || removed[0] != milkID {
+		t.Errorf("Expected milk to be removed, got %v", removed)
+	}
+
+	if resp, result := doJSON(router, http.MethodPost, path, owner, `{"name": "Flour", "quantity": "1", "unit": "`+strings.Repeat("k", 51)+`"}`); resp.Code != http.StatusBadRequest || result["error"] != "invalid_pantry_item" {
+		t.Errorf("Expected an overlong unit to be refused, got %d: %v", resp.Code, result)
+	}
+
+	// Connections that join later are sent the pantry as it is
+	bob := dialMix(t, server.URL, id)
+	defer bob.Close()
+	sendMessage(t, bob, "USER_IDENTIFY", identifyPayload(t, id, "bob", "Bob"))
+	stocked := readMessageOfType(t, bob, "PANTRY_ITEM_UPDATES")["data"].(map[string]any)["list"].([]any)
+	if len(stocked) != 1 || stocked[0].(map[string]any)["name"] != "Cocoa" {
+		t.Errorf("Expected the pantry to be sent on joining, got %v", stocked)
+	}
+}
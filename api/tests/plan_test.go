@@ -0,0 +1,242 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/plan"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+func TestMealPlan_BroadcastsEntryChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	pancakes := addRecipe(t, id, "Pancakes", "https://example.com/pancakes")
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	bob := dialMix(t, server.URL, id)
+	defer bob.Close()
+	sendMessage(t, bob, "USER_IDENTIFY", identifyPayload(t, id, "bob", "Bob"))
+	readMessageOfType(t, bob, "PRESENCE_STATE")
+
+	sendMessage(t, alice, "PLAN_ENTRY_ADD", map[string]any{"recipeId": pancakes.ID, "date": "2025-03-01", "meal": "breakfast"})
+	added := readMessageOfType(t, bob, "PLAN_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	if added["recipeId"] != pancakes.ID || added["date"] != "2025-03-01" || added["meal"] != "breakfast" || added["addedByName"] != "Alice" {
+		t.Fatalf("Expected pancakes for breakfast added by Alice, got %v", added)
+	}
+	entryID := added["id"].(string)
+
+	sendMessage(t, alice, "PLAN_ENTRY_MOVE", map[string]any{"entryId": entryID, "date": "2025-03-02", "meal": "dinner"})
+	moved := readMessageOfType(t, bob, "PLAN_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	if moved["id"] != entryID || moved["date"] != "2025-03-02" || moved["meal"] != "dinner" {
+		t.Errorf("Expected the entry to move to dinner the next day, got %v", moved)
+	}
+
+	sendMessage(t, alice, "PLAN_ENTRY_ADD", map[string]any{"recipeId": pancakes.ID, "date": "2025-03-01", "meal": "elevenses"})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "INVALID_PAYLOAD" {
+		t.Errorf("Expected INVALID_PAYLOAD for an unknown meal, got %v", code)
+	}
+	sendMessage(t, alice, "PLAN_ENTRY_ADD", map[string]any{"recipeId": "missing", "date": "2025-03-01", "meal": "lunch"})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "RECIPE_NOT_FOUND" {
+		t.Errorf("Expected RECIPE_NOT_FOUND for a recipe outside the mix, got %v", code)
+	}
+
+	sendMessage(t, alice, "PLAN_ENTRY_REMOVE", map[string]any{"entryId": entryID})
+	if ids := readMessageOfType(t, bob, "PLAN_REMOVALS")["data"].(map[string]any)["entryIds"].([]any); len(ids) != 1 || ids[0] != entryID {
+		t.Errorf("Expected the entry to be removed, got %v", ids)
+	}
+	sendMessage(t, alice, "PLAN_ENTRY_REMOVE", map[string]any{"entryId": entryID})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "PLAN_ENTRY_NOT_FOUND" {
+		t.Errorf("Expected PLAN_ENTRY_NOT_FOUND, got %v", code)
+	}
+
+	// Removing a recipe takes it off the plan too
+	sendMessage(t, alice, "PLAN_ENTRY_ADD", map[string]any{"recipeId": pancakes.ID, "date": "2025-03-03", "meal": "lunch"})
+	entryID = readMessageOfType(t, bob, "PLAN_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)["id"].(string)
+	sendMessage(t, alice, "RECIPE_REMOVE", map[string]any{"recipeId": pancakes.ID, "version": 1})
+	if ids := readMessageOfType(t, bob, "PLAN_REMOVALS")["data"].(map[string]any)["entryIds"].([]any); len(ids) != 1 || ids[0] != entryID {
+		t.Errorf("Expected the removed recipe's entry to be unplanned, got %v", ids)
+	}
+}
+
+func TestMealPlan_ShoppingListAndCalendar(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	id := createMix(t)
+	pancakes := addRecipe(t, id, "Pancakes", "https://example.com/pancakes")
+	bread := addRecipe(t, id, "Bread; with seeds", "https://example.com/bread")
+	owner := identityToken(t, testOwnerID, "Owner")
+	path := "/api/v1/mixes/" + id + "/plan"
+
+	for _, body := range []string{
+		`{"recipeId": "` + pancakes.ID + `", "date": "2025-03-01", "meal": "breakfast", "note": "Double batch"}`,
+		`{"recipeId": "` + pancakes.ID + `", "date": "2025-03-02", "meal": "Breakfast"}`,
+		`{"recipeId": "` + bread.ID + `", "date": "2025-03-09", "meal": "lunch"}`,
+	} {
+		if resp, result := doJSON(router, http.MethodPost, path, owner, body); resp.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %v", resp.Code, result)
+		}
+	}
+
+	resp, result := doJSON(router, http.MethodGet, path+"?from=2025-03-02&to=2025-03-31", owner, "")
+	if entries := result["entries"].([]any); resp.Code != http.StatusOK || len(entries) != 2 || entries[0].(map[string]any)["meal"] != "breakfast" {
+		t.Errorf("Expected two entries from March 2nd, got %d: %v", resp.Code, result)
+	}
+
+	// Pancakes are planned twice in the first week, so their flour counts twice
+	resp, result = doJSON(router, http.MethodGet, path+"/shopping-list?from=2025-03-01", owner, "")
+	items := result["items"].([]any)
+	if resp.Code != http.StatusOK || result["to"] != "2025-03-07" || len(items) != 1 {
+		t.Fatalf("Expected one item for the week from March 1st, got %d: %v", resp.Code, result)
+	}
+	if amount := items[0].(map[string]any)["amounts"].([]any)[0].(map[string]any); amount["quantity"] != "4" || amount["unit"] != "cup" {
+		t.Errorf("Expected 4 cups of flour, got %v", amount)
+	}
+
+	if resp, result := doJSON(router, http.MethodGet, path+"/shopping-list?from=2025-03-09&to=2025-03-01", owner, ""); resp.Code != http.StatusBadRequest || result["error"] != "invalid_date" {
+		t.Errorf("Expected 400 for a backwards range, got %d: %v", resp.Code, result)
+	}
+
+	resp, _ = doJSON(router, http.MethodGet, path+"/calendar.ics", owner, "")
+	calendar := resp.Body.String()
+	if resp.Code != http.StatusOK || !strings.HasPrefix(resp.Header().Get("Content-Type"), "text/calendar") {
+		t.Fatalf("Expected a calendar, got %d: %s", resp.Code, calendar)
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20250301T080000\r\n",
+		"SUMMARY:Breakfast: Pancakes\r\n",
+		"DESCRIPTION:Double batch\\n\\n- 2 cups flour\r\n",
+		"SUMMARY:Lunch: Bread\\; with seeds\r\n",
+		"URL:https://example.com/bread\r\n",
+	} {
+		if !strings.Contains(calendar, want) {
+			t.Errorf("Expected the calendar to contain %q, got %s", want, calendar)
+		}
+	}
+	if strings.Count(calendar, "BEGIN:VEVENT") != 3 {
+		t.Errorf("Expected three events, got %s", calendar)
+	}
+
+	viewer := identityToken(t, "stranger", "Stranger")
+	if resp, _ := doJSON(router, http.MethodPost, path, viewer, `{"recipeId": "`+pancakes.ID+`", "date": "2025-03-01", "meal": "lunch"}`); resp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for non-members, got %d", resp.Code)
+	}
+}
+
+func TestMealPlan_BroadcastsInterleavedEditsInOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	pancakes := addRecipe(t, id, "Pancakes", "https://example.com/pancakes")
+	entry, err := ws.AddPlanEntry(id, models.PlanEntry{RecipeID: pancakes.ID, Date: "2025-03-01", Meal: "breakfast"}, "")
+	if err != nil {
+		t.Fatalf("Failed to plan pancakes: %v", err)
+	}
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	// The last move broadcast is where the entry is stored
+	const movers, movesEach = 8, 20
+	var wg sync.WaitGroup
+	for mover := range movers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for move := range movesEach {
+				date := fmt.Sprintf("2025-03-%02d", (mover*movesEach+move)%28+1)
+				if _, err := ws.MovePlanEntry(id, entry.ID, date, "dinner", ""); err != nil {
+					t.Errorf("Failed to move pancakes: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var last map[string]any
+	for range movers * movesEach {
+		last = readMessageOfType(t, alice, "PLAN_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	}
+	if stored := ws.Plans.Entries(id, "", ""); len(stored) != 1 || last["date"] != stored[0].Date {
+		t.Errorf("Expected the last move to be to %v, got %v", stored, last["date"])
+	}
+
+	// Planning a recipe as it is removed never leaves it planned
+	for n := range 20 {
+		waffles := addRecipe(t, id, "Waffles", fmt.Sprintf("https://example.com/waffles/%d", n))
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			ws.AddPlanEntry(id, models.PlanEntry{RecipeID: waffles.ID, Date: "2025-03-02", Meal: "lunch"}, "")
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := ws.RemoveRecipe(id, waffles.ID, waffles.Version, ""); err != nil {
+				t.Errorf("Failed to remove waffles: %v", err)
+			}
+		}()
+		wg.Wait()
+		for _, planned := range ws.Plans.Entries(id, "", "") {
+			if planned.RecipeID == waffles.ID {
+				t.Fatalf("Expected removed waffles not to be planned, got %v", planned)
+			}
+		}
+	}
+}
+
+func TestMealPlanCalendar_WritesOnlyWebURLs(t *testing.T) {
+	entries := []models.PlanEntry{}
+	recipes := map[string]*models.Recipe{}
+	for n, link := range []string{
+		"https://example.com/soup\r\nEND:VEVENT\r\nBEGIN:VEVENT\r\nSUMMARY:Injected",
+		"javascript:alert(1)",
+		"/recipes/soup",
+		"https://example.com/soup?serves=4",
+	} {
+		recipeID := fmt.Sprintf("soup-%d", n)
+		recipes[recipeID] = &models.Recipe{ID: recipeID, Name: "Soup", URL: link}
+		entries = append(entries, models.PlanEntry{ID: fmt.Sprintf("entry-%d", n), RecipeID: recipeID, Date: "2025-03-01", Meal: "dinner"})
+	}
+
+	var b strings.Builder
+	if err := plan.WriteICS(&b, "Plan", entries, recipes); err != nil {
+		t.Fatalf("Failed to write calendar: %v", err)
+	}
+	calendar := b.String()
+	if strings.Contains(calendar, "Injected") || strings.Count(calendar, "BEGIN:VEVENT") != 4 {
+		t.Errorf("Expected a URL with line breaks to be left out, got %s", calendar)
+	}
+	if strings.Count(calendar, "URL:") != 1 || !strings.Contains(calendar, "URL:https://example.com/soup?serves=4\r\n") {
+		t.Errorf("Expected only the web URL to be written, got %s", calendar)
+	}
+}
@@ -0,0 +1,232 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"kitchenmix/api/internal/services/recipe"
+)
+
+func newRobotsServer(robots string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte(robots))
+			return
+		}
+		w.Write([]byte("page"))
+	}))
+}
+
+func TestPoliteness_HonoursRobots(t *testing.T) {
+	server := newRobotsServer("User-agent: *\nDisallow: /\n\nUser-agent: KitchenMix\nDisallow: /private\nAllow: /private/recipes\n")
+	defer server.Close()
+
+	politeness := recipe.NewPoliteness(recipe.PolitenessConfig{
+		RobotsAgent: "KitchenMix",
+		MaxPerHost:  1,
+		RobotsTTL:   time.Hour,
+	}, recipe.NewFetcher(testFetcherConfig()))
+
+	ctx := context.Background()
+
+	if _, err := politeness.Acquire(ctx, server.URL+"/private/notes"); !errors.Is(err, recipe.ErrBlockedByRobots) {
+		t.Errorf("Expected ErrBlockedByRobots for disallowed path, got %v", err)
+	}
+
+	release, err := politeness.Acquire(ctx, server.URL+"/private/recipes/soup")
+	if err != nil {
+		t.Fatalf("Expected more specific Allow rule to win, got %v", err)
+	}
+	release()
+
+	release, err = politeness.Acquire(ctx, server.URL+"/recipes/soup")
+	if err != nil {
+		t.Fatalf("Expected path outside Disallow to be allowed, got %v", err)
+	}
+	release()
+}
+
+func TestPoliteness_EnforcesMinDelay(t *testing.T) {
+	server := newRobotsServer("")
+	defer server.Close()
+
+	politeness := recipe.NewPoliteness(recipe.PolitenessConfig{
+		RobotsAgent: "KitchenMix",
+		MinDelay:    50 * time.Millisecond,
+		MaxPerHost:  2,
+		RobotsTTL:   time.Hour,
+	}, recipe.NewFetcher(testFetcherConfig()))
+
+	ctx := context.Background()
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		release, err := politeness.Acquire(ctx, server.URL+"/recipe")
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		release()
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Expected requests to be spaced by the minimum delay, took %s", elapsed)
+	}
+}
+
+func TestPoliteness_UnreachableRobotsDisallowsBriefly(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" && failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(""))
+	}))
+	defer server.Close()
+
+	cfg := testFetcherConfig()
+	cfg.MaxRetries = 0
+	politeness := recipe.NewPoliteness(recipe.PolitenessConfig{
+		RobotsAgent:    "KitchenMix",
+		MaxPerHost:     1,
+		RobotsTTL:      time.Hour,
+		RobotsRetryTTL: 50 * time.Millisecond,
+	}, recipe.NewFetcher(cfg))
+
+	ctx := context.Background()
+	if _, err := politeness.Acquire(ctx, server.URL+"/recipe"); !errors.Is(err, recipe.ErrBlockedByRobots) {
+		t.Fatalf("Expected a 5xx robots.txt to disallow the host, got %v", err)
+	}
+
+	failing.Store(false)
+	if _, err := politeness.Acquire(ctx, server.URL+"/recipe"); !errors.Is(err, recipe.ErrBlockedByRobots) {
+		t.Errorf("Expected the failure to be cached until it expires, got %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	release, err := politeness.Acquire(ctx, server.URL+"/recipe")
+	if err != nil {
+		t.Fatalf("Expected robots.txt to be fetched again once the failure expired, got %v", err)
+	}
+	release()
+}
+
+func TestPoliteness_FetchesRobotsOnce(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fetches.Add(1)
+			time.Sleep(20 * time.Millisecond)
+		}
+		w.Write([]byte(""))
+	}))
+	defer server.Close()
+
+	politeness := recipe.NewPoliteness(recipe.PolitenessConfig{
+		RobotsAgent: "KitchenMix",
+		MaxPerHost:  4,
+		RobotsTTL:   time.Hour,
+	}, recipe.NewFetcher(testFetcherConfig()))
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := politeness.Acquire(context.Background(), server.URL+"/recipe")
+			if err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			release()
+		}()
+	}
+	wg.Wait()
+
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("Expected concurrent requests to share one robots.txt fetch, got %d", n)
+	}
+}
+
+func TestPoliteness_MatchesProductToken(t *testing.T) {
+	// A group for an agent whose name merely contains ours must not apply
+	server := newRobotsServer("User-agent: kitchenmixer\nDisallow: /\n\nUser-agent: KITCHENMIX\nDisallow: /private\n")
+	defer server.Close()
+
+	politeness := recipe.NewPoliteness(recipe.PolitenessConfig{
+		RobotsAgent: "KitchenMix/1.0",
+		MaxPerHost:  1,
+		RobotsTTL:   time.Hour,
+	}, recipe.NewFetcher(testFetcherConfig()))
+
+	ctx := context.Background()
+	release, err := politeness.Acquire(ctx, server.URL+"/recipes/soup")
+	if err != nil {
+		t.Fatalf("Expected only our own group to apply, got %v", err)
+	}
+	release()
+
+	if _, err := politeness.Acquire(ctx, server.URL+"/private/notes"); !errors.Is(err, recipe.ErrBlockedByRobots) {
+		t.Errorf("Expected our group to be matched case-insensitively, got %v", err)
+	}
+}
+
+func TestPoliteness_ForgetsIdleHosts(t *testing.T) {
+	var fetches atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fetches.Add(1)
+		}
+		w.Write([]byte(""))
+	})
+	servers := make([]*httptest.Server, 3)
+	for i := range servers {
+		servers[i] = httptest.NewServer(handler)
+		defer servers[i].Close()
+	}
+
+	politeness := recipe.NewPoliteness(recipe.PolitenessConfig{
+		RobotsAgent: "KitchenMix",
+		MaxPerHost:  1,
+		RobotsTTL:   time.Hour,
+		MaxHosts:    2,
+	}, recipe.NewFetcher(testFetcherConfig()))
+
+	ctx := context.Background()
+	held, err := politeness.Acquire(ctx, servers[0].URL+"/recipe")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	for _, server := range servers[1:] {
+		release, err := politeness.Acquire(ctx, server.URL+"/recipe")
+		if err != nil {
+			t.Fatalf("Acquire failed: %v", err)
+		}
+		release()
+	}
+
+	// The host in use is kept, so its one slot is still taken
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := politeness.Acquire(waitCtx, servers[0].URL+"/recipe"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected a host in use to be remembered, got %v", err)
+	}
+	held()
+
+	// The idle second host made way for the third, so its robots.txt is fetched again
+	release, err := politeness.Acquire(ctx, servers[1].URL+"/recipe")
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	release()
+	if n := fetches.Load(); n != 4 {
+		t.Errorf("Expected the idle host to be forgotten and its robots.txt fetched again, got %d fetches", n)
+	}
+}
@@ -0,0 +1,387 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"kitchenmix/api/internal/models"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/recipe"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// addRecipe stores a recipe in a mix without fetching anything
+func addRecipe(t *testing.T, mixID string, name string, url string) *models.Recipe {
+	t.Helper()
+
+	quantity, unit := "2", "cups"
+	stored, duplicate := ws.Recipes.AddRecipe(mixID, &models.Recipe{
+		Name: name,
+		URL:  url,
+		Ingredients: []models.Ingredient{
+			{Name: "flour", Quantity: &quantity, Unit: &unit},
+		},
+		SharerID:   testOwnerID,
+		SharerName: "Owner",
+	})
+	if duplicate {
+		t.Fatalf("Expected %s to be a new recipe", name)
+	}
+	return stored
+}
+
+func TestRecipeEditing_BroadcastsUpdatesAndRemovals(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	pancakes := addRecipe(t, id, "Pancakes", "https://example.com/pancakes")
+	waffles := addRecipe(t, id, "Belgian waffles with a crisp shell", "https://example.com/waffles")
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	bob := dialMix(t, server.URL, id)
+	defer bob.Close()
+	sendMessage(t, bob, "USER_IDENTIFY", identifyPayload(t, id, "bob", "Bob"))
+	readMessageOfType(t, bob, "PRESENCE_STATE")
+
+	sendMessage(t, alice, "RECIPE_UPDATE", map[string]any{
+		"recipeId":    pancakes.ID,
+		"version":     1,
+		"ingredients": []map[string]any{{"name": "buckwheat flour", "quantity": "2", "unit": "cups"}},
+	})
+	updates := readMessageOfType(t, bob, "RECIPE_UPDATES")
+	updated := updates["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	if updated["name"] != "Pancakes" || updated["version"].(float64) != 2 || updated["ingredients"].([]any)[0].(map[string]any)["name"] != "buckwheat flour" {
+		t.Errorf("Expected corrected ingredients with the name kept, got %v", updated)
+	}
+	if updatedAt, _ := time.Parse(time.RFC3339Nano, updated["updatedAt"].(string)); !updatedAt.After(pancakes.UpdatedAt) {
+		t.Errorf("Expected updatedAt to move forward, got %v", updated["updatedAt"])
+	}
+
+	sendMessage(t, alice, "RECIPE_UPDATE", map[string]any{"recipeId": pancakes.ID, "version": 2, "ingredients": []map[string]any{{"name": " "}}})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "INVALID_PAYLOAD" {
+		t.Errorf("Expected INVALID_PAYLOAD for a nameless ingredient, got %v", code)
+	}
+
+	sendMessage(t, alice, "RECIPE_REMOVE", map[string]any{"recipeId": pancakes.ID, "version": 2})
+	removals := readMessageOfType(t, bob, "RECIPE_REMOVALS")
+	if ids := removals["data"].(map[string]any)["recipeIds"].([]any); len(ids) != 1 || ids[0] != pancakes.ID {
+		t.Errorf("Expected pancakes to be removed, got %v", ids)
+	}
+
+	sendMessage(t, alice, "RECIPE_REMOVE", map[string]any{"recipeId": pancakes.ID, "version": 2})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "RECIPE_NOT_FOUND" {
+		t.Errorf("Expected RECIPE_NOT_FOUND for a removed recipe, got %v", code)
+	}
+
+	owner := identityToken(t, testOwnerID, "Owner")
+	path := "/api/v1/mixes/" + id + "/recipes/" + waffles.ID
+	if resp, body := doJSON(router, "PATCH", path, owner, `{"version": 1, "name": "Waffles"}`); resp.Code != http.StatusOK || body["name"] != "Waffles" {
+		t.Errorf("Expected rename over REST, got %d: %v", resp.Code, body)
+	}
+	readMessageOfType(t, bob, "RECIPE_UPDATES")
+
+	if resp, _ := doJSON(router, "DELETE", path+"?version=2", owner, ""); resp.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 on delete, got %d", resp.Code)
+	}
+	readMessageOfType(t, bob, "RECIPE_REMOVALS")
+
+	if resp, _ := doJSON(router, "DELETE", path+"?version=2", owner, ""); resp.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a removed recipe, got %d", resp.Code)
+	}
+	if count := ws.Recipes.GetMixRecipeCount(id); count != 0 {
+		t.Errorf("Expected the mix to be empty, got %d recipes", count)
+	}
+}
+
+func TestRecipeEditing_RejectsStaleVersions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	soup := addRecipe(t, id, "Tomato soup", "https://example.com/soup")
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	// The owner's edit lands first, so Alice's edit of the same version is stale
+	owner := identityToken(t, testOwnerID, "Owner")
+	path := "/api/v1/mixes/" + id + "/recipes/" + soup.ID
+	if resp, _ := doJSON(router, "PATCH", path, owner, `{"version": 1, "name": "Roasted tomato soup"}`); resp.Code != http.StatusOK {
+		t.Fatalf("Expected the first edit to succeed, got %d", resp.Code)
+	}
+	readMessageOfType(t, alice, "RECIPE_UPDATES")
+
+	sendMessage(t, alice, "RECIPE_UPDATE", map[string]any{"recipeId": soup.ID, "version": 1, "name": "Tomato bisque"})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "VERSION_CONFLICT" {
+		t.Errorf("Expected VERSION_CONFLICT, got %v", code)
+	}
+	resync := readMessageOfType(t, alice, "RECIPE_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	if resync["name"] != "Roasted tomato soup" || resync["version"].(float64) != 2 {
+		t.Errorf("Expected the current recipe to resync, got %v", resync)
+	}
+
+	resp, conflict := doJSON(router, "DELETE", path+"?version=1", owner, "")
+	if resp.Code != http.StatusConflict || conflict["recipe"].(map[string]any)["version"].(float64) != 2 {
+		t.Errorf("Expected 409 with the current recipe, got %d: %v", resp.Code, conflict)
+	}
+	if resp, _ := doJSON(router, "PATCH", path, owner, `{"name": "No version"}`); resp.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a version, got %d", resp.Code)
+	}
+	invalid := map[string]string{
+		"empty ingredients":     `{"version": 2, "ingredients": []}`,
+		"too many ingredients":  `{"version": 2, "ingredients": [` + strings.Repeat(`{"name": "salt"},`, recipe.MaxIngredients) + `{"name": "salt"}]}`,
+		"long ingredient name":  `{"version": 2, "ingredients": [{"name": "` + strings.Repeat("a", recipe.MaxIngredientNameLength+1) + `"}]}`,
+		"blank ingredient name": `{"version": 2, "ingredients": [{"name": " "}]}`,
+	}
+	for name, body := range invalid {
+		if resp, _ := doJSON(router, "PATCH", path, owner, body); resp.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", name, resp.Code)
+		}
+	}
+
+	current, _ := ws.Recipes.GetMixRecipe(id, soup.ID)
+	if current.Name != "Roasted tomato soup" || len(current.Ingredients) == 0 {
+		t.Errorf("Expected rejected edits to leave the recipe alone, got %+v", current)
+	}
+}
+
+func TestRecipeText_ParsesPastedRecipes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	sendMessage(t, alice, "RECIPE_TEXT_REQUEST", map[string]any{
+		"text": "# Grandma's scones\n\nIngredients:\n- 2 cups flour\n- 1/2 cup butter\nFor the glaze:\n* 100g icing sugar\n\nMethod\n1. Rub the butter into the flour.\n",
+	})
+	progress := readMessageOfType(t, alice, "RECIPE_PROGRESS")
+	if url := progress["data"].(map[string]any)["request"].(map[string]any)["url"]; url != "" {
+		t.Errorf("Expected progress without a url, got %v", url)
+	}
+
+	additions := readMessageOfType(t, alice, "RECIPE_ADDITIONS")
+	scones := additions["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+	ingredients := scones["ingredients"].([]any)
+	if scones["name"] != "Grandma's scones" || scones["url"] != "" || scones["sharerId"] != "alice" || len(ingredients) != 3 {
+		t.Fatalf("Expected scones with three ingredients, got %v", scones)
+	}
+	if butter := ingredients[1].(map[string]any); butter["name"] != "butter" || butter["quantity"] != "1/2" || butter["unit"] != "cup" {
+		t.Errorf("Expected 1/2 cup butter, got %v", butter)
+	}
+
+	sendMessage(t, alice, "RECIPE_TEXT_REQUEST", map[string]any{"text": "Just stir it until it looks right."})
+	if code := readMessageOfType(t, alice, "ERROR")["data"].(map[string]any)["code"]; code != "NO_INGREDIENTS" {
+		t.Errorf("Expected NO_INGREDIENTS, got %v", code)
+	}
+
+	owner := identityToken(t, testOwnerID, "Owner")
+	resp, salad := doJSON(router, "POST", "/api/v1/mixes/"+id+"/recipes/text", owner, `{"name": "Salad", "text": "3 tomatoes\n1 cucumber\nolive oil"}`)
+	if resp.Code != http.StatusCreated || salad["name"] != "Salad" || len(salad["ingredients"].([]any)) != 2 || salad["sharerName"] != "Owner" {
+		t.Errorf("Expected a salad with two ingredients over REST, got %d: %v", resp.Code, salad)
+	}
+	readMessageOfType(t, alice, "RECIPE_ADDITIONS")
+
+	if resp, _ := doJSON(router, "POST", "/api/v1/mixes/"+id+"/recipes/text", owner, `{"text": "no ingredients here"}`); resp.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 without ingredients, got %d", resp.Code)
+	}
+	if count := ws.Recipes.GetMixRecipeCount(id); count != 2 {
+		t.Errorf("Expected two recipes in the mix, got %d", count)
+	}
+}
+
+func TestRecipeService_KeepsRecipesAcrossRestarts(t *testing.T) {
+	mixID := uuid.New().String()
+
+	before := recipe.NewRecipeService()
+	added, _ := before.AddRecipe(mixID, &models.Recipe{Name: "Soda bread", Ingredients: []models.Ingredient{recipe.ParseIngredient("500g flour")}})
+	name := "Irish soda bread"
+	before.UpdateRecipe(mixID, added.ID, added.Version, recipe.RecipeUpdate{Name: &name})
+
+	// Plans and grocery lists persist, so the recipes they refer to must too
+	after := recipe.NewRecipeService()
+	stored, err := after.GetMixRecipe(mixID, added.ID)
+	if err != nil || stored.Name != "Irish soda bread" || stored.Version != 2 {
+		t.Fatalf("Expected the edited recipe to be read back, got %+v (%v)", stored, err)
+	}
+
+	after.ClearMix(mixID)
+	if count := recipe.NewRecipeService().GetMixRecipeCount(mixID); count != 0 {
+		t.Errorf("Expected a cleared mix to stay empty, got %d recipes", count)
+	}
+}
+
+func TestRecipeEditing_BroadcastsInterleavedEditsInOrder(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	soup := addRecipe(t, id, "Tomato soup", "https://example.com/soup")
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	// Several editors keep retrying against the latest version, so their
+	// edits interleave
+	const editors, editsEach = 8, 20
+	var wg sync.WaitGroup
+	for editor := range editors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for edit := range editsEach {
+				name := fmt.Sprintf("Soup %d.%d", editor, edit)
+				for {
+					current, _ := ws.Recipes.GetMixRecipe(id, soup.ID)
+					if _, err := ws.UpdateRecipe(id, soup.ID, current.Version, recipe.RecipeUpdate{Name: &name}, ""); err == nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	last := float64(soup.Version)
+	for range editors * editsEach {
+		updated := readMessageOfType(t, alice, "RECIPE_UPDATES")["data"].(map[string]any)["list"].([]any)[0].(map[string]any)
+		if version := updated["version"].(float64); version != last+1 {
+			t.Fatalf("Expected version %v next, got %v", last+1, version)
+		}
+		last++
+	}
+}
+
+func TestRecipeEditing_BroadcastsAdditionsInOrderWithEdits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	// Each recipe is edited, and half of them removed, once stored and
+	// before its addition is broadcast
+	const cakes = 20
+	var wg sync.WaitGroup
+	for n := range cakes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprintf("Cake %d", n)
+			edit := func(phase, status, message string) {
+				if phase != "complete" {
+					return
+				}
+				for _, stored := range ws.Recipes.GetMixRecipes(id) {
+					if stored.Name != name {
+						continue
+					}
+					edited := name + " (edited)"
+					updated, err := ws.UpdateRecipe(id, stored.ID, stored.Version, recipe.RecipeUpdate{Name: &edited}, "")
+					if err != nil {
+						t.Errorf("Failed to edit %s: %v", name, err)
+						return
+					}
+					if n%2 == 0 {
+						if _, err := ws.RemoveRecipe(id, stored.ID, updated.Version, ""); err != nil {
+							t.Errorf("Failed to remove %s: %v", name, err)
+						}
+					}
+				}
+			}
+			text := fmt.Sprintf("%d cups flour\n1 egg", n+1)
+			if _, err := ws.AddRecipeFromText(id, text, name, false, testOwnerID, "Owner", "", edit); err != nil {
+				t.Errorf("Failed to add %s: %v", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Apply the broadcasts as the web client does, keeping the newest version
+	seen := make(map[string]float64)
+	keep := func(list any) {
+		for _, r := range list.([]any) {
+			r := r.(map[string]any)
+			if version, ok := seen[r["id"].(string)]; !ok || r["version"].(float64) > version {
+				seen[r["id"].(string)] = r["version"].(float64)
+			}
+		}
+	}
+	for {
+		alice.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+		var msg map[string]any
+		if err := alice.ReadJSON(&msg); err != nil {
+			break
+		}
+		data, _ := msg["data"].(map[string]any)
+		switch msg["type"] {
+		case "RECIPE_ADDITIONS":
+			keep(data["list"])
+		case "RECIPE_UPDATES":
+			keep(data["list"])
+		case "RECIPE_REMOVALS":
+			for _, removed := range data["recipeIds"].([]any) {
+				delete(seen, removed.(string))
+			}
+		}
+	}
+
+	stored := ws.Recipes.GetMixRecipes(id)
+	if len(seen) != len(stored) {
+		t.Errorf("Expected clients to have the %d recipes left, got %d", len(stored), len(seen))
+	}
+	for _, r := range stored {
+		if seen[r.ID] != float64(r.Version) {
+			t.Errorf("Expected %s at version %d, got %v", r.Name, r.Version, seen[r.ID])
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"kitchenmix/api/internal/routes"
+)
+
+func TestMixRoles_ViewersCanChatButNotShareRecipes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	owner := identityToken(t, testOwnerID, "Owner")
+
+	resp, invite := doJSON(router, "POST", "/api/v1/mixes/"+id+"/invites", owner, `{"role": "viewer"}`)
+	if resp.Code != http.StatusCreated || invite["role"] != "viewer" {
+		t.Fatalf("Expected a viewer invite, got %d: %v", resp.Code, invite)
+	}
+
+	viewer := dialMix(t, server.URL, id)
+	defer viewer.Close()
+	sendMessage(t, viewer, "USER_IDENTIFY", map[string]any{
+		"token":  identityToken(t, "vera", "Vera"),
+		"invite": invite["token"],
+	})
+
+	presence := readMessageOfType(t, viewer, "PRESENCE_STATE")
+	users := presence["data"].(map[string]any)["users"].([]any)
+	if len(users) != 1 || users[0].(map[string]any)["role"] != "viewer" {
+		t.Errorf("Expected to join as a viewer, got %v", users)
+	}
+
+	sendMessage(t, viewer, "RECIPE_URL_REQUEST", map[string]any{
+		"sharerId":   "vera",
+		"sharerName": "Vera",
+		"url":        "https://example.com/recipe",
+	})
+	denied := readMessageOfType(t, viewer, "ERROR")
+	if code := denied["data"].(map[string]any)["code"]; code != "FORBIDDEN" {
+		t.Errorf("Expected FORBIDDEN for a viewer sharing a recipe, got %v", code)
+	}
+
+	sendMessage(t, viewer, "CHAT_MESSAGE", map[string]any{
+		"type":    "MESSAGE",
+		"payload": map[string]any{"text": "looks tasty"},
+	})
+	readMessageOfType(t, viewer, "CHAT_MESSAGE")
+
+	vera := identityToken(t, "vera", "Vera")
+	if resp, _ := doJSON(router, "PATCH", "/api/v1/mixes/"+id, vera, `{"title": "Mine now"}`); resp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a viewer renaming the mix, got %d", resp.Code)
+	}
+	if resp, _ := doJSON(router, "GET", "/api/v1/mixes/"+id+"/recipes", vera, ""); resp.Code != http.StatusOK {
+		t.Errorf("Expected a viewer to read recipes, got %d", resp.Code)
+	}
+	if resp, _ := doJSON(router, "GET", "/api/v1/mixes/"+id+"/recipes", identityToken(t, "stranger", "Stranger"), ""); resp.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-member, got %d", resp.Code)
+	}
+
+	if resp, member := doJSON(router, "PATCH", "/api/v1/mixes/"+id+"/members/vera", owner, `{"role": "editor"}`); resp.Code != http.StatusOK || member["role"] != "editor" {
+		t.Fatalf("Expected Vera to become an editor, got %d: %v", resp.Code, member)
+	}
+	presence = readMessageOfType(t, viewer, "PRESENCE_STATE")
+	if role := presence["data"].(map[string]any)["users"].([]any)[0].(map[string]any)["role"]; role != "editor" {
+		t.Errorf("Expected presence to show the new role, got %v", role)
+	}
+
+	if resp, _ := doJSON(router, "PATCH", "/api/v1/mixes/"+id+"/members/"+testOwnerID, owner, `{"role": "viewer"}`); resp.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 when demoting the owner, got %d", resp.Code)
+	}
+}
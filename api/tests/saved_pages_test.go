@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"kitchenmix/api/internal/routes"
+	"kitchenmix/api/internal/services/recipe"
+	ws "kitchenmix/api/internal/websocket"
+)
+
+// savedRecipePage is a page saved with "HTML only", which records its URL in a comment
+func savedRecipePage(url string, name string, ingredients ...string) string {
+	return `<!DOCTYPE html>
+<!-- saved from url=(0040)` + url + ` -->
+<html><head><title>` + name + `</title>
+<script type="application/ld+json">{"@context": "https://schema.org", "@type": "Recipe", "name": "` + name + `", "recipeIngredient": ["` + strings.Join(ingredients, `", "`) + `"]}</script>
+</head><body><h1>` + name + `</h1></body></html>`
+}
+
+const savedMHTML = `From: <Saved by Blink>
+Snapshot-Content-Location: https://example.com/recipes/flapjacks
+Subject: Flapjacks
+MIME-Version: 1.0
+Content-Type: multipart/related;
+	type="text/html";
+	boundary="----MultipartBoundary--flapjacks----"
+
+------MultipartBoundary--flapjacks----
+Content-Type: text/html
+Content-Transfer-Encoding: quoted-printable
+Content-Location: https://example.com/recipes/flapjacks
+
+<html><head><script type=3D"application/ld+json">{"@type": "Recipe", "name": =
+"Flapjacks", "recipeIngredient": ["250g oats", "125g butter", "4 tbsp golden syrup"]}</script>=
+</head><body>Flapjacks</body></html>
+------MultipartBoundary--flapjacks----
+Content-Type: image/png
+Content-Transfer-Encoding: base64
+Content-Location: https://example.com/flapjacks.png
+
+iVBORw0KGgo=
+------MultipartBoundary--flapjacks------
+`
+
+func zipPages(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("Failed to add %s to zip: %v", name, err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to write zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadSavedPages_Formats(t *testing.T) {
+	pages, err := recipe.ReadSavedPages("Scones.html", []byte(savedRecipePage("https://example.com/recipes/scones", "Scones", "2 cups flour")))
+	if err != nil || len(pages) != 1 || pages[0].URL != "https://example.com/recipes/scones" {
+		t.Errorf("Expected a saved page with its URL, got %+v (%v)", pages, err)
+	}
+
+	pages, err = recipe.ReadSavedPages("Flapjacks.mhtml", []byte(savedMHTML))
+	if err != nil || len(pages) != 1 {
+		t.Fatalf("Expected one page in the MHTML archive, got %+v (%v)", pages, err)
+	}
+	if pages[0].URL != "https://example.com/recipes/flapjacks" || !strings.Contains(pages[0].HTML, `type="application/ld+json"`) || !strings.Contains(pages[0].HTML, `"name": "Flapjacks"`) {
+		t.Errorf("Expected the decoded HTML part, got %+v", pages[0])
+	}
+
+	archive := zipPages(t, map[string]string{
+		"export/Scones.html":            savedRecipePage("https://example.com/recipes/scones", "Scones", "2 cups flour"),
+		"export/Flapjacks.mht":          savedMHTML,
+		"export/Scones_files/a.png":     "not a page",
+		"__MACOSX/export/._Scones.html": "resource fork",
+	})
+	if pages, err := recipe.ReadSavedPages("upload", archive); err != nil || len(pages) != 2 {
+		t.Errorf("Expected both pages from a zip recognised by its content, got %d (%v)", len(pages), err)
+	}
+
+	if _, err := recipe.ReadSavedPages("notes.txt", []byte("just some notes")); err != recipe.ErrUnsupportedPageFile {
+		t.Errorf("Expected ErrUnsupportedPageFile, got %v", err)
+	}
+}
+
+func TestImportMixRecipes_SummarisesPages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+
+	alice := dialMix(t, server.URL, id)
+	defer alice.Close()
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "PRESENCE_STATE")
+
+	token := identityToken(t, testOwnerID, "Owner")
+	owner := dialMix(t, server.URL, id)
+	defer owner.Close()
+	sendMessage(t, owner, "USER_IDENTIFY", map[string]any{"token": token})
+	readMessageOfType(t, owner, "PRESENCE_STATE")
+
+	crumble := savedRecipePage("https://example.com/recipes/crumble", "Apple crumble", "4 apples", "100g butter", "150g flour")
+	resp, accepted := uploadImport(t, router, id, token, map[string][]byte{
+		"Crumble.html": []byte(crumble),
+		"pages.zip": zipPages(t, map[string]string{
+			"Crumble again.html": crumble,
+			"Flapjacks.mhtml":    savedMHTML,
+		}),
+		"notes.txt": []byte("just some notes"),
+	})
+	if resp.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", resp.Code, resp.Body.String())
+	}
+
+	result := readImportResult(t, owner, accepted["importId"].(string))
+	added, skipped, failed := result["added"].([]any), result["skipped"].([]any), result["failed"].([]any)
+	if len(added) != 2 || len(skipped) != 1 || len(failed) != 1 {
+		t.Fatalf("Expected two added, one skipped and one failed, got %v", result)
+	}
+	if failed[0].(map[string]any)["name"] != "notes.txt" {
+		t.Errorf("Expected the text file to fail, got %v", failed)
+	}
+	for _, entry := range added {
+		if r := entry.(map[string]any); r["sharerName"] != "Owner" || !strings.HasPrefix(r["url"].(string), "https://example.com/recipes/") {
+			t.Errorf("Expected recipes shared by the owner with their saved URLs, got %v", r)
+		}
+	}
+
+	additions := readMessageOfType(t, alice, "RECIPE_ADDITIONS")
+	if list := additions["data"].(map[string]any)["list"].([]any); len(list) != 2 {
+		t.Errorf("Expected the added recipes to be broadcast together, got %d", len(list))
+	}
+	if count := ws.Recipes.GetMixRecipeCount(id); count != 2 {
+		t.Errorf("Expected two recipes in the mix, got %d", count)
+	}
+}
+
+func TestImportPages_DoesNotCacheClaimedURLs(t *testing.T) {
+	site := httptest.NewServer(http.NotFoundHandler())
+	defer site.Close()
+
+	// An uploaded page can claim to be any other site's recipe
+	service := recipe.NewRecipeService()
+	claimed := site.URL + "/recipes/soup"
+	result := service.ImportPages([]recipe.SavedPage{{
+		Name: "Soup.html",
+		HTML: savedRecipePage(claimed, "Definitely soup", "1 cup bleach"),
+		URL:  claimed,
+	}}, uuid.New().String(), "mallory", "Mallory", nil)
+	if len(result.Added) != 1 {
+		t.Fatalf("Expected the page to be imported into its own mix, got %+v", result)
+	}
+
+	if shared, err := service.GetRecipeByURL(claimed, uuid.New().String(), "alice", "Alice", nil); err == nil {
+		t.Errorf("Expected another mix to fetch the real page, got the uploaded %q", shared.Name)
+	}
+}
+
+func TestImportMixRecipes_LimitsPagesPerRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	// Each zip is within the per-file limit, but together they are not
+	pages := map[string]string{}
+	for i := range recipe.MaxSavedPages {
+		pages[fmt.Sprintf("Page %d.html", i)] = savedRecipePage(fmt.Sprintf("https://example.com/recipes/%d", i), "Scones", "2 cups flour")
+	}
+	archive := zipPages(t, pages)
+	files := map[string][]byte{}
+	for i := range recipe.MaxImportPages/recipe.MaxSavedPages + 1 {
+		files[fmt.Sprintf("pages %d.zip", i)] = archive
+	}
+
+	id := createMix(t)
+	resp, body := uploadImport(t, router, id, identityToken(t, testOwnerID, "Owner"), files)
+	if resp.Code != http.StatusRequestEntityTooLarge || body["error"] != "import_too_large" {
+		t.Errorf("Expected 413 import_too_large, got %d: %v", resp.Code, body)
+	}
+
+	budget := recipe.NewPageBudget()
+	page := []byte("<html>" + strings.Repeat(" ", recipe.MaxSavedPageBytes-6))
+	for range recipe.MaxImportPageBytes / recipe.MaxSavedPageBytes {
+		if _, err := budget.Read("big.html", page); err != nil {
+			t.Fatalf("Expected pages within the budget to be read, got %v", err)
+		}
+	}
+	if _, err := budget.Read("big.html", page); !errors.Is(err, recipe.ErrImportTooLarge) {
+		t.Errorf("Expected ErrImportTooLarge once the bytes run out, got %v", err)
+	}
+}
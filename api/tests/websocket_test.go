@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -10,9 +11,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"kitchenmix/api/internal/models"
 	"kitchenmix/api/internal/routes"
+	ws "kitchenmix/api/internal/websocket"
 )
 
+// protocolQuery selects the protocol version this test suite speaks
+var protocolQuery = fmt.Sprintf("?version=%d", ws.ProtocolVersion)
+
 func TestWebSocketUpgrade_Success(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -22,8 +28,8 @@ func TestWebSocketUpgrade_Success(t *testing.T) {
 	server := httptest.NewServer(router)
 	defer server.Close()
 
-	id := uuid.New().String()
-	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws/" + id
+	id := createMix(t)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws/" + id + protocolQuery
 
 	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
 	if err != nil {
@@ -82,8 +88,8 @@ func TestWebSocketPing(t *testing.T) {
 	server := httptest.NewServer(router)
 	defer server.Close()
 
-	id := uuid.New().String()
-	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws/" + id
+	id := createMix(t)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws/" + id + protocolQuery
 
 	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
 	if err != nil {
@@ -108,3 +114,429 @@ func TestWebSocketPing(t *testing.T) {
 
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestWebSocketError_RequestCorrelation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws/" + id + protocolQuery
+
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer ws.Close()
+
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var ackMsg map[string]any
+	ws.ReadJSON(&ackMsg)
+
+	requestMsg := map[string]any{
+		"type":      "RECIPE_URL_REQUEST",
+		"timestamp": time.Now().Format(time.RFC3339),
+		"requestId": "req-1",
+		"data":      map[string]any{"sharerId": "someone", "url": "https://example.com"},
+	}
+	if err := ws.WriteJSON(requestMsg); err != nil {
+		t.Fatalf("Failed to send RECIPE_URL_REQUEST: %v", err)
+	}
+
+	var msg map[string]any
+	if err := ws.ReadJSON(&msg); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+
+	if msg["type"] != "ERROR" || msg["requestId"] != "req-1" {
+		t.Fatalf("Expected ERROR for req-1, got %v", msg)
+	}
+
+	data, ok := msg["data"].(map[string]any)
+	if !ok || data["code"] != "NOT_IDENTIFIED" {
+		t.Errorf("Expected NOT_IDENTIFIED error code, got %v", msg["data"])
+	}
+}
+
+func TestWebSocketUpgrade_IncompatibleProtocol(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/v1/ws/" + createMix(t) + "?version=99"
+
+	ws, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer ws.Close()
+
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	var msg map[string]any
+	if err := ws.ReadJSON(&msg); err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+
+	data, _ := msg["data"].(map[string]any)
+	if msg["type"] != "ERROR" || data["code"] != "INCOMPATIBLE_PROTOCOL" {
+		t.Errorf("Expected INCOMPATIBLE_PROTOCOL error, got %v", msg)
+	}
+
+	_, _, err = ws.ReadMessage()
+	if !websocket.IsCloseError(err, 4000) {
+		t.Errorf("Expected close code 4000, got %v", err)
+	}
+}
+
+// testOwnerID owns the mixes made by createMix
+const testOwnerID = "owner"
+
+// createMix creates a mix for clients to connect to, owned by testOwnerID
+func createMix(t *testing.T) string {
+	t.Helper()
+
+	created, err := ws.Mixes.Create("Test mix", "", testOwnerID, "Owner")
+	if err != nil {
+		t.Fatalf("Failed to create mix: %v", err)
+	}
+	return created.ID
+}
+
+// dialMix connects to a mix and consumes the CONNECTION_ACK
+func dialMix(t *testing.T, serverURL string, mixID string) *websocket.Conn {
+	t.Helper()
+
+	wsURL := "ws" + strings.TrimPrefix(serverURL, "http") + "/api/v1/ws/" + mixID + protocolQuery
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var ackMsg map[string]any
+	if err := conn.ReadJSON(&ackMsg); err != nil {
+		t.Fatalf("Failed to read CONNECTION_ACK: %v", err)
+	}
+	return conn
+}
+
+// identifyPayload is a USER_IDENTIFY payload with a freshly minted token for the
+// user and an editor invite, so they join the mix if they aren't a member yet
+func identifyPayload(t *testing.T, mixID string, userID string, userName string) map[string]any {
+	t.Helper()
+
+	invite, err := ws.Mixes.CreateInvite(mixID, testOwnerID, models.RoleEditor, 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to create invite: %v", err)
+	}
+	return map[string]any{
+		"token":  identityToken(t, userID, userName),
+		"invite": invite.Token,
+	}
+}
+
+// identityToken mints a signed identity token for a user
+func identityToken(t *testing.T, userID string, userName string) string {
+	t.Helper()
+
+	_, token, err := ws.Identities.Mint(userID, userName)
+	if err != nil {
+		t.Fatalf("Failed to mint identity: %v", err)
+	}
+	return token
+}
+
+// sendMessage writes a client message envelope
+func sendMessage(t *testing.T, conn *websocket.Conn, messageType string, data any) {
+	t.Helper()
+
+	msg := map[string]any{
+		"type":      messageType,
+		"timestamp": time.Now().Format(time.RFC3339),
+		"data":      data,
+	}
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatalf("Failed to send %s: %v", messageType, err)
+	}
+}
+
+// readMessageOfType skips messages until one of messageType arrives
+func readMessageOfType(t *testing.T, conn *websocket.Conn, messageType string) map[string]any {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg map[string]any
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Failed waiting for %s: %v", messageType, err)
+		}
+		if msg["type"] == messageType {
+			return msg
+		}
+	}
+}
+
+func TestWebSocketResume_ReplaysMissedEvents(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	chat := func(text string) map[string]any {
+		return map[string]any{"type": "MESSAGE", "payload": map[string]any{"text": text}}
+	}
+
+	alice := dialMix(t, server.URL, id)
+	sendMessage(t, alice, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, alice, "SYNC")
+
+	bob := dialMix(t, server.URL, id)
+	defer bob.Close()
+	sendMessage(t, bob, "USER_IDENTIFY", identifyPayload(t, id, "bob", "Bob"))
+	readMessageOfType(t, bob, "SYNC")
+
+	sendMessage(t, bob, "CHAT_MESSAGE", chat("first"))
+	first := readMessageOfType(t, alice, "CHAT_MESSAGE")
+	lastSeq := first["seq"].(float64)
+
+	alice.Close()
+	readMessageOfType(t, bob, "USER_LEFT")
+	sendMessage(t, bob, "CHAT_MESSAGE", chat("second"))
+
+	alice = dialMix(t, server.URL, id)
+	defer alice.Close()
+	resume := identifyPayload(t, id, "alice", "Alice")
+	resume["lastSeq"] = lastSeq
+	sendMessage(t, alice, "USER_IDENTIFY", resume)
+
+	sync := readMessageOfType(t, alice, "SYNC")
+	if mode := sync["data"].(map[string]any)["mode"]; mode != "replay" {
+		t.Fatalf("Expected replay, got %v", mode)
+	}
+
+	// Alice's own USER_LEFT is replayed first, then the missed chat
+	left := readMessageOfType(t, alice, "USER_LEFT")
+	if left["data"].(map[string]any)["userId"] != "alice" {
+		t.Errorf("Expected Alice's own USER_LEFT to be replayed, got %v", left)
+	}
+	missed := readMessageOfType(t, alice, "CHAT_MESSAGE")
+	text := missed["data"].(map[string]any)["payload"].(map[string]any)["text"]
+	if text != "second" || missed["seq"].(float64) <= lastSeq {
+		t.Errorf("Expected missed chat message after seq %v, got %v", lastSeq, missed)
+	}
+}
+
+func TestWebSocketResume_ReplaysOwnEventsToAnotherTab(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+
+	phone := dialMix(t, server.URL, id)
+	defer phone.Close()
+	sendMessage(t, phone, "USER_IDENTIFY", identifyPayload(t, id, "alice", "Alice"))
+	readMessageOfType(t, phone, "SYNC")
+
+	bob := dialMix(t, server.URL, id)
+	defer bob.Close()
+	sendMessage(t, bob, "USER_IDENTIFY", identifyPayload(t, id, "bob", "Bob"))
+	joined := readMessageOfType(t, phone, "USER_JOINED")
+	lastSeq := joined["seq"].(float64)
+
+	sendMessage(t, phone, "CHAT_MESSAGE", map[string]any{"type": "CHAT_MESSAGE", "payload": map[string]any{"text": "from my phone"}})
+	readMessageOfType(t, bob, "CHAT_MESSAGE")
+
+	laptop := dialMix(t, server.URL, id)
+	defer laptop.Close()
+	resume := identifyPayload(t, id, "alice", "Alice")
+	resume["lastSeq"] = lastSeq
+	sendMessage(t, laptop, "USER_IDENTIFY", resume)
+
+	if mode := readMessageOfType(t, laptop, "SYNC")["data"].(map[string]any)["mode"]; mode != "replay" {
+		t.Fatalf("Expected replay, got %v", mode)
+	}
+	missed := readMessageOfType(t, laptop, "CHAT_MESSAGE")
+	if text := missed["data"].(map[string]any)["payload"].(map[string]any)["text"]; text != "from my phone" {
+		t.Errorf("Expected Alice's own message sent from another tab, got %v", missed)
+	}
+}
+
+func TestEventLog_TooFarBehind(t *testing.T) {
+	eventLog := ws.NewEventLog(time.Hour)
+	for i := 0; i < 600; i++ {
+		eventLog.Append(ws.WSMessage{Type: ws.MessageTypeChatMessage})
+	}
+
+	if _, ok := eventLog.Since(10); ok {
+		t.Error("Expected events evicted from the log to require a snapshot")
+	}
+	if _, ok := eventLog.Since(700); ok {
+		t.Error("Expected a sequence from the future to require a snapshot")
+	}
+	if events, ok := eventLog.Since(590); !ok || len(events) != 10 || events[0].Seq != 591 {
+		t.Errorf("Expected 10 events from seq 591, got %d (ok=%v)", len(events), ok)
+	}
+}
+
+func TestEventLog_ExpiresOldEvents(t *testing.T) {
+	eventLog := ws.NewEventLog(20 * time.Millisecond)
+	eventLog.Append(ws.WSMessage{Type: ws.MessageTypeChatMessage})
+	eventLog.Append(ws.WSMessage{Type: ws.MessageTypeChatMessage})
+
+	time.Sleep(30 * time.Millisecond)
+	eventLog.Append(ws.WSMessage{Type: ws.MessageTypeChatMessage})
+
+	if _, ok := eventLog.Since(1); ok {
+		t.Error("Expected expired events to require a snapshot")
+	}
+	if events, ok := eventLog.Since(2); !ok || len(events) != 1 || events[0].Seq != 3 {
+		t.Errorf("Expected the one recent event, got %d (ok=%v)", len(events), ok)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	eventLog.Prune()
+	if events, ok := eventLog.Since(3); !ok || len(events) != 0 {
+		t.Errorf("Expected a client up to date to need nothing once the log is empty, got %d (ok=%v)", len(events), ok)
+	}
+	if _, ok := eventLog.Since(2); ok {
+		t.Error("Expected a client behind an emptied log to require a snapshot")
+	}
+}
+
+func TestConnectionPool_DisconnectsSlowConsumer(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	serverConns := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("Failed to upgrade: %v", err)
+			return
+		}
+		serverConns <- conn
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect to WebSocket: %v", err)
+	}
+	defer client.Close()
+
+	// Without a WritePump nothing drains Send, so the connection fills up and falls behind
+	mixID := uuid.New().String()
+	conn := ws.NewConnection(uuid.New().String(), mixID, <-serverConns)
+	ws.Pool.Register(conn)
+	defer ws.Pool.Unregister(conn.ID)
+
+	before := ws.Pool.Stats()
+	message, _ := ws.NewMessage(ws.MessageTypeChatMessage, map[string]any{})
+	for i := 0; i < cap(conn.Send)+8; i++ {
+		ws.Pool.BroadcastToUUID(mixID, message)
+	}
+
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := client.ReadMessage(); !websocket.IsCloseError(err, ws.CloseSlowConsumer) {
+		t.Errorf("Expected close code %d, got %v", ws.CloseSlowConsumer, err)
+	}
+
+	stats := ws.Pool.Stats()
+	if conn.Dropped() != 8 || stats.DroppedByConnection[conn.ID] != 8 || stats.SlowDisconnects != before.SlowDisconnects+1 {
+		t.Errorf("Expected 8 dropped messages and one slow disconnect, got %+v", stats)
+	}
+}
+
+// waitForConnections polls until the mix has n registered connections
+func waitForConnections(t *testing.T, mixID string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(ws.Pool.GetUUIDConnections(mixID)) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d connections in mix %s", n, mixID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebSocketPresence_AggregatesConnectionsPerUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	routes.Setup(router)
+
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	id := createMix(t)
+	identify := identifyPayload(t, id, "alice", "Alice")
+
+	aliceTab1 := dialMix(t, server.URL, id)
+	defer aliceTab1.Close()
+	sendMessage(t, aliceTab1, "USER_IDENTIFY", identify)
+	readMessageOfType(t, aliceTab1, "PRESENCE_STATE")
+
+	bob := dialMix(t, server.URL, id)
+	defer bob.Close()
+	sendMessage(t, bob, "USER_IDENTIFY", identifyPayload(t, id, "bob", "Bob"))
+	readMessageOfType(t, bob, "PRESENCE_STATE")
+
+	aliceTab2 := dialMix(t, server.URL, id)
+	sendMessage(t, aliceTab2, "USER_IDENTIFY", identify)
+
+	presence := readMessageOfType(t, aliceTab2, "PRESENCE_STATE")
+	users := presence["data"].(map[string]any)["users"].([]any)
+	if len(users) != 2 {
+		t.Fatalf("Expected Alice and Bob in presence, got %v", users)
+	}
+	alice := users[0].(map[string]any)
+	if alice["userId"] != "alice" || alice["connections"].(float64) != 2 {
+		t.Errorf("Expected Alice with two connections, got %v", alice)
+	}
+
+	// Closing one of Alice's tabs leaves her present, closing the last one doesn't
+	aliceTab2.Close()
+	waitForConnections(t, id, 2)
+	aliceTab1.Close()
+	waitForConnections(t, id, 1)
+
+	bob.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var events []string
+	for {
+		var msg map[string]any
+		if err := bob.ReadJSON(&msg); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		if msg["type"] == "USER_LEFT" {
+			break
+		}
+		events = append(events, msg["type"].(string))
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected Alice's second tab to produce no events, got %v", events)
+	}
+
+	// Any extra USER_LEFT would arrive before the reply to this chat message
+	sendMessage(t, bob, "CHAT_MESSAGE", map[string]any{"type": "MESSAGE", "payload": map[string]any{"text": "bye"}})
+	var next map[string]any
+	if err := bob.ReadJSON(&next); err != nil || next["type"] != "CHAT_MESSAGE" {
+		t.Errorf("Expected a single USER_LEFT for Alice, got %v (%v)", next, err)
+	}
+}
@@ -0,0 +1,188 @@
+import { useState, type FormEvent } from 'react'
+import { Trash2 } from 'lucide-react'
+import { Checkbox } from '@/components/ui/checkbox'
+import type { User } from '@/types'
+import type { GroceryItemChanges } from '@/hooks/useMessagingService'
+import type { GroceryListItem } from '@/types/protocol'
+
+interface GroceryListProps {
+  items: GroceryListItem[]
+  // Members who can be asked to buy an item; anyone already assigned stays listed
+  members: User[]
+  // Viewers see the list and who is buying what, but can't change it
+  readOnly?: boolean
+  onAdd: (name: string, quantity?: string, unit?: string) => void
+  onUpdate: (item: GroceryListItem, changes: GroceryItemChanges) => void
+  onCheck: (item: GroceryListItem, checked: boolean) => void
+  onRemove: (item: GroceryListItem) => void
+  onClearChecked: () => void
+  // Adds what this week's meal plan needs, less the pantry
+  onFillFromPlan: () => void
+}
+
+export default function GroceryList({ items, members, readOnly, onAdd, onUpdate, onCheck, onRemove, onClearChecked, onFillFromPlan }: GroceryListProps) {
+  const [name, setName] = useState('')
+  const [quantity, setQuantity] = useState('')
+  const [unit, setUnit] = useState('')
+
+  const handleAdd = (event: FormEvent) => {
+    event.preventDefault()
+    if (!name.trim()) return
+    onAdd(name.trim(), quantity.trim() || undefined, unit.trim() || undefined)
+    setName('')
+    setQuantity('')
+    setUnit('')
+  }
+
+  // Unticked items first so the shop trip works down the list
+  const ordered = [...items.filter(item => !item.checked), ...items.filter(item => item.checked)]
+  const checkedCount = items.length - items.filter(item => !item.checked).length
+
+  return (
+    <div className="flex flex-col gap-4 p-4">
+      {!readOnly && (
+        <form onSubmit={handleAdd} className="flex gap-2">
+          <input
+            value={name}
+            onChange={e => setName(e.target.value)}
+            placeholder="Add an item..."
+            className="flex-1 h-9 rounded-md border bg-background px-3 text-sm"
+          />
+          <input
+            value={quantity}
+            onChange={e => setQuantity(e.target.value)}
+            placeholder="Qty"
+            className="w-16 h-9 rounded-md border bg-background px-2 text-sm"
+          />
+          <input
+            value={unit}
+            onChange={e => setUnit(e.target.value)}
+            placeholder="Unit"
+            className="w-20 h-9 rounded-md border bg-background px-2 text-sm"
+          />
+          <button type="submit" className="h-9 px-3 rounded-md bg-primary text-primary-foreground text-sm cursor-pointer">
+            Add
+          </button>
+        </form>
+      )}
+      {!readOnly && (
+        <button onClick={onFillFromPlan} className="self-start text-sm text-primary hover:underline cursor-pointer">
+          Add this week's meal plan
+        </button>
+      )}
+
+      {items.length === 0 ? (
+        <p className="text-muted-foreground text-center py-8">
+          Nothing to buy yet.
+        </p>
+      ) : (
+        <>
+          <p className="text-sm text-muted-foreground">
+            {checkedCount} of {items.length} ticked off
+          </p>
+          <ul className="flex flex-col divide-y">
+            {ordered.map(item => (
+              <GroceryListRow
+                key={item.id}
+                item={item}
+                members={members}
+                readOnly={readOnly}
+                onUpdate={onUpdate}
+                onCheck={onCheck}
+                onRemove={onRemove}
+              />
+            ))}
+          </ul>
+          {!readOnly && checkedCount > 0 && (
+            <button onClick={onClearChecked} className="self-start text-sm text-primary hover:underline cursor-pointer">
+              Clear ticked items
+            </button>
+          )}
+        </>
+      )}
+    </div>
+  )
+}
+
+interface GroceryListRowProps {
+  item: GroceryListItem
+  members: User[]
+  readOnly?: boolean
+  onUpdate: GroceryListProps['onUpdate']
+  onCheck: GroceryListProps['onCheck']
+  onRemove: GroceryListProps['onRemove']
+}
+
+function GroceryListRow({ item, members, readOnly, onUpdate, onCheck, onRemove }: GroceryListRowProps) {
+  const written = [item.quantity, item.unit].filter(Boolean).join(' ')
+  const [amount, setAmount] = useState(written)
+  const [editing, setEditing] = useState(false)
+
+  // Quantities are typed as "2 kg"; the first word is the quantity
+  const commitAmount = () => {
+    setEditing(false)
+    if (amount.trim() === written) return
+    const [quantity = '', ...unit] = amount.trim().split(/\s+/)
+    onUpdate(item, { quantity, unit: unit.join(' ') })
+  }
+
+  const assignees = item.assigneeId && !members.some(m => m.id === item.assigneeId)
+    ? [...members, { id: item.assigneeId, name: item.assigneeName ?? item.assigneeId }]
+    : members
+
+  return (
+    <li className="flex items-center gap-3 py-2">
+      <Checkbox
+        checked={item.checked}
+        disabled={readOnly}
+        onCheckedChange={checked => onCheck(item, checked === true)}
+        aria-label={`Tick off ${item.name}`}
+      />
+      <div className="flex-1 min-w-0">
+        <div className={item.checked ? 'line-through text-muted-foreground' : ''}>{item.name}</div>
+        {item.checked && item.checkedByName && (
+          <div className="text-xs text-muted-foreground">Got by {item.checkedByName}</div>
+        )}
+      </div>
+      {editing ? (
+        <input
+          autoFocus
+          value={amount}
+          onChange={e => setAmount(e.target.value)}
+          onBlur={commitAmount}
+          onKeyDown={e => e.key === 'Enter' && commitAmount()}
+          className="w-24 h-8 rounded-md border bg-background px-2 text-sm"
+        />
+      ) : (
+        <button
+          onClick={() => { setAmount(written); setEditing(true) }}
+          disabled={readOnly}
+          className="min-w-12 text-sm text-muted-foreground text-right hover:text-foreground disabled:hover:text-muted-foreground"
+        >
+          {written || (readOnly ? '' : 'Qty')}
+        </button>
+      )}
+      <select
+        value={item.assigneeId ?? ''}
+        disabled={readOnly}
+        onChange={e => onUpdate(item, { assigneeId: e.target.value })}
+        className="h-8 max-w-32 rounded-md border bg-background px-1 text-sm"
+        aria-label={`Who is buying ${item.name}`}
+      >
+        <option value="">Anyone</option>
+        {assignees.map(member => (
+          <option key={member.id} value={member.id}>{member.name}</option>
+        ))}
+      </select>
+      {!readOnly && (
+        <button
+          onClick={() => onRemove(item)}
+          className="p-1 text-muted-foreground hover:text-destructive cursor-pointer"
+          aria-label={`Remove ${item.name}`}
+        >
+          <Trash2 className="w-4 h-4" />
+        </button>
+      )}
+    </li>
+  )
+}
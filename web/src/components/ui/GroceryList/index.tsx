@@ -0,0 +1 @@
+export { default as GroceryList } from './GroceryList'
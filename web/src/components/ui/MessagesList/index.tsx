@@ -9,6 +9,7 @@ export interface MessagesListProps {
   currentUser: User
   emptyMessage?: string
   onMessageSubmit?: (message: string) => void
+  onLoadEarlier?: () => void
   showInput?: boolean
   inputPlaceholder?: string
 }
@@ -18,6 +19,7 @@ export default function MessagesList({
   currentUser,
   emptyMessage = 'No messages',
   onMessageSubmit,
+  onLoadEarlier,
   showInput = false,
   inputPlaceholder,
 }: MessagesListProps) {
@@ -50,6 +52,14 @@ export default function MessagesList({
     <div className="grid grid-rows-[1fr_auto] h-full min-h-0">
       <div className="pr-2">
         <div className="flex flex-col gap-4 pb-4">
+          {onLoadEarlier && (
+            <button
+              onClick={onLoadEarlier}
+              className="self-center text-sm text-muted-foreground hover:text-foreground cursor-pointer"
+            >
+              Load earlier messages
+            </button>
+          )}
           {messages.map((message) => (
             <MessageItem
               key={message.id}
@@ -1,14 +1,16 @@
-import { ExternalLink } from 'lucide-react'
+import { ExternalLink, Trash2 } from 'lucide-react'
 import { Card, CardContent } from '@/components/ui/card'
 import { Checkbox } from '@/components/ui/checkbox'
-import type { Recipe } from '@/types/websocket'
+import type { Recipe } from '@/types/protocol'
 import { useRecipeContext } from '@/contexts/RecipeContext'
 
 interface RecipeCardProps {
   recipe: Recipe
+  // Omitted for viewers, who can't remove recipes
+  onRemove?: (recipe: Recipe) => void
 }
 
-export default function RecipeCard({ recipe }: RecipeCardProps) {
+export default function RecipeCard({ recipe, onRemove }: RecipeCardProps) {
   const { selectedRecipes, selectRecipe } = useRecipeContext()
   const isSelected = selectedRecipes.includes(recipe.id)
 
@@ -33,7 +35,7 @@ export default function RecipeCard({ recipe }: RecipeCardProps) {
         <div className="mb-3 h-32 w-full rounded-t-md bg-muted overflow-hidden">
           {recipe.image ? (
             <img
-              src={recipe.image}
+              src={recipe.thumbnail ?? recipe.image}
               alt={recipe.name}
               className="h-full w-full object-cover transition-transform duration-200 group-hover:scale-105"
               onError={(e) => {
@@ -76,6 +78,19 @@ export default function RecipeCard({ recipe }: RecipeCardProps) {
           </div>
         </div>
 
+        {onRemove && (
+          <button
+            onClick={(e) => {
+              e.stopPropagation()
+              onRemove(recipe)
+            }}
+            className="absolute top-2 right-2 h-8 w-8 inline-flex items-center justify-center rounded-md bg-background/80 text-muted-foreground opacity-0 group-hover:opacity-100 hover:text-foreground transition-opacity cursor-pointer"
+            title="Remove recipe"
+          >
+            <Trash2 className="h-4 w-4" />
+          </button>
+        )}
+
         <div className="absolute bottom-2 right-2">
           <Checkbox
             checked={isSelected}
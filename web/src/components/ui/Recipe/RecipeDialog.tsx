@@ -1,11 +1,12 @@
 import { useEffect, useState, useRef } from 'react'
 import { Button } from '@/components/ui/button'
+import { Textarea } from '@/components/ui/textarea'
 import { InputGroupAddon, InputGroupInput, InputGroupButton } from '@/components/ui/input-group'
 import { Tooltip, TooltipContent, TooltipProvider, TooltipTrigger } from '@/components/ui/tooltip'
 import { CircleCheck, TriangleAlert, LoaderPinwheel } from 'lucide-react'
 import { useKeydownShortcut } from '@/hooks/useKeydownShortcut'
-import type { RecipeUrlRequestPayload } from '@/types'
-import type { WebSocketMessage } from '@/types/websocket'
+import type { ServerEvent } from '@/services/websocket'
+import type { RecipeUrlRequestPayload } from '@/types/protocol'
 
 // URL validation function
 const isValidUrl = (url: string): boolean | null => {
@@ -38,8 +39,10 @@ export interface RecipeDialogProps {
     name: string
     [key: string]: any
   } | null
-  sendRecipeUrlRequest: (payload: Omit<RecipeUrlRequestPayload, 'id' | 'sentAt'>) => void
-  onMessage: (callback: (wsMessage: WebSocketMessage) => void) => () => void
+  sendRecipeUrlRequest: (payload: RecipeUrlRequestPayload) => void
+  sendRecipeText: (text: string, useAi?: boolean) => void
+  importRecipes: (files: File[]) => Promise<string | undefined>
+  onMessage: (callback: (event: ServerEvent) => void) => () => void
 }
 
 export default function RecipeDialog({
@@ -47,32 +50,44 @@ export default function RecipeDialog({
   onClose,
   user,
   sendRecipeUrlRequest,
+  sendRecipeText,
+  importRecipes,
   onMessage
 }: RecipeDialogProps) {
   const [isLoading, setIsLoading] = useState(false)
   const [shouldClose, setShouldClose] = useState(false)
   const [isValid, setIsValid] = useState<boolean | null>(null);
   const [url, setUrl] = useState<string>("");
+  const [mode, setMode] = useState<'url' | 'text' | 'file'>('url')
+  const [files, setFiles] = useState<File[]>([])
+  const [text, setText] = useState<string>("");
+  const [useAi, setUseAi] = useState(false)
   const [progressMessage, setProgressMessage] = useState<string>("");
+  // The file import in progress, whose RECIPE_IMPORT_PROGRESS we show
+  const [importId, setImportId] = useState<string | null>(null)
   const inputRef = useRef<HTMLInputElement>(null)
 
   // Handle WebSocket messages
   useEffect(() => {
     if (!open) return
 
-    const unsubscribe = onMessage((wsMessage) => {
-      switch (wsMessage.type) {
+    const unsubscribe = onMessage((event) => {
+      switch (event.type) {
         case 'RECIPE_ADDITIONS': {
+          // File imports finish with RECIPE_IMPORT_PROGRESS instead
+          if (importId) break
+
           // Reset loading state when recipe processing completes
           setIsLoading(false)
 
           // Auto-close dialog when recipe processing completes
-          if (wsMessage.payload.status === 'success') {
+          if (event.data.status === 'success') {
             setShouldClose(true)
             setTimeout(() => {
               onClose()
               setShouldClose(false)
               setUrl("")
+              setText("")
               setIsValid(null)
               setProgressMessage("")
             }, 100) // Small delay to show success state
@@ -81,19 +96,38 @@ export default function RecipeDialog({
         }
         case 'RECIPE_PROGRESS': {
           // Display progress messages
-          setProgressMessage(wsMessage.payload.message)
+          setProgressMessage(event.data.message)
+          break
+        }
+        case 'RECIPE_IMPORT_PROGRESS': {
+          if (event.data.importId !== importId) break
+          setProgressMessage(event.data.message)
+          if (event.data.phase === 'complete') {
+            setIsLoading(false)
+            setImportId(null)
+            setFiles([])
+            setProgressMessage("")
+            onClose()
+          }
+          break
+        }
+        case 'ERROR': {
+          // Failed requests can be corrected and resubmitted
+          setIsLoading(false)
+          setProgressMessage(event.data.message)
           break
         }
       }
     })
 
     return unsubscribe
-  }, [open, onMessage, onClose])
+  }, [open, onMessage, onClose, importId])
 
   // Handle form clearing when dialog should close
   useEffect(() => {
     if (shouldClose) {
       setUrl("");
+      setText("");
       setIsValid(null);
       setProgressMessage("");
     }
@@ -159,22 +193,54 @@ export default function RecipeDialog({
     }
   };
 
+  const canSubmit = (): boolean => {
+    switch (mode) {
+      case 'url': return !!isValid
+      case 'text': return text.trim().length > 0
+      case 'file': return files.length > 0
+    }
+  }
+
   const handleSubmit = (e: React.FormEvent) => {
     e.preventDefault();
 
+    if (mode === 'file') {
+      if (canSubmit() && !isLoading) {
+        setIsLoading(true)
+        setProgressMessage("Importing recipes...")
+        importRecipes(files).then(id => {
+          if (id) {
+            setImportId(id)
+            return
+          }
+          // The upload was refused; the files can be changed and resubmitted
+          setIsLoading(false)
+          setProgressMessage("")
+        })
+      }
+      return
+    }
+
+    if (mode === 'text') {
+      if (canSubmit() && !isLoading) {
+        setIsLoading(true)
+        setProgressMessage("")
+        sendRecipeText(text, useAi)
+      }
+      return
+    }
+
     if (isValid && !isLoading) {
       // Set loading state and process recipe
       setIsLoading(true)
 
       if (!user) return
 
-      const recipePayload: Omit<RecipeUrlRequestPayload, 'id' | 'sentAt'> = {
-        sender: user,
-        channel: { id: 'channel-1', name: 'General' },
+      sendRecipeUrlRequest({
+        sharerId: user.id,
+        sharerName: user.name,
         url: url,
-      }
-
-      sendRecipeUrlRequest(recipePayload)
+      })
     }
   }
 
@@ -196,34 +262,101 @@ export default function RecipeDialog({
           {/* Remove the close "x" button as per requirements */}
         </div>
 
+        <div className="mb-4 flex gap-2">
+          <Button
+            type="button"
+            size="sm"
+            variant={mode === 'url' ? 'default' : 'outline'}
+            onClick={() => setMode('url')}
+            disabled={isLoading}
+          >
+            Link
+          </Button>
+          <Button
+            type="button"
+            size="sm"
+            variant={mode === 'text' ? 'default' : 'outline'}
+            onClick={() => setMode('text')}
+            disabled={isLoading}
+          >
+            Paste text
+          </Button>
+          <Button
+            type="button"
+            size="sm"
+            variant={mode === 'file' ? 'default' : 'outline'}
+            onClick={() => setMode('file')}
+            disabled={isLoading}
+          >
+            Import files
+          </Button>
+        </div>
+
         <form onSubmit={handleSubmit}>
           <TooltipProvider>
             <div>
-              <div className="relative">
-                <InputGroupInput
-                  className="pr-7" // Add padding to the right to make space for the icon
-                  disabled={isLoading}
-                  onChange={handleInputChange}
-                  onKeyDown={handleKeyDown}
-                  onPaste={handlePaste}
-                  placeholder="https://example.com/recipe"
-                  ref={inputRef}
-                  type="url"
-                  value={url}
-                />
-                <div className="absolute inset-y-0 right-0 flex items-center">
-                  <InputGroupAddon align="inline-end">
-                    <Tooltip>
-                      <TooltipTrigger asChild>
-                        <InputGroupButton className="rounded-full" size="icon-xs">
-                          {getValidationIcon()}
-                        </InputGroupButton>
-                      </TooltipTrigger>
-                      <TooltipContent>{getValidationTooltip()}</TooltipContent>
-                    </Tooltip>
-                  </InputGroupAddon>
+              {mode === 'file' && (
+                <div className="space-y-2">
+                  <input
+                    accept=".html,.htm,.mhtml,.mht,.zip,.json,.jsonld,.paprikarecipes,.paprikarecipe"
+                    className="block w-full text-sm text-muted-foreground"
+                    disabled={isLoading}
+                    multiple
+                    onChange={(e) => setFiles(Array.from(e.target.files ?? []))}
+                    type="file"
+                  />
+                  <p className="text-sm text-muted-foreground">
+                    Pages saved from your browser, exports from Paprika, Mealie or Tandoor, or a zip of them
+                  </p>
                 </div>
-              </div>
+              )}
+              {mode === 'text' && (
+                <div className="space-y-2">
+                  <Textarea
+                    className="max-h-72"
+                    disabled={isLoading}
+                    onChange={(e) => setText(e.target.value)}
+                    placeholder={"Pancakes\n\nIngredients\n- 2 cups flour\n- 1 cup milk"}
+                    value={text}
+                  />
+                  <label className="flex items-center gap-2 text-sm text-muted-foreground">
+                    <input
+                      type="checkbox"
+                      checked={useAi}
+                      disabled={isLoading}
+                      onChange={(e) => setUseAi(e.target.checked)}
+                    />
+                    Extract ingredients with AI
+                  </label>
+                </div>
+              )}
+              {mode === 'url' && (
+                <div className="relative">
+                  <InputGroupInput
+                    className="pr-7" // Add padding to the right to make space for the icon
+                    disabled={isLoading}
+                    onChange={handleInputChange}
+                    onKeyDown={handleKeyDown}
+                    onPaste={handlePaste}
+                    placeholder="https://example.com/recipe"
+                    ref={inputRef}
+                    type="url"
+                    value={url}
+                  />
+                  <div className="absolute inset-y-0 right-0 flex items-center">
+                    <InputGroupAddon align="inline-end">
+                      <Tooltip>
+                        <TooltipTrigger asChild>
+                          <InputGroupButton className="rounded-full" size="icon-xs">
+                            {getValidationIcon()}
+                          </InputGroupButton>
+                        </TooltipTrigger>
+                        <TooltipContent>{getValidationTooltip()}</TooltipContent>
+                      </Tooltip>
+                    </InputGroupAddon>
+                  </div>
+                </div>
+              )}
 
               {/* Display progress message between input and button */}
               {progressMessage && (
@@ -244,7 +377,7 @@ export default function RecipeDialog({
                 </Button>
                 <Button
                   type="submit"
-                  disabled={!isValid || isLoading}
+                  disabled={!canSubmit() || isLoading}
                   className="flex-1"
                 >
                   Submit
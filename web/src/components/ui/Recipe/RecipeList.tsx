@@ -1,7 +1,12 @@
 import RecipeCard from './RecipeCard'
+import type { Recipe } from '@/types/protocol'
 import { useRecipeContext } from '@/contexts/RecipeContext'
 
-export default function RecipeList() {
+interface RecipeListProps {
+  onRemoveRecipe?: (recipe: Recipe) => void
+}
+
+export default function RecipeList({ onRemoveRecipe }: RecipeListProps) {
   const { clearSelection, recipes, selectedRecipes } = useRecipeContext()
 
   return (
@@ -33,6 +38,7 @@ export default function RecipeList() {
               <RecipeCard
                 key={recipe.id}
                 recipe={recipe}
+                onRemove={onRemoveRecipe}
               />
             ))}
           </div>
@@ -33,7 +33,7 @@ export default function UserMenu({ error, reconnect }: UserMenuProps) {
   )
 
   const handleSignOut = () => {
-    clearUser()
+    clearUser(true)
     window.localStorage.removeItem('mixUserName')
     setIsOpen(false)
   }
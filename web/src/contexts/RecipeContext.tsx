@@ -1,5 +1,5 @@
 import { createContext, useContext, useState } from 'react'
-import type { Recipe } from '../types/websocket'
+import type { Recipe } from '@/types/protocol'
 import type { ReactNode } from 'react'
 
 export interface RecipeContextValue {
@@ -25,10 +25,15 @@ export function RecipeProvider({ children }: RecipeProviderProps) {
   const [selectedRecipes, setSelectedRecipes] = useState<string[]>([])
   const [loading, setLoading] = useState(false)
 
+  // Updates that arrive late, or replays of ones already seen, must not
+  // overwrite a newer version of the recipe
   const addRecipe = (recipe: Recipe) => {
     setRecipes(prev => {
       const exists = prev.find(r => r.id === recipe.id)
       if (exists) {
+        if (exists.version >= recipe.version) {
+          return prev
+        }
         return prev.map(r => r.id === recipe.id ? recipe : r)
       }
       return [...prev, recipe]
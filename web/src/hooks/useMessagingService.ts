@@ -1,6 +1,42 @@
 import { useEffect, useState, useCallback, useRef } from 'react'
-import { websocketService, handleConnectionAck, type ConnectionState } from '@/services/websocket'
-import type { WebSocketMessage, MessagePayload, RecipeUrlRequestPayload, RecipeUrlRequestData } from '@/types'
+import { websocketService, handleConnectionAck, type ConnectionState, type ServerEvent } from '@/services/websocket'
+import type {
+  ChatMessageBody,
+  ChatMessagePayload,
+  GroceryItemUpdatePayload,
+  Meal,
+  RecipeUrlRequestPayload,
+  RecipeUpdatePayload,
+  ServerMessageType
+} from '@/types/protocol'
+
+// RecipeChanges corrects a recipe; ingredients replaces the whole list
+export type RecipeChanges = Omit<RecipeUpdatePayload, 'recipeId' | 'version'>
+
+// GroceryItemChanges edits a grocery list item; empty strings clear a field
+// and an empty assigneeId unassigns it
+export type GroceryItemChanges = Omit<GroceryItemUpdatePayload, 'itemId' | 'version'>
+
+// eventTypes are the server messages passed on to onMessage callbacks
+const eventTypes: ServerMessageType[] = [
+  'CHAT_MESSAGE',
+  'CHAT_HISTORY',
+  'USER_JOINED',
+  'USER_LEFT',
+  'PRESENCE_STATE',
+  'RECIPE_PROGRESS',
+  'RECIPE_IMPORT_PROGRESS',
+  'RECIPE_ADDITIONS',
+  'RECIPE_UPDATES',
+  'RECIPE_REMOVALS',
+  'PLAN_UPDATES',
+  'PLAN_REMOVALS',
+  'GROCERY_ITEM_UPDATES',
+  'GROCERY_ITEM_REMOVALS',
+  'PANTRY_ITEM_UPDATES',
+  'PANTRY_ITEM_REMOVALS',
+  'ERROR'
+]
 
 interface UseMessagingServiceOptions {
   uuid: string
@@ -12,9 +48,21 @@ interface UseMessagingServiceReturn {
   isConnected: boolean
   connectionState: ConnectionState
   error: Error | null
-  sendMessage: (message: Omit<MessagePayload, 'id' | 'sentAt'>) => void
-  sendRecipeUrlRequest: (payload: Omit<RecipeUrlRequestPayload, 'id' | 'sentAt'>) => void
-  onMessage: (callback: (message: WebSocketMessage) => void) => () => void
+  sendMessage: (message: Omit<ChatMessageBody, 'id' | 'sentAt'>) => string | undefined
+  requestChatHistory: (before?: string) => void
+  sendRecipeUrlRequest: (payload: RecipeUrlRequestPayload) => void
+  sendRecipeText: (text: string, useAi?: boolean) => void
+  sendRecipeRemove: (recipeId: string, version: number) => void
+  sendRecipeUpdate: (recipeId: string, version: number, changes: RecipeChanges) => void
+  sendPlanEntryAdd: (recipeId: string, date: string, meal: Meal, note?: string) => void
+  sendPlanEntryMove: (entryId: string, date: string, meal: Meal) => void
+  sendPlanEntryRemove: (entryId: string) => void
+  sendGroceryItemAdd: (name: string, quantity?: string, unit?: string) => void
+  sendGroceryItemUpdate: (itemId: string, version: number, changes: GroceryItemChanges) => void
+  sendGroceryItemCheck: (itemId: string, checked: boolean) => void
+  sendGroceryItemRemove: (itemId: string) => void
+  sendGroceryListClearChecked: () => void
+  onMessage: (callback: (event: ServerEvent) => void) => () => void
   reconnect: () => Promise<void>
   disconnect: () => void
 }
@@ -76,14 +124,14 @@ export function useMessagingService(options: UseMessagingServiceOptions): UseMes
     websocketService.disconnect()
   }, [])
 
-  const sendMessage = (message: Omit<MessagePayload, 'id' | 'sentAt'>) => {
+  const sendMessage = (message: Omit<ChatMessageBody, 'id' | 'sentAt'>) => {
     if (!websocketService.isConnected()) {
       console.error('WebSocket not connected')
       return
     }
 
-    const chatMessage: WebSocketMessage = {
-      type: 'MESSAGE',
+    const chatMessage: ChatMessagePayload = {
+      type: 'CHAT_MESSAGE',
       payload: {
         ...message,
         id: `msg-${Date.now()}-${Math.random().toString(36).substr(2, 9)}`,
@@ -92,152 +140,147 @@ export function useMessagingService(options: UseMessagingServiceOptions): UseMes
       timestamp: new Date().toISOString()
     }
 
-    websocketService.send('CHAT_MESSAGE', chatMessage)
+    return websocketService.send('CHAT_MESSAGE', chatMessage)
   }
 
-  const sendRecipeUrlRequest = (payload: Omit<RecipeUrlRequestPayload, 'id' | 'sentAt'>) => {
+  const requestChatHistory = (before?: string) => {
     if (!websocketService.isConnected()) {
       console.error('WebSocket not connected')
       return
     }
 
-    const wsData: RecipeUrlRequestData = {
-      sharerId: payload.sender.id,
-      sharerName: payload.sender.name,
-      url: payload.url,
+    websocketService.send('CHAT_HISTORY_REQUEST', { before })
+  }
+
+  const sendRecipeUrlRequest = (payload: RecipeUrlRequestPayload) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
     }
 
-    websocketService.send('RECIPE_URL_REQUEST', wsData)
+    websocketService.send('RECIPE_URL_REQUEST', payload)
   }
 
-  const onMessage = useCallback((callback: (message: WebSocketMessage) => void) => {
-    const unsubscribeChatMessage = websocketService.on('CHAT_MESSAGE', (data: WebSocketMessage) => {
-      try {
-        callback(data)
-      } catch (error) {
-        console.error('Error in messaging callback:', error)
-      }
-    })
+  // Pasted recipes are shared by the identified user and reported with the
+  // same RECIPE_PROGRESS and RECIPE_ADDITIONS as shared URLs
+  const sendRecipeText = (text: string, useAi = false) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
 
-    const unsubscribeUserJoined = websocketService.on('USER_JOINED', (data: any) => {
-      try {
-        const userJoinedEvent: WebSocketMessage = {
-          type: 'USER_JOINED',
-          payload: {
-            user: {
-              id: data.userId,
-              name: data.userName
-            },
-            channelId: data.sessionId
-          },
-          timestamp: new Date().toISOString()
-        }
-        callback(userJoinedEvent)
-      } catch (error) {
-        console.error('Error in user joined callback:', error)
-      }
-    })
+    websocketService.send('RECIPE_TEXT_REQUEST', { text, useAi })
+  }
 
-    const unsubscribeUserLeft = websocketService.on('USER_LEFT', (data: any) => {
-      try {
-        const userLeftEvent: WebSocketMessage = {
-          type: 'USER_LEFT',
-          payload: {
-            user: {
-              id: data.userId,
-              name: data.userName
-            },
-            channelId: data.sessionId
-          },
-          timestamp: new Date().toISOString()
-        }
-        callback(userLeftEvent)
-      } catch (error) {
-        console.error('Error in user left callback:', error)
-      }
-    })
+  // Recipes are only removed or changed locally once the server broadcasts
+  // RECIPE_REMOVALS or RECIPE_UPDATES, so every connection stays in step.
+  // version is the one the client last saw; if someone else changed the
+  // recipe since, the server answers VERSION_CONFLICT and resends it.
+  const sendRecipeRemove = (recipeId: string, version: number) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
 
-    const unsubscribeRecipeSubmission = websocketService.on('RECIPE_URL_REQUEST', (data: RecipeUrlRequestData) => {
-      try {
-        const recipeSubmissionEvent: WebSocketMessage = {
-          type: 'RECIPE_URL_REQUEST',
-          payload: {
-            id: `recipe-${Date.now()}-${Math.random().toString(36).substr(2, 9)}`,
-            sender: {
-              id: data.sharerId,
-              name: data.sharerName
-            },
-            channel: {
-              id: 'General',
-              name: 'General'
-            },
-            url: data.url,
-            sentAt: new Date().toISOString()
-          },
-          timestamp: new Date().toISOString()
-        }
-        callback(recipeSubmissionEvent)
-      } catch (error) {
-        console.error('Error in recipe submission callback:', error)
-      }
-    })
+    websocketService.send('RECIPE_REMOVE', { recipeId, version })
+  }
 
-    const unsubscribeRecipeProgress = websocketService.on('RECIPE_PROGRESS', (data: any) => {
-      try {
-        // Transform backend's flat structure to frontend's nested structure
-        const recipeProgressEvent: WebSocketMessage = {
-          type: 'RECIPE_PROGRESS',
-          payload: {
-            request: {
-              id: `recipe-${Date.now()}-${Math.random().toString(36).substr(2, 9)}`,
-              sender: {
-                id: data.request.sharerId,
-                name: data.request.sharerName
-              },
-              channel: {
-                id: 'General',
-                name: 'General'
-              },
-              url: data.request.url,
-              sentAt: new Date().toISOString()
-            },
-            phase: data.phase,
-            status: data.status,
-            message: data.message
-          },
-          timestamp: new Date().toISOString()
-        }
-        callback(recipeProgressEvent)
-      } catch (error) {
-        console.error('Error in recipe progress callback:', error)
-      }
-    })
+  const sendRecipeUpdate = (recipeId: string, version: number, changes: RecipeChanges) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
+
+    websocketService.send('RECIPE_UPDATE', { recipeId, version, ...changes })
+  }
 
-    const unsubscribeRecipeResponse = websocketService.on('RECIPE_ADDITIONS', (data: any) => {
+  // Plan entries change locally once the server broadcasts PLAN_UPDATES or
+  // PLAN_REMOVALS; dates are calendar days, YYYY-MM-DD
+  const sendPlanEntryAdd = (recipeId: string, date: string, meal: Meal, note?: string) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
+
+    websocketService.send('PLAN_ENTRY_ADD', { recipeId, date, meal, ...(note ? { note } : {}) })
+  }
+
+  const sendPlanEntryMove = (entryId: string, date: string, meal: Meal) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
+
+    websocketService.send('PLAN_ENTRY_MOVE', { entryId, date, meal })
+  }
+
+  const sendPlanEntryRemove = (entryId: string) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
+
+    websocketService.send('PLAN_ENTRY_REMOVE', { entryId })
+  }
+
+  // Grocery list changes come back to everyone, including the sender, as
+  // GROCERY_ITEM_UPDATES and GROCERY_ITEM_REMOVALS
+  const sendGroceryItemAdd = (name: string, quantity?: string, unit?: string) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
+
+    websocketService.send('GROCERY_ITEM_ADD', { name, ...(quantity ? { quantity } : {}), ...(unit ? { unit } : {}) })
+  }
+
+  const sendGroceryItemUpdate = (itemId: string, version: number, changes: GroceryItemChanges) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
+
+    websocketService.send('GROCERY_ITEM_UPDATE', { itemId, version, ...changes })
+  }
+
+  const sendGroceryItemCheck = (itemId: string, checked: boolean) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
+
+    websocketService.send('GROCERY_ITEM_CHECK', { itemId, checked })
+  }
+
+  const sendGroceryItemRemove = (itemId: string) => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
+
+    websocketService.send('GROCERY_ITEM_REMOVE', { itemId })
+  }
+
+  const sendGroceryListClearChecked = () => {
+    if (!websocketService.isConnected()) {
+      console.error('WebSocket not connected')
+      return
+    }
+
+    websocketService.send('GROCERY_LIST_CLEAR_CHECKED', {})
+  }
+
+  // Callbacks get server messages as they arrived, narrowed on their type
+  const onMessage = useCallback((callback: (event: ServerEvent) => void) => {
+    const unsubscribes = eventTypes.map(type => websocketService.on(type, (_data, message) => {
       try {
-        // Transform backend's flat structure to frontend's nested structure
-        const recipeResponseEvent: WebSocketMessage = {
-          type: 'RECIPE_ADDITIONS',
-          payload: {
-            status: data.status,
-            list: data.list || []
-          },
-          timestamp: new Date().toISOString()
-        }
-        callback(recipeResponseEvent)
+        callback(message as ServerEvent)
       } catch (error) {
-        console.error('Error in recipe response callback:', error)
+        console.error(`Error in ${type} callback:`, error)
       }
-    })
+    }))
 
-    return () => {
-      unsubscribeChatMessage()
-      unsubscribeUserJoined()
-      unsubscribeUserLeft()
-      unsubscribeRecipeSubmission()
-      unsubscribeRecipeProgress()
-      unsubscribeRecipeResponse()
-    }
+    return () => unsubscribes.forEach(unsubscribe => unsubscribe())
   }, [])
 
   return {
@@ -245,7 +288,19 @@ export function useMessagingService(options: UseMessagingServiceOptions): UseMes
     connectionState,
     error,
     sendMessage,
+    requestChatHistory,
     sendRecipeUrlRequest,
+    sendRecipeText,
+    sendRecipeRemove,
+    sendRecipeUpdate,
+    sendPlanEntryAdd,
+    sendPlanEntryMove,
+    sendPlanEntryRemove,
+    sendGroceryItemAdd,
+    sendGroceryItemUpdate,
+    sendGroceryItemCheck,
+    sendGroceryItemRemove,
+    sendGroceryListClearChecked,
     onMessage,
     reconnect,
     disconnect
@@ -4,8 +4,8 @@ import type { User } from '@/types'
 
 interface UseUserIdentityReturn {
   user: User | null
-  setUser: (name: string) => User
-  clearUser: () => void
+  setUser: (name: string) => Promise<User>
+  clearUser: (forgetToken?: boolean) => void
 }
 
 export function useUserIdentity(): UseUserIdentityReturn {
@@ -19,12 +19,12 @@ export function useUserIdentity(): UseUserIdentityReturn {
     return unsubscribe
   }, [])
 
-  const setUser = (name: string): User => {
+  const setUser = (name: string): Promise<User> => {
     return userIdentityService.setUserIdentity(name)
   }
 
-  const clearUser = (): void => {
-    userIdentityService.clearUserIdentity()
+  const clearUser = (forgetToken = false): void => {
+    userIdentityService.clearUserIdentity(forgetToken)
   }
 
   return {
@@ -1,41 +1,77 @@
-import { useParams } from 'react-router-dom'
-import { Plus } from 'lucide-react'
+import { useNavigate, useParams, useSearchParams } from 'react-router-dom'
+import { Download, Link2, Plus } from 'lucide-react'
 import { useMessagingService } from '@/hooks/useMessagingService'
 import { useUserIdentity } from '@/hooks/useUserIdentity'
 import { useToastService } from '@/services/toastService'
+import { userIdentityService } from '@/services/userIdentity'
+import { exportFormats, mixService, MixNotFoundError, type ExportFormat } from '@/services/mixes'
+import { useClickOutside } from '@/hooks/useClickOutside'
 import { useRecipeContext } from '@/contexts/RecipeContext'
 import { useNavigationContext } from '@/contexts/NavigationContext'
 import { MixLayout } from "@/components/layout"
-import { useEffect, useState } from 'react'
+import { useEffect, useRef, useState } from 'react'
 import { MessagesList } from '@/components/ui'
 import UserNameDialog from '@/components/ui/UserNameDialog'
 
 import { RecipeList } from '@/components/ui/Recipe'
+import { GroceryList } from '@/components/ui/GroceryList'
 import RecipeDialog from '@/components/ui/Recipe/RecipeDialog'
 
-import type { ChatMessage, MessagePayload } from '@/types'
-import type { Recipe } from '@/types/websocket'
+import type { ChatMessage, User } from '@/types'
+import type { ChatMessageBody, GroceryListItem, Role } from '@/types/protocol'
 
 export default function MixPage() {
   const { id } = useParams<{ id: string }>()
+  const navigate = useNavigate()
+  // Invite links carry a token that makes newcomers members on USER_IDENTIFY
+  const [searchParams] = useSearchParams()
+  const invite = searchParams.get('invite') ?? undefined
   const [messages, setMessages] = useState<ChatMessage[]>([]);
+  const [hasMoreHistory, setHasMoreHistory] = useState(false);
+  const [groceryItems, setGroceryItems] = useState<GroceryListItem[]>([]);
+  const [presentUsers, setPresentUsers] = useState<User[]>([]);
+  const [exportMenuOpen, setExportMenuOpen] = useState(false)
+  const exportMenuRef = useRef<HTMLSpanElement>(null)
+  useClickOutside(exportMenuRef, () => setExportMenuOpen(false), { enabled: exportMenuOpen })
+  const [role, setRole] = useState<Role | null>(null);
   const { activeTab } = useNavigationContext()
   const [recipeDialogOpen, setRecipeDialogOpen] = useState(false);
-  const { user, setUser } = useUserIdentity()
-  const { addRecipe } = useRecipeContext()
+  const { user, setUser, clearUser } = useUserIdentity()
+  const { addRecipe, removeRecipe } = useRecipeContext()
   const toastService = useToastService()
 
+  // Mixes must be created before they can be joined. Users who haven't joined
+  // yet get a 403 here, which is fine; they become members on USER_IDENTIFY
+  useEffect(() => {
+    if (!id || !user) return
+    mixService.get(id).catch(error => {
+      if (error instanceof MixNotFoundError) {
+        navigate('/not-found', { replace: true })
+      }
+    })
+  }, [id, user, navigate])
+
+  // Minting an identity is async, so guard against hydrating twice
+  const hydrating = useRef(false)
+
   // Hydrate the user from localStorage on first mount
   useEffect(() => {
     try {
       const savedName = window.localStorage.getItem('mixUserName')
-      if (savedName && savedName.trim() !== '' && !user) {
+      if (savedName && savedName.trim() !== '' && !user && !hydrating.current) {
+        hydrating.current = true
         setUser(savedName)
+          .catch(error => toastService.showRecipeError(error.message))
+          .finally(() => { hydrating.current = false })
       }
     } catch { }
-  }, [user, setUser])
+  }, [user, setUser, toastService])
 
-  const { connectionState, sendMessage, sendRecipeUrlRequest, onMessage } = useMessagingService({
+  const {
+    connectionState, sendMessage, requestChatHistory, sendRecipeUrlRequest, sendRecipeText, sendRecipeRemove,
+    sendGroceryItemAdd, sendGroceryItemUpdate, sendGroceryItemCheck, sendGroceryItemRemove, sendGroceryListClearChecked,
+    onMessage
+  } = useMessagingService({
     uuid: id || "",
     autoConnect: !!id && !!user
   });
@@ -45,33 +81,103 @@ export default function MixPage() {
     if (connectionState === 'connected' && user) {
       import('@/services/websocket').then(({ websocketService }) => {
         websocketService.send('USER_IDENTIFY', {
+          token: userIdentityService.getToken(),
           userId: user.id,
-          userName: user.name
+          lastSeq: websocketService.getLastSeq(),
+          invite
         })
       })
     }
-  }, [connectionState, user])
+  }, [connectionState, user, invite])
 
   // Handle incoming messages
   useEffect(() => {
-    const unsubscribe = onMessage((wsMessage) => {
-      switch (wsMessage.type) {
-        case 'MESSAGE':
-          setMessages(prev => [...prev, wsMessage.payload])
+    const unsubscribe = onMessage((event) => {
+      switch (event.type) {
+        case 'CHAT_MESSAGE': {
+          const stored = event.data.payload
+          const optimisticId = `temp-${event.requestId}`
+          setMessages(prev => {
+            if (prev.some(m => m.id === stored.id)) return prev
+            // Our own message came back from the server; swap in the stored copy
+            if (event.requestId && prev.some(m => m.id === optimisticId)) {
+              return prev.map(m => m.id === optimisticId ? stored : m)
+            }
+            return [...prev, stored]
+          })
+          break
+        }
+        case 'CHAT_HISTORY': {
+          const { messages: history, hasMore } = event.data
+          setMessages(prev => {
+            const known = new Set(prev.map(m => m.id))
+            return [...history.filter(m => !known.has(m.id)), ...prev]
+          })
+          setHasMoreHistory(hasMore)
           break
+        }
+        case 'PRESENCE_STATE': {
+          setPresentUsers(event.data.users.map(u => ({ id: u.userId, name: u.userName })))
+          // Presence is resent when the owner changes someone's role
+          setRole(event.data.users.find(u => u.userId === user?.id)?.role ?? null)
+          break
+        }
         case 'USER_JOINED': {
-          toastService.showUserJoined(wsMessage.payload.user.name)
+          const joined: User = { id: event.data.userId, name: event.data.userName }
+          setPresentUsers(prev => prev.some(u => u.id === joined.id) ? prev : [...prev, joined])
+          toastService.showUserJoined(joined.name)
           break
         }
         case 'USER_LEFT': {
-          toastService.showUserLeft(wsMessage.payload.user.name)
+          setPresentUsers(prev => prev.filter(u => u.id !== event.data.userId))
+          toastService.showUserLeft(event.data.userName)
           break
         }
         case 'RECIPE_ADDITIONS': {
-          if (wsMessage.payload.status === 'success') {
-            wsMessage.payload.list?.forEach((recipe: Recipe) => {
-              addRecipe(recipe)
-            })
+          if (event.data.status === 'success') {
+            event.data.list?.forEach(addRecipe)
+          }
+          break
+        }
+        case 'RECIPE_UPDATES': {
+          event.data.list.forEach(addRecipe)
+          break
+        }
+        case 'RECIPE_IMPORT_PROGRESS': {
+          if (event.data.phase === 'complete' && event.data.result) {
+            toastService.showImportSummary(event.data.result)
+          }
+          break
+        }
+        case 'RECIPE_REMOVALS': {
+          event.data.recipeIds.forEach(removeRecipe)
+          break
+        }
+        case 'GROCERY_ITEM_UPDATES': {
+          // Late or replayed updates must not overwrite a newer version
+          const changed = new Map(event.data.list.map(item => [item.id, item]))
+          setGroceryItems(prev => [
+            ...prev.map(item => {
+              const update = changed.get(item.id)
+              return update && update.version > item.version ? update : item
+            }),
+            ...event.data.list.filter(item => !prev.some(p => p.id === item.id))
+          ])
+          break
+        }
+        case 'GROCERY_ITEM_REMOVALS': {
+          const removed = new Set(event.data.itemIds)
+          setGroceryItems(prev => prev.filter(item => !removed.has(item.id)))
+          break
+        }
+        case 'ERROR': {
+          toastService.showRecipeError(event.data.message)
+          if (event.data.code === 'NAME_TAKEN') {
+            // Ask for a different name
+            window.localStorage.removeItem('mixUserName')
+            clearUser()
+          } else if (event.data.code === 'NOT_MEMBER' || event.data.code === 'INVALID_INVITE') {
+            navigate('/not-found', { replace: true })
           }
           break
         }
@@ -79,29 +185,99 @@ export default function MixPage() {
     })
 
     return unsubscribe
-  }, [onMessage, toastService, addRecipe])
+  }, [onMessage, toastService, addRecipe, removeRecipe, clearUser, user, navigate])
+
+  // Viewers can read recipes and chat but not share recipes
+  const canShareRecipes = role !== 'viewer'
+
+  // Owners share the mix through editor invites that expire after a week
+  const handleCopyInvite = async () => {
+    if (!id) return
+    try {
+      const created = await mixService.createInvite(id, 'editor', { expiresIn: 7 * 24 * 60 * 60 })
+      await navigator.clipboard.writeText(mixService.inviteLink(id, created))
+      toastService.showInviteCopied()
+    } catch (error) {
+      toastService.showInviteError(error instanceof Error ? error.message : undefined)
+    }
+  }
+
+  // Resolves to the import's ID; its summary arrives as RECIPE_IMPORT_PROGRESS
+  const handleImportRecipes = async (files: File[]) => {
+    if (!id) return
+    try {
+      return (await mixService.importRecipes(id, files)).importId
+    } catch (error) {
+      toastService.showRecipeError(error instanceof Error ? error.message : undefined)
+    }
+  }
+
+  const handleFillGroceryListFromPlan = async () => {
+    if (!id) return
+    try {
+      await mixService.groceryListFromPlan(id)
+    } catch (error) {
+      toastService.showRecipeError(error instanceof Error ? error.message : undefined)
+    }
+  }
+
+  const handleExport = async (format: ExportFormat) => {
+    setExportMenuOpen(false)
+    if (!id) return
+    try {
+      await mixService.exportRecipes(id, format)
+    } catch (error) {
+      toastService.showRecipeError(error instanceof Error ? error.message : undefined)
+    }
+  }
+
+  const handlePrintCookbook = async () => {
+    setExportMenuOpen(false)
+    if (!id) return
+    try {
+      await mixService.openCookbook(id)
+    } catch (error) {
+      toastService.showRecipeError(error instanceof Error ? error.message : undefined)
+    }
+  }
+
+  const handleDownloadPlanCalendar = async () => {
+    setExportMenuOpen(false)
+    if (!id) return
+    try {
+      await mixService.downloadPlanCalendar(id)
+    } catch (error) {
+      toastService.showRecipeError(error instanceof Error ? error.message : undefined)
+    }
+  }
 
   const handleMessageSubmit = (text: string) => {
     if (!user) return
 
-    const messagePayload: Omit<MessagePayload, 'id' | 'sentAt'> = {
+    const messagePayload: Omit<ChatMessageBody, 'id' | 'sentAt'> = {
       sender: user,
       channel: { id: 'channel-1', name: 'General' },
       text
     }
 
-    sendMessage(messagePayload)
+    const requestId = sendMessage(messagePayload)
+    if (!requestId) return
 
     const optimisticMessage: ChatMessage = {
       ...messagePayload,
-      id: `temp-${Date.now()}`,
+      id: `temp-${requestId}`,
       sentAt: new Date().toISOString()
     }
     setMessages(prev => [...prev, optimisticMessage])
   }
 
+  const handleLoadEarlier = () => {
+    const oldest = messages.find(m => !('isSystem' in m))
+    requestChatHistory(oldest?.id)
+  }
+
   const handleUserNameSubmit = (name: string) => {
-    setUser(name)
+    setUser(name).catch(error => toastService.showRecipeError(error.message))
   }
 
   const sectionName = () => {
@@ -124,13 +300,80 @@ export default function MixPage() {
           <div className="flex justify-between items-center pt-4 px-4">
             <h2 className="text-xl font-semibold text-foreground">
               {sectionName()}
+              {presentUsers.length > 0 && (
+                <span
+                  className="ml-3 text-sm font-normal text-muted-foreground"
+                  title={presentUsers.map(u => u.name).join(', ')}
+                >
+                  {presentUsers.length} online
+                </span>
+              )}
+              {role === 'owner' && (
+                <button
+                  onClick={handleCopyInvite}
+                  className="ml-2 h-8 w-8 inline-flex items-center justify-center rounded-md text-muted-foreground hover:text-foreground hover:bg-muted cursor-pointer align-middle"
+                  title="Copy invite link"
+                >
+                  <Link2 className="w-4 h-4" />
+                </button>
+              )}
+              {activeTab === 'grocerylist' && (
+              <GroceryList
+                items={groceryItems}
+                members={presentUsers}
+                readOnly={!canShareRecipes}
+                onAdd={sendGroceryItemAdd}
+                onUpdate={(item, changes) => sendGroceryItemUpdate(item.id, item.version, changes)}
+                onCheck={(item, checked) => sendGroceryItemCheck(item.id, checked)}
+                onRemove={item => sendGroceryItemRemove(item.id)}
+                onClearChecked={sendGroceryListClearChecked}
+                onFillFromPlan={handleFillGroceryListFromPlan}
+              />
+            )}
+
+            {activeTab === 'recipe' && (
+                <span ref={exportMenuRef} className="relative ml-1 inline-block align-middle">
+                  <button
+                    onClick={() => setExportMenuOpen(open => !open)}
+                    className="h-8 w-8 inline-flex items-center justify-center rounded-md text-muted-foreground hover:text-foreground hover:bg-muted cursor-pointer"
+                    title="Export recipes"
+                  >
+                    <Download className="w-4 h-4" />
+                  </button>
+                  {exportMenuOpen && (
+                    <div className="absolute left-0 top-9 z-10 min-w-48 rounded-md border bg-background py-1 text-sm font-normal shadow-md">
+                      <button
+                        onClick={handlePrintCookbook}
+                        className="block w-full px-3 py-1.5 text-left hover:bg-muted cursor-pointer"
+                      >
+                        Print cookbook
+                      </button>
+                      {exportFormats.map(({ format, label }) => (
+                        <button
+                          key={format}
+                          onClick={() => handleExport(format)}
+                          className="block w-full px-3 py-1.5 text-left hover:bg-muted cursor-pointer"
+                        >
+                          {label}
+                        </button>
+                      ))}
+                      <button
+                        onClick={handleDownloadPlanCalendar}
+                        className="block w-full px-3 py-1.5 text-left hover:bg-muted cursor-pointer"
+                      >
+                        Meal plan calendar
+                      </button>
+                    </div>
+                  )}
+                </span>
+              )}
             </h2>
             <button
               onClick={() => setRecipeDialogOpen(true)}
-              className={`h-10 w-10 text-sm font-medium rounded-md transition-colors ${activeTab === 'recipe'
+              className={`h-10 w-10 text-sm font-medium rounded-md transition-colors ${activeTab === 'recipe' && canShareRecipes
                 ? 'text-muted-foreground hover:text-foreground hover:bg-muted cursor-pointer'
                 : 'opacity-0 cursor-default'}`}
-              disabled={activeTab !== 'recipe'}
+              disabled={activeTab !== 'recipe' || !canShareRecipes}
             >
               <Plus className="w-5 h-5 inline" />
             </button>
@@ -147,6 +390,7 @@ export default function MixPage() {
                   currentUser={user}
                   showInput={true}
                   onMessageSubmit={handleMessageSubmit}
+                  onLoadEarlier={hasMoreHistory ? handleLoadEarlier : undefined}
                   inputPlaceholder="Type a message..."
                 />
               </div>
@@ -154,6 +398,7 @@ export default function MixPage() {
 
             {activeTab === 'recipe' && (
               <RecipeList
+                onRemoveRecipe={canShareRecipes ? recipe => sendRecipeRemove(recipe.id, recipe.version) : undefined}
               />
             )}
           </div>
@@ -165,6 +410,8 @@ export default function MixPage() {
         onClose={() => setRecipeDialogOpen(false)}
         user={user}
         sendRecipeUrlRequest={sendRecipeUrlRequest}
+        sendRecipeText={sendRecipeText}
+        importRecipes={handleImportRecipes}
         onMessage={onMessage}
       />
     </MixLayout>
@@ -0,0 +1,46 @@
+import { useEffect, useRef } from 'react'
+import { useNavigate } from 'react-router-dom'
+import { mixService } from '@/services/mixes'
+import { useUserIdentity } from '@/hooks/useUserIdentity'
+import { useToastService } from '@/services/toastService'
+import UserNameDialog from '@/components/ui/UserNameDialog'
+
+// NewMixPage creates a mix on the server, owned by the current user, and opens it
+export default function NewMixPage() {
+  const navigate = useNavigate()
+  const { user, setUser } = useUserIdentity()
+  const toastService = useToastService()
+  const creating = useRef(false)
+  const hydrating = useRef(false)
+
+  // Reuse the saved name so returning users go straight to their new mix
+  useEffect(() => {
+    try {
+      const savedName = window.localStorage.getItem('mixUserName')
+      if (savedName && savedName.trim() !== '' && !user && !hydrating.current) {
+        hydrating.current = true
+        setUser(savedName)
+          .catch(error => toastService.showRecipeError(error.message))
+          .finally(() => { hydrating.current = false })
+      }
+    } catch { }
+  }, [user, setUser, toastService])
+
+  useEffect(() => {
+    if (!user || creating.current) return
+    creating.current = true
+
+    mixService.create('Untitled mix')
+      .then(mix => navigate(`/mixes/${mix.id}`, { replace: true }))
+      .catch(error => {
+        console.error('Failed to create mix:', error)
+        navigate('/not-found', { replace: true })
+      })
+  }, [user, navigate])
+
+  const handleUserNameSubmit = (name: string) => {
+    setUser(name).catch(error => toastService.showRecipeError(error.message))
+  }
+
+  return <UserNameDialog open={!user} onSubmit={handleUserNameSubmit} />
+}
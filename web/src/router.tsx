@@ -1,8 +1,8 @@
 import { createBrowserRouter, Navigate } from 'react-router-dom'
-import { v4 as uuidv4 } from 'uuid'
 import App from './App'
 import HomePage from './pages/HomePage'
 import MixPage from './pages/MixPage'
+import NewMixPage from './pages/NewMixPage'
 import NotFoundPage from './pages/NotFoundPage'
 
 export const router = createBrowserRouter([
@@ -12,7 +12,7 @@ export const router = createBrowserRouter([
     children: [
       {
         index: true,
-        element: <Navigate to={`/mixes/${uuidv4()}`} replace />
+        element: <NewMixPage />
       },
       {
         path: 'home',
@@ -20,7 +20,7 @@ export const router = createBrowserRouter([
       },
       {
         path: 'mixes',
-        element: <Navigate to={`/mixes/${uuidv4()}`} replace />
+        element: <NewMixPage />
       },
       {
         path: '/mixes/:id',
@@ -0,0 +1,269 @@
+import type { Recipe } from '@/types/protocol'
+import type { GroceryListItem, PantryItem, PlanEntry, Role } from '@/types/protocol'
+import { userIdentityService } from '@/services/userIdentity'
+
+export interface MixMember {
+  userId: string
+  userName: string
+  role: Role
+  joinedAt: string
+  inviteId?: string
+}
+
+export interface Invite {
+  id: string
+  token: string
+  role: Exclude<Role, 'owner'>
+  createdBy: string
+  createdAt: string
+  expiresAt?: string
+  maxUses?: number
+  uses: number
+  revokedAt?: string
+}
+
+export interface Mix {
+  id: string
+  title: string
+  description: string
+  members: MixMember[]
+  invites?: Invite[]
+  createdAt: string
+  updatedAt: string
+  recipeCount: number
+}
+
+// Imports run in the background; progress and the result arrive over the
+// WebSocket as RECIPE_IMPORT_PROGRESS with this importId
+export interface ImportAccepted {
+  importId: string
+}
+
+export interface ShoppingAmount {
+  quantity?: string
+  unit?: string
+}
+
+// Amounts in units that can't be added up are listed side by side. amounts
+// is what's left to buy once the pantry is taken off; stocked is what it covers.
+export interface ShoppingItem {
+  name: string
+  category?: string
+  amounts: ShoppingAmount[]
+  recipes: string[]
+  stocked?: ShoppingAmount[]
+  partlyStocked?: boolean
+}
+
+// stocked lists the items the pantry has enough of
+export interface PlanShoppingList {
+  from: string
+  to: string
+  items: ShoppingItem[]
+  stocked: ShoppingItem[]
+}
+
+// skipped lists the shopping list items left off the grocery list, e.g.
+// because they were already on it
+export interface GroceryListFromPlan {
+  from: string
+  to: string
+  items: GroceryListItem[]
+  skipped: { name: string, reason: string }[]
+}
+
+// A null quantity is a staple, such as salt, that's always in stock. Changes
+// reach everyone in the mix as PANTRY_ITEM_UPDATES and PANTRY_ITEM_REMOVALS.
+export type { PantryItem }
+
+export type PantryItemChanges = Pick<PantryItem, 'name' | 'groceryItem' | 'quantity' | 'unit'>
+
+export type ExportFormat = 'jsonld' | 'markdown' | 'shopping-list' | 'csv' | 'paprika'
+
+export const exportFormats: { format: ExportFormat, label: string }[] = [
+  { format: 'markdown', label: 'Markdown' },
+  { format: 'shopping-list', label: 'Shopping list' },
+  { format: 'csv', label: 'CSV of ingredients' },
+  { format: 'jsonld', label: 'schema.org JSON-LD' },
+  { format: 'paprika', label: 'Paprika archive' }
+]
+
+export class MixNotFoundError extends Error {
+  constructor() {
+    super('Mix does not exist')
+    this.name = 'MixNotFoundError'
+  }
+}
+
+// Requests carry the user's identity token; the server checks their role in the mix.
+// Form uploads set their own multipart Content-Type.
+const request = async <T>(path: string, init?: RequestInit): Promise<T> => {
+  const token = userIdentityService.getToken()
+  const response = await fetch(`/api/v1${path}`, {
+    ...init,
+    headers: {
+      ...(init?.body instanceof FormData ? {} : { 'Content-Type': 'application/json' }),
+      ...(token ? { Authorization: `Bearer ${token}` } : {}),
+      ...init?.headers
+    }
+  })
+
+  if (response.status === 404) {
+    throw new MixNotFoundError()
+  }
+  if (response.status === 204) {
+    return undefined as T
+  }
+
+  const body = await response.json()
+  if (!response.ok) {
+    throw new Error(body.message || `Request failed with status ${response.status}`)
+  }
+  return body as T
+}
+
+// download saves a response as a file, named by the server if it says so
+const download = async (path: string, fallbackName: string): Promise<void> => {
+  const token = userIdentityService.getToken()
+  const response = await fetch(`/api/v1${path}`, {
+    headers: token ? { Authorization: `Bearer ${token}` } : {}
+  })
+  if (!response.ok) {
+    const body = await response.json().catch(() => ({}))
+    throw new Error(body.message || `Download failed with status ${response.status}`)
+  }
+
+  const filename = response.headers.get('Content-Disposition')?.match(/filename\*?=(?:utf-8'')?"?([^";]+)"?/i)?.[1] ?? fallbackName
+  const url = URL.createObjectURL(await response.blob())
+  const link = document.createElement('a')
+  link.href = url
+  link.download = decodeURIComponent(filename)
+  link.click()
+  URL.revokeObjectURL(url)
+}
+
+export const mixService = {
+  create: (title: string, description = ''): Promise<Mix> =>
+    request('/mixes', { method: 'POST', body: JSON.stringify({ title, description }) }),
+
+  get: (id: string): Promise<Mix> =>
+    request(`/mixes/${id}`),
+
+  update: (id: string, changes: { title?: string, description?: string }): Promise<Mix> =>
+    request(`/mixes/${id}`, { method: 'PATCH', body: JSON.stringify(changes) }),
+
+  setMemberRole: (id: string, userId: string, role: Invite['role']): Promise<MixMember> =>
+    request(`/mixes/${id}/members/${userId}`, { method: 'PATCH', body: JSON.stringify({ role }) }),
+
+  // expiresIn is in seconds; omit it, or maxUses, for no limit
+  createInvite: (id: string, role: Invite['role'], limits: { expiresIn?: number, maxUses?: number } = {}): Promise<Invite> =>
+    request(`/mixes/${id}/invites`, { method: 'POST', body: JSON.stringify({ role, ...limits }) }),
+
+  invites: async (id: string): Promise<Invite[]> => {
+    const body = await request<{ invites: Invite[] }>(`/mixes/${id}/invites`)
+    return body.invites
+  },
+
+  revokeInvite: (id: string, inviteId: string): Promise<void> =>
+    request(`/mixes/${id}/invites/${inviteId}`, { method: 'DELETE' }),
+
+  join: (id: string, token: string): Promise<MixMember> =>
+    request(`/mixes/${id}/join`, { method: 'POST', body: JSON.stringify({ token }) }),
+
+  inviteLink: (id: string, invite: Invite): string =>
+    `${window.location.origin}/mixes/${id}?invite=${encodeURIComponent(invite.token)}`,
+
+  remove: (id: string): Promise<void> =>
+    request(`/mixes/${id}`, { method: 'DELETE' }),
+
+  // Recipe manager exports (Paprika, Mealie, Tandoor, schema.org JSON-LD),
+  // saved HTML pages, MHTML archives or zips of them; added recipes also
+  // arrive over the WebSocket as RECIPE_ADDITIONS
+  importRecipes: (id: string, files: File[]): Promise<ImportAccepted> => {
+    const form = new FormData()
+    files.forEach(file => form.append('file', file))
+    return request(`/mixes/${id}/recipes/import`, { method: 'POST', body: form })
+  },
+
+  // Downloads the mix, or only recipeIds, as a file named by the server
+  exportRecipes: (id: string, format: ExportFormat, recipeIds?: string[]): Promise<void> => {
+    const query = new URLSearchParams({ format })
+    if (recipeIds?.length) query.set('recipes', recipeIds.join(','))
+    return download(`/mixes/${id}/export?${query}`, `recipes.${format}`)
+  },
+
+  // Opens the printable cookbook in a new tab; scale multiplies quantities
+  openCookbook: async (id: string, options: { scale?: number, recipeIds?: string[] } = {}): Promise<void> => {
+    // Open the tab before awaiting so popup blockers treat it as user-initiated
+    const tab = window.open('', '_blank')
+    const token = userIdentityService.getToken()
+    const query = new URLSearchParams()
+    if (options.scale) query.set('scale', String(options.scale))
+    if (options.recipeIds?.length) query.set('recipes', options.recipeIds.join(','))
+    const response = await fetch(`/api/v1/mixes/${id}/cookbook?${query}`, {
+      headers: token ? { Authorization: `Bearer ${token}` } : {}
+    })
+    if (!response.ok) {
+      tab?.close()
+      const body = await response.json().catch(() => ({}))
+      throw new Error(body.message || `Cookbook failed with status ${response.status}`)
+    }
+
+    const url = URL.createObjectURL(new Blob([await response.text()], { type: 'text/html' }))
+    if (tab) {
+      tab.location.href = url
+    } else {
+      window.location.href = url
+    }
+  },
+
+  // Dates are calendar days, YYYY-MM-DD; omit both for the whole plan
+  plan: async (id: string, range: { from?: string, to?: string } = {}): Promise<PlanEntry[]> => {
+    const body = await request<{ entries: PlanEntry[] }>(`/mixes/${id}/plan?${new URLSearchParams(range)}`)
+    return body.entries
+  },
+
+  // Sums the ingredients planned from today, or from, for a week, or until to,
+  // less what's in the pantry unless usePantry is false
+  planShoppingList: (id: string, range: { from?: string, to?: string } = {}, usePantry = true): Promise<PlanShoppingList> => {
+    const query = new URLSearchParams(range)
+    if (!usePantry) query.set('pantry', 'false')
+    return request(`/mixes/${id}/plan/shopping-list?${query}`)
+  },
+
+  downloadPlanCalendar: (id: string): Promise<void> =>
+    download(`/mixes/${id}/plan/calendar.ics`, 'meal-plan.ics'),
+
+  groceryList: async (id: string): Promise<GroceryListItem[]> => {
+    const body = await request<{ items: GroceryListItem[] }>(`/mixes/${id}/grocery-list`)
+    return body.items
+  },
+
+  // Puts the plan's shopping list for the range on the grocery list; the new
+  // items reach everyone, including the caller, as GROCERY_ITEM_UPDATES
+  groceryListFromPlan: (id: string, range: { from?: string, to?: string } = {}, usePantry = true): Promise<GroceryListFromPlan> => {
+    const query = new URLSearchParams(range)
+    if (!usePantry) query.set('pantry', 'false')
+    return request(`/mixes/${id}/grocery-list/from-plan?${query}`, { method: 'POST' })
+  },
+
+  pantry: async (id: string): Promise<PantryItem[]> => {
+    const body = await request<{ items: PantryItem[] }>(`/mixes/${id}/pantry`)
+    return body.items
+  },
+
+  addPantryItem: (id: string, item: PantryItemChanges): Promise<PantryItem> =>
+    request(`/mixes/${id}/pantry`, { method: 'POST', body: JSON.stringify(item) }),
+
+  // Replaces the whole item, e.g. with a new quantity as it's used up
+  replacePantryItem: (id: string, itemId: string, item: PantryItemChanges): Promise<PantryItem> =>
+    request(`/mixes/${id}/pantry/${itemId}`, { method: 'PUT', body: JSON.stringify(item) }),
+
+  removePantryItem: (id: string, itemId: string): Promise<void> =>
+    request(`/mixes/${id}/pantry/${itemId}`, { method: 'DELETE' }),
+
+  recipes: async (id: string): Promise<Recipe[]> => {
+    const body = await request<{ recipes: Recipe[] }>(`/mixes/${id}/recipes`)
+    return body.recipes
+  }
+}
@@ -1,7 +1,8 @@
 import { toast } from "sonner"
-import { ChefHat, CookingPot, Users } from "lucide-react"
+import { ChefHat, CookingPot, Link2, Users } from "lucide-react"
 import { useDeviceDetection } from "@/hooks/useDeviceDetection"
 import type { Recipe, RecipeUrlRequestPayload } from "@/types"
+import type { RecipeImportResult } from "@/types/protocol"
 
 export function useToastService() {
   const isMobile = useDeviceDetection()
@@ -67,6 +68,23 @@ export function useToastService() {
     )
   }
 
+  // Added recipes are announced by RECIPE_ADDITIONS; this only reports what wasn't added
+  const showImportSummary = (result: RecipeImportResult) => {
+    const notAdded = [...result.skipped, ...result.failed]
+    if (notAdded.length === 0) return
+
+    const toastId = toast.warning(`${result.added.length} of ${result.added.length + notAdded.length} recipes imported`,
+      getToastOptions({
+        action: {
+          label: "Dismiss",
+          onClick: () => toast.dismiss(toastId),
+        },
+        description: notAdded.map(issue => `${issue.name}: ${issue.reason}`).join("\n"),
+        duration: Infinity,
+      }) as any
+    )
+  }
+
   const showUserJoined = (userName: string) => {
     toast(`${userName} joined the session`,
       getToastOptions({
@@ -87,11 +105,33 @@ export function useToastService() {
     )
   }
 
+  const showInviteCopied = () => {
+    toast("Invite link copied",
+      getToastOptions({
+        description: "Anyone with the link can join for the next week",
+        duration: 3000,
+        icon: <Link2 className="h-4 w-4 text-gray-500" />,
+      }) as any
+    )
+  }
+
+  const showInviteError = (errorMessage?: string) => {
+    toast.error("Could not create an invite",
+      getToastOptions({
+        description: errorMessage || "Please try again",
+        duration: 4000,
+      }) as any
+    )
+  }
+
   return {
     showRecipeProgress,
     showRecipeSuccess,
     showRecipeError,
+    showImportSummary,
     showUserJoined,
     showUserLeft,
+    showInviteCopied,
+    showInviteError,
   }
 }
@@ -1,10 +1,39 @@
-import { v4 as uuidv4 } from 'uuid'
 import type { User } from '@/types'
 
+interface IdentityResponse {
+  userId: string
+  userName: string
+  expiresAt: string
+  token: string
+}
+
 type UserChangeHandler = (user: User | null) => void
 
+// The token is kept across reloads so the user ID, and with it any mix roles, survives
+const TOKEN_STORAGE_KEY = 'mixUserToken'
+
+const loadToken = (): string | null => {
+  try {
+    return window.localStorage.getItem(TOKEN_STORAGE_KEY)
+  } catch {
+    return null
+  }
+}
+
+const saveToken = (token: string | null) => {
+  try {
+    if (token) {
+      window.localStorage.setItem(TOKEN_STORAGE_KEY, token)
+    } else {
+      window.localStorage.removeItem(TOKEN_STORAGE_KEY)
+    }
+  } catch { }
+}
+
 function createUserIdentityService() {
   let currentUser: User | null = null
+  // Server-signed token presented on USER_IDENTIFY
+  let currentToken: string | null = loadToken()
   const userChangeHandlers = new Set<UserChangeHandler>()
 
   const notifyUserChange = (user: User | null) => {
@@ -21,18 +50,50 @@ function createUserIdentityService() {
     return currentUser
   }
 
-  const setUserIdentity = (name: string): User => {
+  const getToken = (): string | null => {
+    return currentToken
+  }
+
+  // setUserIdentity mints a guest identity, keeping the current user ID when renaming
+  const setUserIdentity = async (name: string): Promise<User> => {
+    const response = await fetch('/api/v1/identity/guest', {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ name, token: currentToken ?? undefined })
+    })
+
+    if (response.status === 401) {
+      // The token is no longer valid, e.g. the server's secret changed; start over as a new guest
+      currentToken = null
+      saveToken(null)
+      return setUserIdentity(name)
+    }
+
+    const body = await response.json()
+    if (!response.ok) {
+      throw new Error(body.message || 'Failed to create identity')
+    }
+
+    const identity = body as IdentityResponse
     const user: User = {
-      id: uuidv4(),
-      name: name.trim()
+      id: identity.userId,
+      name: identity.userName
     }
     currentUser = user
+    currentToken = identity.token
+    saveToken(identity.token)
     notifyUserChange(user)
     return user
   }
 
-  const clearUserIdentity = (): void => {
+  // clearUserIdentity forgets the name. The token is kept unless signing out, so
+  // picking a new name renames the same user rather than starting over
+  const clearUserIdentity = (forgetToken = false): void => {
     currentUser = null
+    if (forgetToken) {
+      currentToken = null
+      saveToken(null)
+    }
     notifyUserChange(null)
   }
 
@@ -45,6 +106,7 @@ function createUserIdentityService() {
 
   return {
     getUserIdentity,
+    getToken,
     setUserIdentity,
     clearUserIdentity,
     onUserChange
@@ -1,44 +1,33 @@
-export type WSMessageType =
-  | 'CONNECTION_ACK'
-  | 'GENERIC_DATA'
-  | 'USER_IDENTIFY'
-  | 'USER_JOINED'
-  | 'USER_LEFT'
-  | 'CHAT_MESSAGE'
-  | 'RECIPE_URL_REQUEST'
-  | 'RECIPE_PROGRESS'
-  | 'RECIPE_ADDITIONS'
-
-export interface ConnectionAckData {
-  id: string
-  message: string
-}
+import { v4 as uuidv4 } from 'uuid'
+
+import {
+  PROTOCOL_VERSION,
+  type ConnectionAckPayload,
+  type ServerMessageType,
+  type SyncPayload,
+  type WSMessageType,
+  type WSPayloadMap
+} from '@/types/protocol'
+
+export type { UserIdentifyPayload, WSMessageType } from '@/types/protocol'
+
+// Close code sent when this client fell too far behind the server
+const CLOSE_SLOW_CONSUMER = 4001
 
 export interface WSMessage<T = unknown> {
   type: WSMessageType
   timestamp: string
+  requestId?: string
+  seq?: number
   data: T
 }
 
-export interface GenericPayload {
-  data: unknown
-  status: string
-  queued_at: string
-}
-
-export interface UserIdentifyPayload {
-  userId: string
-  userName: string
-}
-
-export interface RecipeUrlSubmissionData {
-  sharerId: string
-  sharerName: string
-  sessionId: string
-  url: string
-}
+// ServerEvent is any message the server sends, narrowed on its type
+export type ServerEvent = {
+  [K in ServerMessageType]: WSMessage<WSPayloadMap[K]> & { type: K }
+}[ServerMessageType]
 
-export type MessageHandler<T = unknown> = (data: T) => void
+export type MessageHandler<T = unknown> = (data: T, message: WSMessage<T>) => void
 
 export type ConnectionState = 'disconnected' | 'connecting' | 'connected' | 'error'
 
@@ -47,30 +36,40 @@ export type StateChangeHandler = (state: ConnectionState) => void
 export interface WebSocketConnection {
   connect: (uuid: string) => Promise<void>
   disconnect: () => void
-  send: <T>(type: WSMessageType, data: T) => void
+  send: <T>(type: WSMessageType, data: T, requestId?: string) => string
   on: <T>(type: WSMessageType, handler: MessageHandler<T>) => () => void
   off: <T>(type: WSMessageType, handler: MessageHandler<T>) => void
   isConnected: () => boolean
   getConnectionState: () => ConnectionState
   onStateChange: (handler: StateChangeHandler) => () => void
+  getLastSeq: () => number
 }
 
 function createWebSocketConnection(): WebSocketConnection {
   let ws: WebSocket | null = null
   let currentUuid: string | null = null
   let connectionState: ConnectionState = 'disconnected'
+  // Sequence of the last mix event received, sent with USER_IDENTIFY to resume
+  let lastSeq = 0
   const handlers = new Map<WSMessageType, Set<MessageHandler>>()
   const stateChangeHandlers = new Set<StateChangeHandler>()
 
   const handleMessage = (event: MessageEvent) => {
     try {
       const message: WSMessage = JSON.parse(event.data)
+
+      if (message.type === 'SYNC') {
+        lastSeq = (message.data as SyncPayload).seq
+      } else if (message.seq && message.seq > lastSeq) {
+        lastSeq = message.seq
+      }
+
       const typeHandlers = handlers.get(message.type)
 
       if (typeHandlers) {
         typeHandlers.forEach(handler => {
           try {
-            handler(message.data)
+            handler(message.data, message)
           } catch (error) {
             console.error(`Error in handler for ${message.type}:`, error)
           }
@@ -95,9 +94,12 @@ function createWebSocketConnection(): WebSocketConnection {
         ws = null
       }
 
+      if (currentUuid !== uuid) {
+        lastSeq = 0
+      }
       currentUuid = uuid
       const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
-      const wsUrl = `${protocol}//${window.location.host}/api/v1/ws/${uuid}`
+      const wsUrl = `${protocol}//${window.location.host}/api/v1/ws/${uuid}?version=${PROTOCOL_VERSION}`
 
       notifyStateChange('connecting')
 
@@ -117,6 +119,13 @@ function createWebSocketConnection(): WebSocketConnection {
         }, { once: true })
 
         ws.addEventListener('close', (event) => {
+          // The server dropped us for falling behind; reconnect and resume from lastSeq
+          if (event.code === CLOSE_SLOW_CONSUMER && currentUuid === uuid) {
+            ws = null
+            notifyStateChange('disconnected')
+            connect(uuid).catch(error => console.error('Failed to reconnect:', error))
+            return
+          }
           notifyStateChange(event.wasClean ? 'disconnected' : 'error')
         })
       } catch (error) {
@@ -134,7 +143,8 @@ function createWebSocketConnection(): WebSocketConnection {
     notifyStateChange('disconnected')
   }
 
-  const send = <T>(type: WSMessageType, data: T): void => {
+  // send returns the request ID so replies, progress and errors can be correlated
+  const send = <T>(type: WSMessageType, data: T, requestId: string = uuidv4()): string => {
     if (!ws || ws.readyState !== WebSocket.OPEN) {
       throw new Error('WebSocket not connected')
     }
@@ -142,10 +152,12 @@ function createWebSocketConnection(): WebSocketConnection {
     const message: WSMessage<T> = {
       type,
       timestamp: new Date().toISOString(),
+      requestId,
       data
     }
 
     ws.send(JSON.stringify(message))
+    return requestId
   }
 
   const isConnected = (): boolean => {
@@ -185,6 +197,10 @@ function createWebSocketConnection(): WebSocketConnection {
     return connectionState
   }
 
+  const getLastSeq = (): number => {
+    return lastSeq
+  }
+
   const onStateChange = (handler: StateChangeHandler): (() => void) => {
     stateChangeHandlers.add(handler)
     return () => {
@@ -200,13 +216,14 @@ function createWebSocketConnection(): WebSocketConnection {
     off,
     isConnected,
     getConnectionState,
-    onStateChange
+    onStateChange,
+    getLastSeq
   }
 }
 
 export const websocketService = createWebSocketConnection()
 
 // Helper function to handle connection acknowledgment
-export const handleConnectionAck = (callback: (data: ConnectionAckData) => void) => {
+export const handleConnectionAck = (callback: (data: ConnectionAckPayload) => void) => {
   return websocketService.on('CONNECTION_ACK', callback)
 }
@@ -1,2 +1 @@
-export * from './messages'
-export * from './websocket'
\ No newline at end of file
+export * from './messages'
\ No newline at end of file
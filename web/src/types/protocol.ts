@@ -0,0 +1,460 @@
+// Code generated by protogen from protocol.schema.json. DO NOT EDIT.
+
+export const PROTOCOL_VERSION = 4
+export const MIN_PROTOCOL_VERSION = 4
+
+export type WSMessageType =
+  | 'CONNECTION_ACK'
+  | 'PING'
+  | 'USER_IDENTIFY'
+  | 'USER_JOINED'
+  | 'USER_LEFT'
+  | 'PRESENCE_STATE'
+  | 'CHAT_MESSAGE'
+  | 'CHAT_HISTORY_REQUEST'
+  | 'CHAT_HISTORY'
+  | 'RECIPE_URL_REQUEST'
+  | 'RECIPE_TEXT_REQUEST'
+  | 'RECIPE_ADDITIONS'
+  | 'RECIPE_PROGRESS'
+  | 'RECIPE_IMPORT_PROGRESS'
+  | 'RECIPE_REMOVE'
+  | 'RECIPE_REMOVALS'
+  | 'RECIPE_UPDATE'
+  | 'RECIPE_UPDATES'
+  | 'PLAN_ENTRY_ADD'
+  | 'PLAN_ENTRY_MOVE'
+  | 'PLAN_ENTRY_REMOVE'
+  | 'PLAN_UPDATES'
+  | 'PLAN_REMOVALS'
+  | 'GROCERY_ITEM_ADD'
+  | 'GROCERY_ITEM_UPDATE'
+  | 'GROCERY_ITEM_CHECK'
+  | 'GROCERY_ITEM_REMOVE'
+  | 'GROCERY_LIST_CLEAR_CHECKED'
+  | 'GROCERY_ITEM_UPDATES'
+  | 'GROCERY_ITEM_REMOVALS'
+  | 'PANTRY_ITEM_UPDATES'
+  | 'PANTRY_ITEM_REMOVALS'
+  | 'ERROR'
+  | 'SYNC'
+
+export type ClientMessageType =
+  | 'PING'
+  | 'USER_IDENTIFY'
+  | 'CHAT_MESSAGE'
+  | 'CHAT_HISTORY_REQUEST'
+  | 'RECIPE_URL_REQUEST'
+  | 'RECIPE_TEXT_REQUEST'
+  | 'RECIPE_REMOVE'
+  | 'RECIPE_UPDATE'
+  | 'PLAN_ENTRY_ADD'
+  | 'PLAN_ENTRY_MOVE'
+  | 'PLAN_ENTRY_REMOVE'
+  | 'GROCERY_ITEM_ADD'
+  | 'GROCERY_ITEM_UPDATE'
+  | 'GROCERY_ITEM_CHECK'
+  | 'GROCERY_ITEM_REMOVE'
+  | 'GROCERY_LIST_CLEAR_CHECKED'
+
+export type ServerMessageType =
+  | 'CONNECTION_ACK'
+  | 'USER_JOINED'
+  | 'USER_LEFT'
+  | 'PRESENCE_STATE'
+  | 'CHAT_MESSAGE'
+  | 'CHAT_HISTORY'
+  | 'RECIPE_ADDITIONS'
+  | 'RECIPE_PROGRESS'
+  | 'RECIPE_IMPORT_PROGRESS'
+  | 'RECIPE_REMOVALS'
+  | 'RECIPE_UPDATES'
+  | 'PLAN_UPDATES'
+  | 'PLAN_REMOVALS'
+  | 'GROCERY_ITEM_UPDATES'
+  | 'GROCERY_ITEM_REMOVALS'
+  | 'PANTRY_ITEM_UPDATES'
+  | 'PANTRY_ITEM_REMOVALS'
+  | 'ERROR'
+  | 'SYNC'
+
+export type Role =
+  | 'owner'
+  | 'editor'
+  | 'viewer'
+
+export type Meal =
+  | 'breakfast'
+  | 'lunch'
+  | 'dinner'
+  | 'snack'
+
+export type ErrorCode =
+  | 'INVALID_PAYLOAD'
+  | 'NOT_IDENTIFIED'
+  | 'SHARER_MISMATCH'
+  | 'UNKNOWN_MESSAGE_TYPE'
+  | 'RECIPE_UNAVAILABLE'
+  | 'INCOMPATIBLE_PROTOCOL'
+  | 'INVALID_IDENTITY'
+  | 'NAME_TAKEN'
+  | 'FORBIDDEN'
+  | 'NOT_MEMBER'
+  | 'INVALID_INVITE'
+  | 'RECIPE_NOT_FOUND'
+  | 'VERSION_CONFLICT'
+  | 'NO_INGREDIENTS'
+  | 'PLAN_ENTRY_NOT_FOUND'
+  | 'GROCERY_ITEM_NOT_FOUND'
+
+export type SyncMode =
+  | 'replay'
+  | 'snapshot'
+
+export interface ConnectionAckPayload {
+  id: string
+  message: string
+  protocolVersion: number
+  minProtocolVersion: number
+}
+
+export type PingPayload = Record<string, never>
+
+// UserIdentifyPayload identifies the connection as the user named by token; userId, when sent, must match it
+export interface UserIdentifyPayload {
+  token: string
+  userId?: string
+  userName?: string
+  lastSeq?: number
+  invite?: string
+}
+
+export interface UserJoinedPayload {
+  userId: string
+  userName: string
+  sessionId: string
+}
+
+export interface UserLeftPayload {
+  userId: string
+  userName: string
+  sessionId: string
+}
+
+// PresenceStatePayload lists everyone identified in the mix and is sent after USER_IDENTIFY. USER_JOINED and USER_LEFT then follow a user's first and last connection
+export interface PresenceStatePayload {
+  users: PresenceUser[]
+}
+
+export interface PresenceUser {
+  userId: string
+  userName: string
+  role: Role
+  connections: number
+}
+
+export interface ChatUser {
+  id: string
+  name: string
+  imageUrl?: string
+}
+
+export interface ChatChannel {
+  id: string
+  name: string
+}
+
+// ChatMessageBody has its id and sentAt assigned by the server
+export interface ChatMessageBody {
+  id: string
+  sender: ChatUser
+  channel: ChatChannel
+  text: string
+  sentAt: string
+}
+
+// ChatMessagePayload is stored and relayed to the other connections in the mix; the sender gets the stored message as a reply
+export interface ChatMessagePayload {
+  type: string
+  payload: ChatMessageBody
+  timestamp: string
+}
+
+export interface ChatHistoryRequestPayload {
+  before?: string
+  limit?: number
+}
+
+// ChatHistoryPayload is a page of chat history, oldest message first
+export interface ChatHistoryPayload {
+  messages: ChatMessageBody[]
+  hasMore: boolean
+}
+
+export interface RecipeUrlRequestPayload {
+  sharerId: string
+  sharerName: string
+  url: string
+}
+
+// RecipeTextRequestPayload adds a recipe from pasted text, shared by the identified user. Progress is reported with RECIPE_PROGRESS as for RECIPE_URL_REQUEST, with an empty url
+export interface RecipeTextRequestPayload {
+  text: string
+  name?: string
+  useAi?: boolean
+}
+
+export interface RecipeAdditionsPayload {
+  status: string
+  list: Recipe[]
+}
+
+export interface RecipeProgressPayload {
+  request: RecipeUrlRequestPayload
+  phase: string
+  status: string
+  message: string
+}
+
+// RecipeImportProgressPayload reports on a file import started with POST /mixes/:id/recipes/import, to the importing user's connections only. The last one has phase complete and the result
+export interface RecipeImportProgressPayload {
+  importId: string
+  phase: string
+  status: string
+  message: string
+  result?: RecipeImportResult | null
+}
+
+// RecipeImportResult summarises an import. Skipped entries are recipes already in the mix; failed entries couldn't be read or extracted
+export interface RecipeImportResult {
+  added: Recipe[]
+  skipped: ImportIssue[]
+  failed: ImportIssue[]
+}
+
+export interface ImportIssue {
+  name: string
+  reason: string
+}
+
+export interface RecipeRemovePayload {
+  recipeId: string
+  version: number
+}
+
+// RecipeRemovalsPayload lists recipes removed from the mix
+export interface RecipeRemovalsPayload {
+  recipeIds: string[]
+}
+
+// RecipeUpdatePayload corrects a stored recipe; omitted fields are left unchanged and ingredients replaces the whole list. On VERSION_CONFLICT the current recipe is sent back in RECIPE_UPDATES
+export interface RecipeUpdatePayload {
+  recipeId: string
+  version: number
+  name?: string
+  ingredients?: Ingredient[]
+}
+
+// RecipeUpdatesPayload carries the new state of edited recipes
+export interface RecipeUpdatesPayload {
+  list: Recipe[]
+}
+
+// PlanEntryAddPayload schedules a recipe in the mix for a meal on a date; everyone gets the new entry in PLAN_UPDATES
+export interface PlanEntryAddPayload {
+  recipeId: string
+  date: string
+  meal: Meal
+  note?: string
+}
+
+// PlanEntryMovePayload reschedules a plan entry for another date and meal
+export interface PlanEntryMovePayload {
+  entryId: string
+  date: string
+  meal: Meal
+}
+
+export interface PlanEntryRemovePayload {
+  entryId: string
+}
+
+// PlanUpdatesPayload carries new and rescheduled plan entries; clients replace entries with the same id
+export interface PlanUpdatesPayload {
+  list: PlanEntry[]
+}
+
+// PlanRemovalsPayload lists entries taken off the plan, including those for recipes removed from the mix
+export interface PlanRemovalsPayload {
+  entryIds: string[]
+}
+
+// GroceryItemAddPayload adds an item to the mix's grocery list; everyone gets it in GROCERY_ITEM_UPDATES
+export interface GroceryItemAddPayload {
+  name: string
+  quantity?: string
+  unit?: string
+  groceryItem?: GroceryItem | null
+  assigneeId?: string
+}
+
+// GroceryItemUpdatePayload edits a grocery list item; omitted fields are left unchanged and empty ones are cleared. On VERSION_CONFLICT the current item is sent back in GROCERY_ITEM_UPDATES
+export interface GroceryItemUpdatePayload {
+  itemId: string
+  version: number
+  name?: string | null
+  quantity?: string | null
+  unit?: string | null
+  assigneeId?: string | null
+}
+
+// GroceryItemCheckPayload ticks an item off the grocery list, or unticks it; the last tick wins, so no version is needed
+export interface GroceryItemCheckPayload {
+  itemId: string
+  checked: boolean
+}
+
+export interface GroceryItemRemovePayload {
+  itemId: string
+}
+
+// GroceryListClearCheckedPayload takes every checked item off the grocery list
+export type GroceryListClearCheckedPayload = Record<string, never>
+
+// GroceryItemUpdatesPayload carries new and changed grocery list items; clients replace items with the same id
+export interface GroceryItemUpdatesPayload {
+  list: GroceryListItem[]
+}
+
+// GroceryItemRemovalsPayload lists items taken off the grocery list
+export interface GroceryItemRemovalsPayload {
+  itemIds: string[]
+}
+
+// PantryItemUpdatesPayload carries new and changed pantry items; clients replace items with the same id
+export interface PantryItemUpdatesPayload {
+  list: PantryItem[]
+}
+
+// PantryItemRemovalsPayload lists items taken out of the pantry
+export interface PantryItemRemovalsPayload {
+  itemIds: string[]
+}
+
+export interface ErrorPayload {
+  code: ErrorCode
+  message: string
+  requestId?: string
+}
+
+// SyncPayload is sent after USER_IDENTIFY. In replay mode the missed events follow; in snapshot mode the current recipes, meal plan, grocery list and chat history follow
+export interface SyncPayload {
+  mode: SyncMode
+  seq: number
+}
+
+export interface Recipe {
+  id: string
+  name: string
+  url: string
+  image?: string | null
+  thumbnail?: string | null
+  imageSource?: string | null
+  ingredients: Ingredient[]
+  sharerId: string
+  sharerName: string
+  createdAt: string
+  updatedAt: string
+  version: number
+}
+
+export interface Ingredient {
+  name: string
+  groceryItem?: GroceryItem | null
+  quantity: string | null
+  unit: string | null
+}
+
+export interface PlanEntry {
+  id: string
+  recipeId: string
+  date: string
+  meal: Meal
+  note?: string
+  addedById: string
+  addedByName: string
+  createdAt: string
+  updatedAt: string
+}
+
+export interface GroceryListItem {
+  id: string
+  name: string
+  groceryItem?: GroceryItem | null
+  quantity: string | null
+  unit: string | null
+  checked: boolean
+  checkedById?: string
+  checkedByName?: string
+  assigneeId?: string
+  assigneeName?: string
+  addedById: string
+  addedByName: string
+  createdAt: string
+  updatedAt: string
+  version: number
+}
+
+export interface PantryItem {
+  id: string
+  name: string
+  groceryItem?: GroceryItem | null
+  quantity: string | null
+  unit: string | null
+  updatedById: string
+  updatedByName: string
+  updatedAt: string
+}
+
+export interface GroceryItem {
+  id: string
+  name: string
+  category: string
+}
+
+export interface WSPayloadMap {
+  CONNECTION_ACK: ConnectionAckPayload
+  PING: PingPayload
+  USER_IDENTIFY: UserIdentifyPayload
+  USER_JOINED: UserJoinedPayload
+  USER_LEFT: UserLeftPayload
+  PRESENCE_STATE: PresenceStatePayload
+  CHAT_MESSAGE: ChatMessagePayload
+  CHAT_HISTORY_REQUEST: ChatHistoryRequestPayload
+  CHAT_HISTORY: ChatHistoryPayload
+  RECIPE_URL_REQUEST: RecipeUrlRequestPayload
+  RECIPE_TEXT_REQUEST: RecipeTextRequestPayload
+  RECIPE_ADDITIONS: RecipeAdditionsPayload
+  RECIPE_PROGRESS: RecipeProgressPayload
+  RECIPE_IMPORT_PROGRESS: RecipeImportProgressPayload
+  RECIPE_REMOVE: RecipeRemovePayload
+  RECIPE_REMOVALS: RecipeRemovalsPayload
+  RECIPE_UPDATE: RecipeUpdatePayload
+  RECIPE_UPDATES: RecipeUpdatesPayload
+  PLAN_ENTRY_ADD: PlanEntryAddPayload
+  PLAN_ENTRY_MOVE: PlanEntryMovePayload
+  PLAN_ENTRY_REMOVE: PlanEntryRemovePayload
+  PLAN_UPDATES: PlanUpdatesPayload
+  PLAN_REMOVALS: PlanRemovalsPayload
+  GROCERY_ITEM_ADD: GroceryItemAddPayload
+  GROCERY_ITEM_UPDATE: GroceryItemUpdatePayload
+  GROCERY_ITEM_CHECK: GroceryItemCheckPayload
+  GROCERY_ITEM_REMOVE: GroceryItemRemovePayload
+  GROCERY_LIST_CLEAR_CHECKED: GroceryListClearCheckedPayload
+  GROCERY_ITEM_UPDATES: GroceryItemUpdatesPayload
+  GROCERY_ITEM_REMOVALS: GroceryItemRemovalsPayload
+  PANTRY_ITEM_UPDATES: PantryItemUpdatesPayload
+  PANTRY_ITEM_REMOVALS: PantryItemRemovalsPayload
+  ERROR: ErrorPayload
+  SYNC: SyncPayload
+}